@@ -0,0 +1,126 @@
+// Package config reúne la configuración de la aplicación leída desde
+// variables de entorno.
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config contiene los parámetros necesarios para levantar una instancia de
+// la aplicación.
+type Config struct {
+	// Port es el puerto HTTP en el que escucha el servidor.
+	Port string
+	// DemoMode usa repositorios en memoria en lugar de las bases de datos.
+	DemoMode bool
+	// TemplatesDir, si no está vacío, hace que las plantillas HTML se lean
+	// desde ese directorio en cada petición (modo desarrollo).
+	TemplatesDir string
+
+	// Conexión a SQL Server
+	SQLServerHost     string
+	SQLServerPort     string
+	SQLServerUser     string
+	SQLServerPassword string
+	SQLServerDatabase string
+
+	// Conexión a MySQL
+	MySQLUser     string
+	MySQLPassword string
+	MySQLHost     string
+	MySQLDatabase string
+
+	// SucursalPorDefecto es la sucursal de SQL Server usada cuando la
+	// petición no indica una.
+	SucursalPorDefecto int
+
+	// Plazos máximos de cada consulta por base de datos
+	MySQLTimeout     time.Duration
+	SQLServerTimeout time.Duration
+
+	// CacheTTL es cuánto se reutilizan el stock agrupado de SQL Server, los
+	// saldos por año y las zetas del panel de inicio antes de volver a
+	// consultarlos; cero desactiva la caché.
+	// CacheStale es el tiempo adicional durante el que se sirve un dato
+	// vencido mientras se refresca en segundo plano.
+	CacheTTL   time.Duration
+	CacheStale time.Duration
+
+	// AdminToken se exige en la cabecera X-Admin-Token de los endpoints de
+	// administración; vacío los desactiva.
+	AdminToken string
+}
+
+// Load construye la configuración a partir de las variables de entorno.
+func Load() Config {
+	cfg := Config{
+		Port:               os.Getenv("PORT"),
+		DemoMode:           os.Getenv("DEMO_MODE") == "true",
+		TemplatesDir:       os.Getenv("TEMPLATES_DIR"),
+		SQLServerHost:      os.Getenv("DB_SERVER"),
+		SQLServerPort:      os.Getenv("DB_PORT"),
+		SQLServerUser:      os.Getenv("DB_USER"),
+		SQLServerPassword:  os.Getenv("DB_PASSWORD"),
+		SQLServerDatabase:  os.Getenv("DB_NAME"),
+		MySQLUser:          os.Getenv("MYSQL_USER"),
+		MySQLPassword:      os.Getenv("MYSQL_PASSWORD"),
+		MySQLHost:          os.Getenv("MYSQL_HOST"),
+		MySQLDatabase:      os.Getenv("MYSQL_DATABASE"),
+		SucursalPorDefecto: intEnv("SUCURSAL_DEFAULT", 211),
+		MySQLTimeout:       durationEnv("MYSQL_TIMEOUT", 30*time.Second),
+		SQLServerTimeout:   durationEnv("SQLSERVER_TIMEOUT", 30*time.Second),
+		CacheTTL:           durationEnv("CACHE_TTL", 5*time.Minute),
+		CacheStale:         durationEnv("CACHE_STALE", 5*time.Minute),
+		AdminToken:         os.Getenv("ADMIN_TOKEN"),
+	}
+	if cfg.Port == "" {
+		cfg.Port = "8080"
+	}
+	return cfg
+}
+
+// MySQLDSN arma el DSN de MySQL.
+// Ejemplo: "usuario:contraseña@tcp(localhost:3306)/nombre_basedatos"
+func (c Config) MySQLDSN() string {
+	return c.MySQLUser + ":" + c.MySQLPassword + "@tcp(" + c.MySQLHost + ")/" + c.MySQLDatabase
+}
+
+// SQLServerConnString arma la cadena de conexión de SQL Server.
+func (c Config) SQLServerConnString() string {
+	return fmt.Sprintf("server=%s;port=%s;user id=%s;password=%s;database=%s",
+		c.SQLServerHost, c.SQLServerPort, c.SQLServerUser, c.SQLServerPassword, c.SQLServerDatabase)
+}
+
+// durationEnv lee una duración (por ejemplo "15s" o "2m") de la variable de
+// entorno indicada, usando def si no está definida o no es válida.
+func durationEnv(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Valor inválido para %s (%q), usando %s", name, v, def)
+		return def
+	}
+	return d
+}
+
+// intEnv lee un entero de la variable de entorno indicada, usando def si no
+// está definida o no es válida.
+func intEnv(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("Valor inválido para %s (%q), usando %d", name, v, def)
+		return def
+	}
+	return n
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+)
+
+// autorizarAdmin verifica el token de administración (cabecera
+// X-Admin-Token). Sin token configurado los endpoints de administración
+// quedan desactivados y responde 403; si el token no corresponde responde
+// 401. En ambos casos devuelve false.
+func (a *App) autorizarAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if a.Config.AdminToken == "" {
+		http.Error(w, "Administración desactivada: configure ADMIN_TOKEN", http.StatusForbidden)
+		return false
+	}
+	token := r.Header.Get("X-Admin-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.Config.AdminToken)) != 1 {
+		http.Error(w, "Token de administración inválido", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// cacheEstado es la respuesta de /api/admin/cache.
+type cacheEstado struct {
+	TTL      string        `json:"ttl"`
+	Obsoleto string        `json:"obsoleto"`
+	Entradas []estadoCache `json:"entradas"`
+}
+
+// CacheEstadoHandler maneja /api/admin/cache y devuelve la configuración de
+// la caché y sus entradas con la fecha de carga y de vencimiento.
+func (a *App) CacheEstadoHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.autorizarAdmin(w, r) {
+		return
+	}
+	writeJSON(w, cacheEstado{
+		TTL:      a.Config.CacheTTL.String(),
+		Obsoleto: a.Config.CacheStale.String(),
+		Entradas: append(append(a.cache.stocks.Estado(), a.cache.saldos.Estado()...), a.cache.zetas.Estado()...),
+	})
+}
+
+// CacheInvalidarHandler maneja POST /api/admin/cache/invalidar y descarta
+// las entradas de la caché indicada en "cache" (stocks, saldos o zetas) o de
+// todas.
+// La siguiente petición vuelve a consultar las bases.
+func (a *App) CacheInvalidarHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	if !a.autorizarAdmin(w, r) {
+		return
+	}
+
+	invalidadas := map[string]int{}
+	switch cache := r.URL.Query().Get("cache"); cache {
+	case "":
+		invalidadas["stocks"] = a.cache.stocks.Invalidar()
+		invalidadas["saldos"] = a.cache.saldos.Invalidar()
+		invalidadas["zetas"] = a.cache.zetas.Invalidar()
+	case "stocks":
+		invalidadas["stocks"] = a.cache.stocks.Invalidar()
+	case "saldos":
+		invalidadas["saldos"] = a.cache.saldos.Invalidar()
+	case "zetas":
+		invalidadas["zetas"] = a.cache.zetas.Invalidar()
+	default:
+		http.Error(w, "cache inválida: "+cache+" (admite stocks, saldos o zetas)", http.StatusBadRequest)
+		return
+	}
+	log.Printf("Caché invalidada: %v", invalidadas)
+	writeJSON(w, map[string]any{"invalidadas": invalidadas})
+}
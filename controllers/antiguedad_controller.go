@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"go_api/models"
+	"go_api/views"
+)
+
+// nuevosTramos crea los tramos vacíos a partir de sus límites superiores. El
+// último tramo abarca todo lo que supere el último límite.
+func nuevosTramos(limites []int) []models.TramoAntiguedad {
+	tramos := make([]models.TramoAntiguedad, 0, len(limites)+1)
+	desde := 0
+	for _, hasta := range limites {
+		tramos = append(tramos, models.TramoAntiguedad{
+			Etiqueta: fmt.Sprintf("%d–%d días", desde, hasta),
+			Desde:    desde,
+			Hasta:    hasta,
+		})
+		desde = hasta + 1
+	}
+	return append(tramos, models.TramoAntiguedad{
+		Etiqueta: fmt.Sprintf(">%d días", desde-1),
+		Desde:    desde,
+		Hasta:    -1,
+	})
+}
+
+// indiceTramo devuelve la posición del tramo que corresponde a dias. Los
+// días negativos (ingresos con fecha futura) caen en el primer tramo.
+func indiceTramo(limites []int, dias int) int {
+	for i, hasta := range limites {
+		if dias <= hasta {
+			return i
+		}
+	}
+	return len(limites)
+}
+
+// sumarTramo acumula un saldo en el tramo.
+func sumarTramo(t *models.TramoAntiguedad, s models.SaldoData) {
+	t.Lotes++
+	t.Cantidad += s.SaldoAnterior
+	t.ValorCostoReal += s.SaldoAnterior * s.CostoReal
+	t.ValorCostoCIF += s.SaldoAnterior * s.CostoCIF
+}
+
+// construirAntiguedad agrupa los saldos en tramos según DiasDesdeIngreso.
+func construirAntiguedad(saldos []models.SaldoData, limites []int) ([]models.TramoAntiguedad, models.TramoAntiguedad) {
+	tramos := nuevosTramos(limites)
+	total := models.TramoAntiguedad{Etiqueta: "Total", Hasta: -1}
+	for _, s := range saldos {
+		sumarTramo(&tramos[indiceTramo(limites, s.DiasDesdeIngreso)], s)
+		sumarTramo(&total, s)
+	}
+	return tramos, total
+}
+
+// loadAntiguedad obtiene los saldos del año con stock en las sucursales
+// pedidas y arma el reporte. Cada saldo se cuenta una sola vez aunque tenga
+// stock en varias sucursales. Responde con el error y devuelve false si los
+// parámetros son inválidos o falla alguna de las bases.
+func (a *App) loadAntiguedad(w http.ResponseWriter, r *http.Request) (models.ReporteAntiguedad, []models.SaldoData, []int, bool) {
+	query := r.URL.Query()
+	reporte := models.ReporteAntiguedad{}
+
+	var err error
+	if reporte.Anio, err = parseYear(query); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return reporte, nil, nil, false
+	}
+	limites, err := parseTramos(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return reporte, nil, nil, false
+	}
+	reporte.Sucursales, err = parseSucursales(query, a.Config.SucursalPorDefecto)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return reporte, nil, nil, false
+	}
+
+	ds, ok := a.loadCombined(w, r, reporte.Anio, reporte.Sucursales)
+	if !ok {
+		return reporte, nil, nil, false
+	}
+
+	saldos := make([]models.SaldoData, 0, len(ds.Saldos))
+	for _, s := range ds.Saldos {
+		if ds.tieneStock(s) {
+			saldos = append(saldos, s)
+		}
+	}
+
+	reporte.Tramos, reporte.Total = construirAntiguedad(saldos, limites)
+	reporte.DatosAl = ds.DatosAl
+	return reporte, saldos, limites, true
+}
+
+// AntiguedadApiHandler maneja /api/reportes/antiguedad y devuelve el reporte
+// de antigüedad en JSON.
+func (a *App) AntiguedadApiHandler(w http.ResponseWriter, r *http.Request) {
+	reporte, _, _, ok := a.loadAntiguedad(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, reporte)
+}
+
+// AntiguedadViewHandler maneja /reportes/antiguedad.
+func (a *App) AntiguedadViewHandler(w http.ResponseWriter, r *http.Request) {
+	reporte, _, _, ok := a.loadAntiguedad(w, r)
+	if !ok {
+		return
+	}
+
+	// Sucursales disponibles para el selector; si falla se muestran solo las pedidas
+	disponibles, err := a.Stocks.Sucursales(r.Context())
+	if err != nil {
+		log.Println("Error obteniendo sucursales:", err)
+	}
+
+	a.Views.RenderAntiguedad(w, views.AntiguedadViewData{
+		Reporte:     reporte,
+		TramosParam: r.URL.Query().Get("tramos"),
+		SucursalesFiltro: views.SucursalesFiltro{
+			Sucursales:  reporte.Sucursales,
+			Disponibles: disponibles,
+		},
+	})
+}
+
+// ExportAntiguedadHandler exporta el reporte de antigüedad a Excel o PDF
+// (format=pdf), con los tramos y luego los lotes y el tramo asignado a cada
+// uno.
+func (a *App) ExportAntiguedadHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseExportOptions(r.URL.Query(), formatoXLSX, formatoPDF)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reporte, saldos, limites, ok := a.loadAntiguedad(w, r)
+	if !ok {
+		return
+	}
+
+	filename := "antiguedad_" + reporte.Anio
+	for _, id := range reporte.Sucursales {
+		filename += "_" + strconv.Itoa(id)
+	}
+	ew, err := newExportWriter(w, opts, filename, "Antigüedad", columnasTramos)
+	if err != nil {
+		http.Error(w, "Error al crear la exportación", http.StatusInternalServerError)
+		log.Println("Error al crear la exportación:", err)
+		return
+	}
+	// El total de los tramos lo agrega la fila de totales del Excel
+	for _, t := range reporte.Tramos {
+		if err := ew.Write(t, []any{t.Etiqueta, t.Lotes, t.Cantidad, t.ValorCostoReal, t.ValorCostoCIF}); err != nil {
+			errorExportacion(w, ew, err)
+			return
+		}
+	}
+
+	// Los lotes van en otra hoja del Excel o en otra sección del PDF
+	if libro, ok := ew.(libroExport); ok {
+		if err := libro.NuevaHoja("Lotes", columnasLotes); err != nil {
+			log.Println("Error al escribir la exportación:", err)
+			return
+		}
+		for _, s := range saldos {
+			tramo := reporte.Tramos[indiceTramo(limites, s.DiasDesdeIngreso)].Etiqueta
+			fila := []any{s.CodigoProducto, s.Zeta, s.AnioProduccion, s.NombreProducto, s.FechaIngreso,
+				s.DiasDesdeIngreso, tramo, s.SaldoAnterior, s.CostoCIF, s.CostoReal}
+			if err := ew.Write(s, fila); err != nil {
+				errorExportacion(w, ew, err)
+				return
+			}
+		}
+	}
+	if err := ew.Close(); err != nil {
+		log.Println("Error al escribir la exportación:", err)
+	}
+}
+
+// columnasTramos son las columnas de la hoja de tramos de antigüedad.
+var columnasTramos = []columna{
+	{"Tramo", colTexto, 18, false, false},
+	{"Lotes", colEntero, 0, true, false},
+	{"Cantidad", colDecimal, 0, true, false},
+	{"Valor Costo Real", colMoneda, 18, true, false},
+	{"Valor Costo CIF", colMoneda, 18, true, false},
+}
+
+// columnasLotes son las columnas de la hoja de lotes de antigüedad.
+var columnasLotes = []columna{
+	{"Código", colTexto, 0, false, false},
+	{"Zeta", colTexto, 0, false, false},
+	{"Año Producción", colEntero, 0, false, false},
+	{"Nombre Producto", colTexto, 40, false, false},
+	{"Fecha Ingreso", colFecha, 0, false, false},
+	{"Días Desde Ingreso", colEntero, 0, false, false},
+	{"Tramo", colTexto, 18, false, false},
+	{"Saldo", colDecimal, 0, true, false},
+	{"Costo CIF", colMoneda, 0, false, false},
+	{"Costo Real", colMoneda, 0, false, false},
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"go_api/config"
+	"go_api/models"
+	"go_api/repository"
+)
+
+func TestIndiceTramo(t *testing.T) {
+	limites := []int{30, 90, 180}
+	tests := []struct {
+		dias int
+		want int
+	}{
+		{-5, 0}, // ingreso con fecha futura
+		{0, 0},
+		{30, 0},
+		{31, 1},
+		{90, 1},
+		{91, 2},
+		{180, 2},
+		{181, 3},
+		{5000, 3},
+	}
+	for _, tt := range tests {
+		if got := indiceTramo(limites, tt.dias); got != tt.want {
+			t.Errorf("indiceTramo(%d) = %d, se esperaba %d", tt.dias, got, tt.want)
+		}
+	}
+}
+
+func TestNuevosTramos(t *testing.T) {
+	var etiquetas []string
+	var rangos [][2]int
+	for _, tr := range nuevosTramos([]int{30, 90}) {
+		etiquetas = append(etiquetas, tr.Etiqueta)
+		rangos = append(rangos, [2]int{tr.Desde, tr.Hasta})
+	}
+	if want := []string{"0–30 días", "31–90 días", ">90 días"}; !reflect.DeepEqual(etiquetas, want) {
+		t.Errorf("etiquetas = %q, se esperaba %q", etiquetas, want)
+	}
+	if want := [][2]int{{0, 30}, {31, 90}, {91, -1}}; !reflect.DeepEqual(rangos, want) {
+		t.Errorf("rangos = %v, se esperaba %v", rangos, want)
+	}
+}
+
+func TestParseTramos(t *testing.T) {
+	tests := []struct {
+		valor   string
+		want    []int
+		wantErr bool
+	}{
+		{"", limitesAntiguedad, false},
+		{"10, 20,40", []int{10, 20, 40}, false},
+		{"0", []int{0}, false},
+		{"30,30", nil, true},
+		{"90,30", nil, true},
+		{"-1,30", nil, true},
+		{"30,,90", nil, true},
+		{"treinta", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTramos(url.Values{"tramos": {tt.valor}})
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTramos(%q) = %v, %v; se esperaba %v (error %v)", tt.valor, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestConstruirAntiguedad(t *testing.T) {
+	saldos := []models.SaldoData{
+		{DiasDesdeIngreso: 10, SaldoAnterior: 2, CostoReal: 100, CostoCIF: 80},
+		{DiasDesdeIngreso: 30, SaldoAnterior: 1, CostoReal: 50, CostoCIF: 40},
+		{DiasDesdeIngreso: 31, SaldoAnterior: 4, CostoReal: 10, CostoCIF: 5},
+		{DiasDesdeIngreso: 400, SaldoAnterior: 3, CostoReal: 20, CostoCIF: 10},
+	}
+	tramos, total := construirAntiguedad(saldos, []int{30, 90})
+
+	type resumen struct {
+		Lotes          int
+		Cantidad, Real float64
+		CIF            float64
+	}
+	var got []resumen
+	for _, tr := range tramos {
+		got = append(got, resumen{tr.Lotes, tr.Cantidad, tr.ValorCostoReal, tr.ValorCostoCIF})
+	}
+	want := []resumen{{2, 3, 250, 200}, {1, 4, 40, 20}, {1, 3, 60, 30}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tramos = %+v, se esperaba %+v", got, want)
+	}
+	if total.Lotes != 4 || total.Cantidad != 10 || total.ValorCostoReal != 350 || total.ValorCostoCIF != 250 {
+		t.Errorf("total = %+v", total)
+	}
+}
+
+func TestAntiguedadApiTotales(t *testing.T) {
+	app := newTestApp(t, config.Config{SucursalPorDefecto: repository.SucursalPorDefecto})
+	for _, tramos := range []string{"", "15,45", "1000"} {
+		rec := httptest.NewRecorder()
+		app.AntiguedadApiHandler(rec, httptest.NewRequest(http.MethodGet,
+			"/api/reportes/antiguedad?year=2025&tramos="+tramos, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("tramos %q: status = %d", tramos, rec.Code)
+		}
+		var reporte models.ReporteAntiguedad
+		if err := json.NewDecoder(rec.Body).Decode(&reporte); err != nil {
+			t.Fatal(err)
+		}
+		lotes := 0
+		for _, tr := range reporte.Tramos {
+			lotes += tr.Lotes
+		}
+		if lotes == 0 || lotes != reporte.Total.Lotes {
+			t.Errorf("tramos %q: la suma de lotes %d no coincide con el total %d", tramos, lotes, reporte.Total.Lotes)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	app.AntiguedadApiHandler(rec, httptest.NewRequest(http.MethodGet, "/api/reportes/antiguedad?tramos=90,30", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("tramos desordenados: status = %d, se esperaba 400", rec.Code)
+	}
+}
+
+func TestExportAntiguedadFormatos(t *testing.T) {
+	app := newTestApp(t, config.Config{SucursalPorDefecto: repository.SucursalPorDefecto})
+	tests := []struct {
+		format string
+		want   int
+	}{
+		{"", http.StatusOK},
+		{"pdf", http.StatusOK},
+		{"csv", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.ExportAntiguedadHandler(rec, httptest.NewRequest(http.MethodGet,
+			"/export/antiguedad?year=2025&format="+tt.format, nil))
+		if rec.Code != tt.want {
+			t.Errorf("format %q: status = %d, se esperaba %d", tt.format, rec.Code, tt.want)
+		}
+	}
+}
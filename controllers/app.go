@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"go_api/config"
+	"go_api/db"
+	"go_api/repository"
+	"go_api/views"
+)
+
+// App agrupa las dependencias de una instancia de la aplicación: la
+// configuración, las conexiones, los repositorios y su propio ServeMux.
+// Los handlers son métodos de App, por lo que pueden convivir varias
+// instancias en un mismo proceso (por ejemplo, una por cliente o por prueba).
+type App struct {
+	Config config.Config
+	Saldos repository.SaldoRepository
+	Stocks repository.StockRepository
+	Views  *views.Renderer
+	Mux    *http.ServeMux
+
+	cache     *datosCache
+	mysql     *sql.DB
+	sqlServer *sql.DB
+}
+
+// NewApp crea una instancia abriendo las conexiones indicadas en la
+// configuración, o con datos en memoria si está activo el modo demo.
+func NewApp(cfg config.Config) (*App, error) {
+	if cfg.DemoMode {
+		saldos, stocks := repository.DemoData()
+		return NewAppWithRepositories(cfg,
+			repository.NewMemorySaldoRepository(saldos),
+			repository.NewMemoryStockRepository(stocks))
+	}
+
+	// Inicializar conexión a SQL Server
+	sqlServer, err := db.OpenSQLServer(cfg.SQLServerConnString())
+	if err != nil {
+		return nil, fmt.Errorf("inicializando SQL Server: %w", err)
+	}
+
+	// Inicializar la conexión a MySQL
+	mysql, err := db.OpenMySQL(cfg.MySQLDSN())
+	if err != nil {
+		sqlServer.Close()
+		return nil, fmt.Errorf("inicializando MySQL: %w", err)
+	}
+
+	app, err := NewAppWithRepositories(cfg,
+		repository.NewMySQLSaldoRepository(mysql, cfg.MySQLTimeout),
+		repository.NewSQLServerStockRepository(sqlServer, cfg.SQLServerTimeout))
+	if err != nil {
+		mysql.Close()
+		sqlServer.Close()
+		return nil, err
+	}
+	app.mysql = mysql
+	app.sqlServer = sqlServer
+	return app, nil
+}
+
+// NewAppWithRepositories crea una instancia sobre repositorios ya construidos.
+func NewAppWithRepositories(cfg config.Config, saldos repository.SaldoRepository, stocks repository.StockRepository) (*App, error) {
+	renderer, err := views.NewRenderer(cfg.TemplatesDir)
+	if err != nil {
+		return nil, fmt.Errorf("cargando plantillas: %w", err)
+	}
+	return &App{
+		Config: cfg,
+		Saldos: saldos,
+		Stocks: stocks,
+		Views:  renderer,
+		Mux:    http.NewServeMux(),
+		cache:  newDatosCache(cfg),
+	}, nil
+}
+
+// ServeHTTP despacha la petición al ServeMux propio de la instancia.
+func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.Mux.ServeHTTP(w, r)
+}
+
+// Close cierra las conexiones abiertas por NewApp.
+func (a *App) Close() error {
+	var firstErr error
+	for _, conn := range []*sql.DB{a.mysql, a.sqlServer} {
+		if conn == nil {
+			continue
+		}
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
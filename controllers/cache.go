@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go_api/config"
+	"go_api/models"
+)
+
+// cacheEntrada es un valor cargado y el momento en que se leyó de la base.
+type cacheEntrada[V any] struct {
+	valor     V
+	cargadoEn time.Time
+}
+
+// cacheCarga es una carga en curso. Las peticiones que piden la misma clave
+// mientras tanto la esperan en lugar de repetir la consulta.
+type cacheCarga[V any] struct {
+	listo   chan struct{}
+	entrada cacheEntrada[V]
+	err     error
+}
+
+// cacheTTL guarda en memoria los valores cargados por clave durante ttl. Un
+// valor vencido hace menos de obsoleto se sigue sirviendo mientras se
+// refresca en segundo plano; pasado ese plazo la petición espera la carga.
+// Los valores se comparten entre peticiones y no deben modificarse. Cada
+// carga tiene un plazo de plazo.
+type cacheTTL[V any] struct {
+	nombre   string
+	ttl      time.Duration
+	obsoleto time.Duration
+	plazo    time.Duration
+
+	mu         sync.Mutex
+	entradas   map[string]cacheEntrada[V]
+	cargas     map[string]*cacheCarga[V]
+	generacion int // cambia al invalidar; las cargas previas no se guardan
+}
+
+func newCacheTTL[V any](nombre string, ttl, obsoleto, plazo time.Duration) *cacheTTL[V] {
+	return &cacheTTL[V]{
+		nombre:   nombre,
+		ttl:      ttl,
+		obsoleto: obsoleto,
+		plazo:    plazo,
+		entradas: make(map[string]cacheEntrada[V]),
+		cargas:   make(map[string]*cacheCarga[V]),
+	}
+}
+
+// Obtener devuelve el valor de clave y cuándo se leyó de la base, usando
+// cargar si no está en la caché o venció. Con ttl cero la caché está
+// desactivada y siempre se carga.
+func (c *cacheTTL[V]) Obtener(ctx context.Context, clave string, cargar func(context.Context) (V, error)) (V, time.Time, error) {
+	if c.ttl <= 0 {
+		v, err := cargar(ctx)
+		return v, time.Now(), err
+	}
+
+	c.mu.Lock()
+	entrada, ok := c.entradas[clave]
+	edad := time.Since(entrada.cargadoEn)
+	switch {
+	case ok && edad < c.ttl:
+		c.mu.Unlock()
+		return entrada.valor, entrada.cargadoEn, nil
+	case ok && edad < c.ttl+c.obsoleto:
+		c.iniciarCarga(clave, cargar)
+		c.mu.Unlock()
+		return entrada.valor, entrada.cargadoEn, nil
+	}
+	carga := c.iniciarCarga(clave, cargar)
+	c.mu.Unlock()
+
+	select {
+	case <-carga.listo:
+		return carga.entrada.valor, carga.entrada.cargadoEn, carga.err
+	case <-ctx.Done():
+		var cero V
+		return cero, time.Time{}, ctx.Err()
+	}
+}
+
+// iniciarCarga lanza la carga de clave si no hay otra en curso y la
+// devuelve. La carga no depende del contexto de la petición que la inició,
+// para que su cancelación no afecte a las demás que la esperan: corre con
+// su propio plazo. Debe llamarse con c.mu tomado.
+func (c *cacheTTL[V]) iniciarCarga(clave string, cargar func(context.Context) (V, error)) *cacheCarga[V] {
+	if carga, ok := c.cargas[clave]; ok {
+		return carga
+	}
+	carga := &cacheCarga[V]{listo: make(chan struct{})}
+	c.cargas[clave] = carga
+	generacion := c.generacion
+
+	go func() {
+		inicio := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), c.plazo)
+		v, err := cargar(ctx)
+		cancel()
+
+		c.mu.Lock()
+		if c.cargas[clave] == carga {
+			delete(c.cargas, clave)
+		}
+		if err == nil {
+			carga.entrada = cacheEntrada[V]{valor: v, cargadoEn: inicio}
+			if generacion == c.generacion {
+				c.guardar(clave, carga.entrada)
+			}
+		}
+		carga.err = err
+		c.mu.Unlock()
+		close(carga.listo)
+
+		if err != nil {
+			log.Printf("Error cargando %s %q en la caché: %v", c.nombre, clave, err)
+		} else {
+			log.Printf("Caché %s %q cargada en %s", c.nombre, clave, time.Since(inicio).Round(time.Millisecond))
+		}
+	}()
+	return carga
+}
+
+// maxEntradasCache limita las claves guardadas en cada caché. Las claves
+// dependen de la petición (año y sucursales), así que sin límite la memoria
+// crecería con cada combinación pedida.
+const maxEntradasCache = 64
+
+// guardar guarda la entrada de clave. Antes descarta las entradas que ya no
+// se pueden servir ni como obsoletas y, si aun así se alcanzó el límite, la
+// cargada hace más tiempo. Debe llamarse con c.mu tomado.
+func (c *cacheTTL[V]) guardar(clave string, entrada cacheEntrada[V]) {
+	if _, ok := c.entradas[clave]; !ok && len(c.entradas) >= maxEntradasCache {
+		masVieja := ""
+		for k, e := range c.entradas {
+			if time.Since(e.cargadoEn) >= c.ttl+c.obsoleto {
+				delete(c.entradas, k)
+			} else if masVieja == "" || e.cargadoEn.Before(c.entradas[masVieja].cargadoEn) {
+				masVieja = k
+			}
+		}
+		if len(c.entradas) >= maxEntradasCache {
+			delete(c.entradas, masVieja)
+		}
+	}
+	c.entradas[clave] = entrada
+}
+
+// Invalidar descarta todas las entradas y devuelve cuántas había. Las cargas
+// en curso terminan pero su resultado no se guarda, y las peticiones
+// siguientes ya no las esperan: inician una carga nueva.
+func (c *cacheTTL[V]) Invalidar() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.entradas)
+	c.entradas = make(map[string]cacheEntrada[V])
+	c.cargas = make(map[string]*cacheCarga[V])
+	c.generacion++
+	return n
+}
+
+// estadoCache describe una entrada de la caché para el endpoint de
+// administración.
+type estadoCache struct {
+	Cache     string    `json:"cache"`
+	Clave     string    `json:"clave"`
+	CargadoEn time.Time `json:"cargadoEn"`
+	VenceEn   time.Time `json:"venceEn"`
+	Vigente   bool      `json:"vigente"`
+}
+
+// Estado devuelve las entradas guardadas ordenadas por clave.
+func (c *cacheTTL[V]) Estado() []estadoCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	estado := make([]estadoCache, 0, len(c.entradas))
+	for clave, e := range c.entradas {
+		estado = append(estado, estadoCache{
+			Cache:     c.nombre,
+			Clave:     clave,
+			CargadoEn: e.cargadoEn,
+			VenceEn:   e.cargadoEn.Add(c.ttl),
+			Vigente:   time.Since(e.cargadoEn) < c.ttl,
+		})
+	}
+	sort.Slice(estado, func(i, j int) bool { return estado[i].Clave < estado[j].Clave })
+	return estado
+}
+
+// plazoCargaMaximo es el plazo de una carga de la caché cuando no hay
+// timeout configurado, y el máximo aunque lo haya.
+const plazoCargaMaximo = 2 * time.Minute
+
+// plazoCarga devuelve el plazo de las cargas que consultan bases con los
+// timeouts dados: el mayor de ellos, sin pasar de plazoCargaMaximo. Un
+// timeout cero (sin límite) usa plazoCargaMaximo.
+func plazoCarga(timeouts ...time.Duration) time.Duration {
+	var plazo time.Duration
+	for _, t := range timeouts {
+		if t <= 0 {
+			return plazoCargaMaximo
+		}
+		plazo = max(plazo, t)
+	}
+	return min(plazo, plazoCargaMaximo)
+}
+
+// stocksAgrupados es el stock de SQL Server agrupado por sucursal y clave de
+// cruce, tal como lo devuelve agruparStocksPorSucursal.
+type stocksAgrupados struct {
+	PorSucursal map[int]map[string]models.StockData
+	Colisiones  []models.ColisionCruce
+}
+
+// stocksSucursales es el stock leído de SQL Server para un conjunto de
+// sucursales. Los agrupados por cada estrategia de cruce se calculan al
+// pedirlos y se guardan junto a las filas, así que se descartan con ellas.
+type stocksSucursales struct {
+	filas []models.StockData
+
+	mu            sync.Mutex
+	porEstrategia map[string]stocksAgrupados
+}
+
+// agrupados devuelve el stock agrupado con la estrategia de cruce.
+func (s *stocksSucursales) agrupados(estrategia string) stocksAgrupados {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g, ok := s.porEstrategia[estrategia]; ok {
+		return g
+	}
+	porSucursal, colisiones := agruparStocksPorSucursal(estrategia, s.filas)
+	g := stocksAgrupados{PorSucursal: porSucursal, Colisiones: colisiones}
+	if s.porEstrategia == nil {
+		s.porEstrategia = make(map[string]stocksAgrupados)
+	}
+	s.porEstrategia[estrategia] = g
+	return g
+}
+
+// datosCache reúne las cachés de los datos que más se repiten entre
+// peticiones: el stock de SQL Server por sucursales, los saldos por año de
+// MySQL y las zetas distintas de ambas bases que cuenta el panel de inicio.
+type datosCache struct {
+	stocks *cacheTTL[*stocksSucursales]
+	saldos *cacheTTL[[]models.SaldoData]
+	zetas  *cacheTTL[[]string]
+}
+
+func newDatosCache(cfg config.Config) *datosCache {
+	ttl, obsoleto := cfg.CacheTTL, cfg.CacheStale
+	return &datosCache{
+		stocks: newCacheTTL[*stocksSucursales]("stocks", ttl, obsoleto, plazoCarga(cfg.SQLServerTimeout)),
+		saldos: newCacheTTL[[]models.SaldoData]("saldos", ttl, obsoleto, plazoCarga(cfg.MySQLTimeout)),
+		zetas:  newCacheTTL[[]string]("zetas", ttl, obsoleto, plazoCarga(cfg.MySQLTimeout, cfg.SQLServerTimeout)),
+	}
+}
+
+// stocksAgrupados devuelve el stock de las sucursales agrupado con la
+// estrategia de cruce y el momento en que se leyó de SQL Server. La lectura
+// se comparte entre estrategias: cambiar de cruce no vuelve a consultar.
+func (a *App) stocksAgrupados(ctx context.Context, estrategia string, sucursales []int) (stocksAgrupados, time.Time, error) {
+	ids := make([]int, len(sucursales))
+	copy(ids, sucursales)
+	sort.Ints(ids)
+	partes := make([]string, len(ids))
+	for i, id := range ids {
+		partes[i] = strconv.Itoa(id)
+	}
+
+	stocks, leidoEn, err := a.cache.stocks.Obtener(ctx, strings.Join(partes, ","), func(ctx context.Context) (*stocksSucursales, error) {
+		filas, err := a.Stocks.List(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		return &stocksSucursales{filas: filas}, nil
+	})
+	if err != nil {
+		return stocksAgrupados{}, leidoEn, err
+	}
+	return stocks.agrupados(estrategia), leidoEn, nil
+}
+
+// zetasMySQL devuelve las zetas distintas de saldos.
+func (a *App) zetasMySQL(ctx context.Context) ([]string, error) {
+	zetas, _, err := a.cache.zetas.Obtener(ctx, "mysql", a.Saldos.Zetas)
+	return zetas, err
+}
+
+// zetasSQLServer devuelve las zetas distintas con stock en la sucursal.
+func (a *App) zetasSQLServer(ctx context.Context, sucursal int) ([]string, error) {
+	clave := "sqlserver:" + strconv.Itoa(sucursal)
+	zetas, _, err := a.cache.zetas.Obtener(ctx, clave, func(ctx context.Context) ([]string, error) {
+		return a.Stocks.Zetas(ctx, []int{sucursal})
+	})
+	return zetas, err
+}
+
+// saldosDelAnio devuelve los saldos del año y el momento en que se leyeron
+// de MySQL.
+func (a *App) saldosDelAnio(ctx context.Context, year string) ([]models.SaldoData, time.Time, error) {
+	return a.cache.saldos.Obtener(ctx, year, func(ctx context.Context) ([]models.SaldoData, error) {
+		return a.Saldos.ListByYear(ctx, year)
+	})
+}
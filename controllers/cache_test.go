@@ -0,0 +1,303 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go_api/config"
+	"go_api/models"
+	"go_api/repository"
+)
+
+// cargaControlada es una carga de prueba que cuenta sus llamadas y espera a
+// que se libere.
+type cargaControlada struct {
+	llamadas atomic.Int32
+	liberar  chan struct{}
+	valor    atomic.Int32
+	err      error
+}
+
+func (c *cargaControlada) cargar(context.Context) (int, error) {
+	c.llamadas.Add(1)
+	<-c.liberar
+	return int(c.valor.Load()), c.err
+}
+
+// esperarLlamadas espera a que la carga se haya llamado n veces.
+func (c *cargaControlada) esperarLlamadas(t *testing.T, n int32) {
+	t.Helper()
+	limite := time.Now().Add(5 * time.Second)
+	for c.llamadas.Load() < n {
+		if time.Now().After(limite) {
+			t.Fatalf("la carga se llamó %d veces, se esperaban %d", c.llamadas.Load(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCacheUnaCargaPorClave(t *testing.T) {
+	cache := newCacheTTL[int]("prueba", time.Minute, time.Minute, time.Minute)
+	carga := &cargaControlada{liberar: make(chan struct{})}
+	carga.valor.Store(42)
+
+	const peticiones = 20
+	var wg sync.WaitGroup
+	resultados := make([]int, peticiones)
+	errores := make([]error, peticiones)
+	for i := 0; i < peticiones; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resultados[i], _, errores[i] = cache.Obtener(context.Background(), "2025", carga.cargar)
+		}(i)
+	}
+	carga.esperarLlamadas(t, 1)
+	close(carga.liberar)
+	wg.Wait()
+
+	if n := carga.llamadas.Load(); n != 1 {
+		t.Errorf("la carga se llamó %d veces, se esperaba una", n)
+	}
+	for i := range resultados {
+		if errores[i] != nil || resultados[i] != 42 {
+			t.Errorf("petición %d = %d, %v", i, resultados[i], errores[i])
+		}
+	}
+	// Vigente: se sirve sin cargar
+	if v, _, err := cache.Obtener(context.Background(), "2025", carga.cargar); err != nil || v != 42 || carga.llamadas.Load() != 1 {
+		t.Errorf("Obtener = %d, %v con %d cargas", v, err, carga.llamadas.Load())
+	}
+}
+
+func TestCacheCancelacionNoAfectaALasDemas(t *testing.T) {
+	cache := newCacheTTL[int]("prueba", time.Minute, time.Minute, time.Minute)
+	carga := &cargaControlada{liberar: make(chan struct{})}
+	carga.valor.Store(7)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCancelada := make(chan error, 1)
+	go func() {
+		_, _, err := cache.Obtener(ctx, "k", carga.cargar)
+		errCancelada <- err
+	}()
+	carga.esperarLlamadas(t, 1)
+	cancel()
+	if err := <-errCancelada; !errors.Is(err, context.Canceled) {
+		t.Errorf("la petición cancelada devolvió %v", err)
+	}
+
+	resultado := make(chan int, 1)
+	go func() {
+		v, _, _ := cache.Obtener(context.Background(), "k", carga.cargar)
+		resultado <- v
+	}()
+	close(carga.liberar)
+	if v := <-resultado; v != 7 {
+		t.Errorf("Obtener = %d, se esperaba 7", v)
+	}
+	if n := carga.llamadas.Load(); n != 1 {
+		t.Errorf("la carga se llamó %d veces, se esperaba una", n)
+	}
+}
+
+func TestCacheErrorNoSeGuarda(t *testing.T) {
+	cache := newCacheTTL[int]("prueba", time.Minute, time.Minute, time.Minute)
+	carga := &cargaControlada{liberar: make(chan struct{}), err: errors.New("sin conexión")}
+	close(carga.liberar)
+	if _, _, err := cache.Obtener(context.Background(), "k", carga.cargar); err == nil {
+		t.Fatal("se esperaba el error de la carga")
+	}
+	if _, _, err := cache.Obtener(context.Background(), "k", carga.cargar); err == nil || carga.llamadas.Load() != 2 {
+		t.Errorf("tras un error se esperaba otra carga: %v, %d cargas", err, carga.llamadas.Load())
+	}
+	if estado := cache.Estado(); len(estado) != 0 {
+		t.Errorf("Estado = %+v, se esperaba vacío", estado)
+	}
+}
+
+func TestCacheInvalidarDuranteLaCarga(t *testing.T) {
+	cache := newCacheTTL[int]("prueba", time.Minute, time.Minute, time.Minute)
+	vieja := &cargaControlada{liberar: make(chan struct{})}
+	vieja.valor.Store(1)
+
+	resultado := make(chan int, 1)
+	go func() {
+		v, _, _ := cache.Obtener(context.Background(), "k", vieja.cargar)
+		resultado <- v
+	}()
+	vieja.esperarLlamadas(t, 1)
+	cache.Invalidar()
+
+	// La petición siguiente no espera la carga previa: inicia otra
+	nueva := &cargaControlada{liberar: make(chan struct{})}
+	nueva.valor.Store(2)
+	close(nueva.liberar)
+	if v, _, err := cache.Obtener(context.Background(), "k", nueva.cargar); err != nil || v != 2 {
+		t.Fatalf("Obtener tras invalidar = %d, %v; se esperaba 2", v, err)
+	}
+
+	// La carga previa termina para quien la esperaba, pero no se guarda
+	close(vieja.liberar)
+	if v := <-resultado; v != 1 {
+		t.Errorf("la petición previa recibió %d, se esperaba 1", v)
+	}
+	otra := &cargaControlada{liberar: make(chan struct{})}
+	close(otra.liberar)
+	if v, _, _ := cache.Obtener(context.Background(), "k", otra.cargar); v != 2 || otra.llamadas.Load() != 0 {
+		t.Errorf("Obtener = %d con %d cargas, se esperaba el valor 2 guardado", v, otra.llamadas.Load())
+	}
+}
+
+func TestCacheObsoleto(t *testing.T) {
+	tests := []struct {
+		nombre    string
+		edad      time.Duration
+		want      int
+		refrescar bool
+	}{
+		{"vigente", 30 * time.Second, 1, false},
+		{"obsoleto", 90 * time.Second, 1, true},
+		{"vencido", 3 * time.Minute, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nombre, func(t *testing.T) {
+			cache := newCacheTTL[int]("prueba", time.Minute, time.Minute, time.Minute)
+			cache.entradas["k"] = cacheEntrada[int]{valor: 1, cargadoEn: time.Now().Add(-tt.edad)}
+			carga := &cargaControlada{liberar: make(chan struct{})}
+			carga.valor.Store(2)
+			close(carga.liberar)
+
+			if v, _, err := cache.Obtener(context.Background(), "k", carga.cargar); err != nil || v != tt.want {
+				t.Errorf("Obtener = %d, %v; se esperaba %d", v, err, tt.want)
+			}
+			if tt.refrescar {
+				carga.esperarLlamadas(t, 1)
+			} else if n := carga.llamadas.Load(); n != 0 {
+				t.Errorf("la carga se llamó %d veces, se esperaba ninguna", n)
+			}
+		})
+	}
+}
+
+func TestCacheLimiteDeEntradas(t *testing.T) {
+	cache := newCacheTTL[int]("prueba", time.Minute, time.Minute, time.Minute)
+	inicio := time.Now()
+	cache.mu.Lock()
+	for i := 0; i < maxEntradasCache; i++ {
+		cache.guardar(fmt.Sprint(i), cacheEntrada[int]{valor: i, cargadoEn: inicio.Add(time.Duration(i) * time.Millisecond)})
+	}
+	// Una vencida del todo se descarta antes que la más vieja vigente
+	cache.entradas["5"] = cacheEntrada[int]{valor: 5, cargadoEn: inicio.Add(-time.Hour)}
+	cache.guardar("nueva", cacheEntrada[int]{valor: -1, cargadoEn: inicio.Add(time.Second)})
+	_, hayVencida := cache.entradas["5"]
+	_, hayMasVieja := cache.entradas["0"]
+	cache.guardar("otra", cacheEntrada[int]{valor: -2, cargadoEn: inicio.Add(time.Second)})
+	_, quedaMasVieja := cache.entradas["0"]
+	n := len(cache.entradas)
+	cache.mu.Unlock()
+
+	if hayVencida || !hayMasVieja {
+		t.Errorf("se esperaba descartar la entrada vencida y conservar las vigentes")
+	}
+	if quedaMasVieja {
+		t.Errorf("se esperaba descartar la entrada vigente más vieja al llegar al límite")
+	}
+	if n != maxEntradasCache {
+		t.Errorf("entradas = %d, se esperaba el límite %d", n, maxEntradasCache)
+	}
+}
+
+func TestCacheCargaConPlazo(t *testing.T) {
+	cache := newCacheTTL[int]("prueba", time.Minute, time.Minute, 20*time.Millisecond)
+	_, _, err := cache.Obtener(context.Background(), "k", func(ctx context.Context) (int, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("la carga no tiene plazo")
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Obtener = %v, se esperaba que venciera el plazo de la carga", err)
+	}
+}
+
+func TestPlazoCarga(t *testing.T) {
+	tests := []struct {
+		timeouts []time.Duration
+		want     time.Duration
+	}{
+		{[]time.Duration{30 * time.Second}, 30 * time.Second},
+		{[]time.Duration{10 * time.Second, 45 * time.Second}, 45 * time.Second},
+		{[]time.Duration{0}, plazoCargaMaximo},
+		{[]time.Duration{10 * time.Second, 0}, plazoCargaMaximo},
+		{[]time.Duration{time.Hour}, plazoCargaMaximo},
+	}
+	for _, tt := range tests {
+		if got := plazoCarga(tt.timeouts...); got != tt.want {
+			t.Errorf("plazoCarga(%v) = %v, se esperaba %v", tt.timeouts, got, tt.want)
+		}
+	}
+}
+
+// stocksContados cuenta las lecturas de stock.
+type stocksContados struct {
+	repository.StockRepository
+	lecturas atomic.Int32
+}
+
+func (s *stocksContados) List(ctx context.Context, sucursales []int) ([]models.StockData, error) {
+	s.lecturas.Add(1)
+	return s.StockRepository.List(ctx, sucursales)
+}
+
+func TestStocksCompartidosEntreEstrategias(t *testing.T) {
+	saldos, stocks := repository.DemoData()
+	contados := &stocksContados{StockRepository: repository.NewMemoryStockRepository(stocks)}
+	app, err := NewAppWithRepositories(config.Config{CacheTTL: time.Minute, CacheStale: time.Minute},
+		repository.NewMemorySaldoRepository(saldos), contados)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	sucursales := []int{repository.SucursalPorDefecto, repository.SucursalPorDefecto + 1}
+
+	var primera time.Time
+	for i, estrategia := range append(estrategiasCruce, cruceZeta) {
+		// El orden de las sucursales no cambia la clave
+		if i%2 == 1 {
+			sucursales[0], sucursales[1] = sucursales[1], sucursales[0]
+		}
+		agrupados, leidoEn, err := app.stocksAgrupados(ctx, estrategia, sucursales)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			primera = leidoEn
+		} else if !leidoEn.Equal(primera) {
+			t.Errorf("%s: leído en %v, se esperaba la lectura compartida de %v", estrategia, leidoEn, primera)
+		}
+		filas, _ := contados.StockRepository.List(ctx, sucursales)
+		porSucursal, colisiones := agruparStocksPorSucursal(estrategia, filas)
+		if !reflect.DeepEqual(agrupados.PorSucursal, porSucursal) || !reflect.DeepEqual(agrupados.Colisiones, colisiones) {
+			t.Errorf("%s: el agrupado de la caché difiere del calculado", estrategia)
+		}
+	}
+	if n := contados.lecturas.Load(); n != 1 {
+		t.Errorf("el stock se leyó %d veces, se esperaba una para todas las estrategias", n)
+	}
+
+	app.cache.stocks.Invalidar()
+	if _, _, err := app.stocksAgrupados(ctx, cruceCodigo, sucursales); err != nil {
+		t.Fatal(err)
+	}
+	if n := contados.lecturas.Load(); n != 2 {
+		t.Errorf("tras invalidar el stock se leyó %d veces, se esperaban 2", n)
+	}
+}
@@ -1,151 +1,65 @@
 package controllers
 
 import (
-	"database/sql"
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
-	"strconv"
+	"net/url"
 	"strings"
 	"time"
+	"unicode"
 
-	"go_api/db"
 	"go_api/models"
+	"go_api/repository"
 	"go_api/views"
 
 	"sort"
-
-	"github.com/tealeg/xlsx"
 )
 
-// getStocksFromSQLServer obtiene datos de SQL Server.
-func getStocksFromSQLServer(db *sql.DB) ([]models.StockData, error) {
-	query := `
-        SELECT 
-            s.ID_SUCURSAL,
-            p.NOMBRE_PRODUCTO,
-            p.CODIGO_INTERNO AS Codigo_Producto,
-            s.ZETA,
-            s.FECHA,
-            p.PRECIO_VENTA,
-            p.PRECIO_OFERTA,
-            s.COSTO_UNITARIO,
-            s.ANIO
-        FROM STOCKS s
-        INNER JOIN PRODUCTO p 
-            ON s.ID_PRODUCTO = p.ID_PRODUCTO
-        WHERE p.ACTIVO = 1 AND s.ID_SUCURSAL = 211
-    `
-	rows, err := db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var stocks []models.StockData
-	for rows.Next() {
-		var s models.StockData
-		if err := rows.Scan(&s.IDSucursal, &s.NombreProducto, &s.CodigoProducto, &s.Zeta,
-			&s.Fecha, &s.PrecioVenta, &s.PrecioOferta, &s.CostoUnitario, &s.Anio); err != nil {
-			return nil, err
-		}
-		stocks = append(stocks, s)
-	}
-	return stocks, nil
-}
-
-// getSaldosFromMySQL obtiene saldos desde MySQL modificando el escaneo de Fecha_Ingreso.
-func getSaldosFromMySQL(db *sql.DB, year string) ([]models.SaldoData, error) {
-	query := `
-        SELECT 
-            COD_ART AS Codigo_Producto,
-            ZET_ART AS Zeta,
-            ANIO_PRO AS Año_Produccion,
-            DES_INT AS Nombre_Producto, 
-            UNI_CAJ AS Unidad_Caja,
-            CIF_UNI AS Costo_CIF,
-            cos_uni AS Costo_Real,
-            MAX(FEC_ING) AS Fecha_Ingreso,    
-            SUM(CAN_ING) AS Cantidad_Ingresada, 
-            MAX(SAL_ANT) AS Saldo_Anterior,   
-            DATEDIFF(CURDATE(), MAX(FEC_ING)) AS Dias_Desde_Ingreso
-        FROM saldos 
-        WHERE ANIO_PRO = ?  
-        GROUP BY COD_ART, ZET_ART, ANIO_PRO, DES_INT, UNI_CAJ, CIF_UNI, cos_uni
-        ORDER BY ANIO_PRO, COD_ART
-    `
-
-	rows, err := db.Query(query, year)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var saldos []models.SaldoData
-	for rows.Next() {
-		var s models.SaldoData
-		var fechaIngresoBytes []byte
-		var dias sql.NullInt64
-		err := rows.Scan(
-			&s.CodigoProducto,
-			&s.Zeta,
-			&s.AnioProduccion,
-			&s.NombreProducto,
-			&s.UnidadCaja,
-			&s.CostoCIF,
-			&s.CostoReal,
-			&fechaIngresoBytes,
-			&s.CantidadIngresada,
-			&s.SaldoAnterior,
-			&dias,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		// Procesar fecha
-		fechaStr := string(fechaIngresoBytes)
-		if fechaStr == "" {
-			s.FechaIngreso = time.Time{}
-		} else {
-			s.FechaIngreso, err = time.Parse("2006-01-02", fechaStr)
-			if err != nil {
-				s.FechaIngreso, err = time.Parse("2006-01-02 15:04:05", fechaStr)
-				if err != nil {
-					return nil, err
-				}
-			}
-		}
-
-		if dias.Valid {
-			s.DiasDesdeIngreso = int(dias.Int64)
+// agruparStocksPorSucursal agrupa los stocks por sucursal y, dentro de cada
+// una, por clave de cruce. Devuelve también las colisiones de todas las
+// sucursales.
+func agruparStocksPorSucursal(estrategia string, stocks []models.StockData) (map[int]map[string]models.StockData, []models.ColisionCruce) {
+	porSucursal := make(map[int][]models.StockData)
+	var ids []int
+	for _, s := range stocks {
+		if _, ok := porSucursal[s.IDSucursal]; !ok {
+			ids = append(ids, s.IDSucursal)
 		}
-
-		saldos = append(saldos, s)
+		porSucursal[s.IDSucursal] = append(porSucursal[s.IDSucursal], s)
 	}
+	sort.Ints(ids)
 
-	return saldos, nil
-}
-
-// agruparStocksPorZeta agrupa la información de stocks por zeta.
-func agruparStocksPorZeta(stocks []models.StockData) map[string]models.StockData {
-	agrupados := make(map[string]models.StockData)
-	for _, s := range stocks {
-		if actual, existe := agrupados[s.Zeta]; !existe || s.Fecha.After(actual.Fecha) {
-			agrupados[s.Zeta] = s
-		}
+	agrupados := make(map[int]map[string]models.StockData, len(porSucursal))
+	var colisiones []models.ColisionCruce
+	for _, id := range ids {
+		var c []models.ColisionCruce
+		agrupados[id], c = agruparStocks(estrategia, porSucursal[id])
+		colisiones = append(colisiones, c...)
 	}
-	return agrupados
+	return agrupados, colisiones
 }
 
-// fusionarDatos combina la información de stocks y saldos.
-func fusionarDatos(stocksMap map[string]models.StockData, saldos []models.SaldoData) []models.CombinedData {
+// fusionarDatos combina la información de stocks y saldos. Cada saldo genera
+// una fila por cada sucursal (en el orden indicado) que tenga stock con su
+// misma clave de cruce.
+func fusionarDatos(estrategia string, stocksPorSucursal map[int]map[string]models.StockData, sucursales []int, saldos []models.SaldoData) []models.CombinedData {
 	var resultados []models.CombinedData
 	for _, saldo := range saldos {
-		if stock, ok := stocksMap[saldo.Zeta]; ok {
+		clave := claveSaldo(estrategia, saldo)
+		encontrado := false
+		for _, sucursal := range sucursales {
+			stock, ok := stocksPorSucursal[sucursal][clave]
+			if !ok {
+				continue
+			}
+			encontrado = true
 			combinado := models.CombinedData{
+				EstrategiaCruce: estrategia,
+				ClaveCruce:      clave,
 				// Datos de SQL Server:
+				IDSucursal:     stock.IDSucursal,
 				CodigoProducto: stock.CodigoProducto,
 				Zeta:           stock.Zeta,
 				AnioProduccion: stock.Anio,
@@ -173,254 +87,788 @@ func fusionarDatos(stocksMap map[string]models.StockData, saldos []models.SaldoD
 				SaldoFinNoviembre:  saldo.SaldoFinNoviembre,
 				SaldoFinDiciembre:  saldo.SaldoFinDiciembre,
 			}
+			combinado.CalcularMargenes()
 			resultados = append(resultados, combinado)
-		} else {
-			log.Printf("No se encontró registro en SQL Server para zeta %s", saldo.Zeta)
+		}
+		if !encontrado {
+			log.Printf("No se encontró registro en SQL Server para la clave %s (cruce %s)", clave, estrategia)
 		}
 	}
 	return resultados
 }
 
-// CombinedDataHandler utiliza las conexiones inicializadas en db/mysql.go y db/sqlserver.go.
-func CombinedDataHandler(w http.ResponseWriter, r *http.Request) {
-	// Utilizar conexión global a SQL Server
-	if db.SQLServerDB == nil {
+// combinedDataset reúne los datos de un año y un conjunto de sucursales ya
+// fusionados.
+type combinedDataset struct {
+	Sucursales        []int
+	Estrategia        string // estrategia de cruce usada
+	Saldos            []models.SaldoData
+	StocksPorSucursal map[int]map[string]models.StockData // por sucursal y clave de cruce
+	Colisiones        []models.ColisionCruce
+	Resultados        []models.CombinedData
+	DatosAl           models.DatosAl
+}
+
+// tieneStock indica si el saldo tiene stock con su clave de cruce en alguna
+// de las sucursales.
+func (ds combinedDataset) tieneStock(saldo models.SaldoData) bool {
+	clave := claveSaldo(ds.Estrategia, saldo)
+	for _, sucursal := range ds.Sucursales {
+		if _, ok := ds.StocksPorSucursal[sucursal][clave]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCombined obtiene stocks de las sucursales y saldos del año (desde la
+// caché si están vigentes) y los fusiona con la estrategia de cruce pedida en
+// "cruce". Informa la fecha de los datos en las cabeceras X-Stocks-Al y
+// X-Saldos-Al. Si el cruce es inválido o alguna de las bases falla (o la
+// petición se cancela) responde con el error y devuelve false.
+func (a *App) loadCombined(w http.ResponseWriter, r *http.Request, year string, sucursales []int) (combinedDataset, bool) {
+	ds := combinedDataset{Sucursales: sucursales}
+
+	var ok bool
+	if ds.Estrategia, ok = a.prepararCombinado(w, r); !ok {
+		return ds, false
+	}
+	stocks, stocksAl, err := a.stocksAgrupados(r.Context(), ds.Estrategia, sucursales)
+	if err != nil {
+		respondDataError(w, "Error obteniendo stocks", err)
+		return ds, false
+	}
+	ds.StocksPorSucursal, ds.Colisiones = stocks.PorSucursal, stocks.Colisiones
+	ds.DatosAl.Stocks = stocksAl
+
+	ds.Saldos, ds.DatosAl.Saldos, err = a.saldosDelAnio(r.Context(), year)
+	if err != nil {
+		respondDataError(w, "Error obteniendo saldos", err)
+		return ds, false
+	}
+	w.Header().Set("X-Stocks-Al", ds.DatosAl.Stocks.Format(time.RFC3339))
+	w.Header().Set("X-Saldos-Al", ds.DatosAl.Saldos.Format(time.RFC3339))
+
+	ds.Resultados = fusionarDatos(ds.Estrategia, ds.StocksPorSucursal, sucursales, ds.Saldos)
+	return ds, true
+}
+
+// prepararCombinado lee la estrategia de cruce y verifica que ambas bases
+// estén disponibles. Si no, responde con el error y devuelve false.
+func (a *App) prepararCombinado(w http.ResponseWriter, r *http.Request) (string, bool) {
+	estrategia, err := parseCruce(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+
+	// Utilizar el repositorio de stocks de SQL Server
+	if a.Stocks == nil {
 		http.Error(w, "Conexión a SQL Server no inicializada", http.StatusInternalServerError)
 		log.Println("Conexión a SQL Server no inicializada")
+		return "", false
+	}
+
+	// Utilizar el repositorio de saldos de MySQL
+	if a.Saldos == nil {
+		http.Error(w, "Conexión a MySQL no inicializada", http.StatusInternalServerError)
+		log.Println("Conexión a MySQL no inicializada")
+		return "", false
+	}
+	return estrategia, true
+}
+
+// Modos de paginación de los datos combinados (parámetro "paginar").
+const (
+	// paginadoPorSaldos pagina los saldos en MySQL y lee de SQL Server solo
+	// los stocks de la página.
+	paginadoPorSaldos = "saldos"
+	// paginadoPorFilas carga todo el año y pagina en memoria las filas
+	// combinadas.
+	paginadoPorFilas = "filas"
+)
+
+// motivoEnMemoria devuelve por qué la página no puede resolverse paginando
+// los saldos en MySQL, o "" si puede: todos los campos del orden deben ser de
+// saldos y no puede haber filtro de margen, que depende de los precios de SQL
+// Server. El año se excluye porque en los datos combinados es el del stock.
+// Las condiciones de la expresión de filtro solo pueden ser sobre campos que
+// no se agregan por producto, porque MySQL las aplica antes de agrupar.
+func motivoEnMemoria(orden repository.Orden, margen filtroMargen, expr repository.ExpresionFiltro) string {
+	for _, c := range orden {
+		if !repository.EsCampoSaldo(c.Campo) || c.Campo == "AnioProduccion" {
+			return fmt.Sprintf("el orden por %s usa datos de SQL Server", c.Campo)
+		}
+	}
+	for _, c := range expr.Condiciones {
+		switch {
+		case repository.EsCampoSaldo(c.Campo) && c.Campo != "AnioProduccion" && !repository.EsCampoDeProducto(c.Campo):
+			return fmt.Sprintf("el filtro por %s se aplica al total agrupado por producto", c.Campo)
+		case !repository.EsCampoDeProducto(c.Campo) || c.Campo == "AnioProduccion":
+			return fmt.Sprintf("el filtro por %s usa datos de SQL Server", c.Campo)
+		}
+	}
+	if margen.activo() {
+		return "el filtro de margen usa los precios de SQL Server"
+	}
+	return ""
+}
+
+// modoCombinado lee "paginar" y decide cómo armar los datos combinados: por
+// defecto (o con paginar=saldos) pagina los saldos en MySQL, salvo que el
+// orden o los filtros lo impidan, y entonces pagina las filas en memoria y
+// devuelve el motivo. Con paginar=filas pagina siempre las filas en memoria.
+func modoCombinado(query url.Values, orden repository.Orden, margen filtroMargen, expr repository.ExpresionFiltro) (modo, motivo string, err error) {
+	switch v := query.Get("paginar"); v {
+	case "", paginadoPorSaldos:
+		if motivo := motivoEnMemoria(orden, margen, expr); motivo != "" {
+			return paginadoPorFilas, motivo, nil
+		}
+		return paginadoPorSaldos, "", nil
+	case paginadoPorFilas:
+		return paginadoPorFilas, "", nil
+	default:
+		return "", "", fmt.Errorf("paginar inválido: %q (admite saldos o filas)", v)
+	}
+}
+
+// maxClavesPorConsulta limita las claves de cada consulta de stocks por
+// página, por debajo del máximo de parámetros de SQL Server.
+const maxClavesPorConsulta = 1000
+
+// loadCombinedPage obtiene solo la página pedida: pagina en MySQL los saldos
+// del año con la expresión de filtro y el orden, lee de SQL Server los stocks
+// de las claves de esa página y los fusiona. Devuelve también el total de
+// saldos que cumplen la búsqueda (cada saldo puede dar una fila por sucursal
+// o ninguna). Las colisiones y los saldos sin stock del dataset son solo los
+// de la página. No usa la caché: ambas consultas son acotadas.
+func (a *App) loadCombinedPage(w http.ResponseWriter, r *http.Request, year string, sucursales []int, params listParams, expr repository.ExpresionFiltro, orden repository.Orden) (combinedDataset, int, bool) {
+	estrategia, ok := a.prepararCombinado(w, r)
+	if !ok {
+		return combinedDataset{Sucursales: sucursales}, 0, false
+	}
+
+	filter := repository.SaldoFilter{Palabras: expr.Palabras, Condiciones: expr.Condiciones}
+	saldos, total, err := a.Saldos.ListByYearPaginated(r.Context(), year, params.Offset(), params.PageSize, filter, orden)
+	if err != nil {
+		respondDataError(w, "Error obteniendo saldos", err)
+		return combinedDataset{Sucursales: sucursales}, 0, false
+	}
+	saldosAl := time.Now()
+
+	ds, err := a.combinarSaldos(r.Context(), estrategia, sucursales, saldos)
+	if err != nil {
+		respondDataError(w, "Error obteniendo stocks", err)
+		return ds, 0, false
+	}
+	ds.DatosAl.Saldos = saldosAl
+	w.Header().Set("X-Stocks-Al", ds.DatosAl.Stocks.Format(time.RFC3339))
+	w.Header().Set("X-Saldos-Al", ds.DatosAl.Saldos.Format(time.RFC3339))
+	return ds, total, true
+}
+
+// combinarSaldos lee de SQL Server los stocks de las claves de cruce de los
+// saldos (zetas, o códigos si el cruce es solo por código) y los fusiona con
+// ellos.
+func (a *App) combinarSaldos(ctx context.Context, estrategia string, sucursales []int, saldos []models.SaldoData) (combinedDataset, error) {
+	ds := combinedDataset{Sucursales: sucursales, Estrategia: estrategia, Saldos: saldos}
+
+	var claves []string
+	vistas := make(map[string]bool)
+	for _, s := range saldos {
+		clave := s.Zeta
+		if estrategia == cruceCodigo {
+			clave = s.CodigoProducto
+		}
+		if !vistas[clave] {
+			vistas[clave] = true
+			claves = append(claves, clave)
+		}
+	}
+
+	var stocks []models.StockData
+	for inicio := 0; inicio < len(claves); inicio += maxClavesPorConsulta {
+		fin := inicio + maxClavesPorConsulta
+		if fin > len(claves) {
+			fin = len(claves)
+		}
+		stockFilter := repository.StockFilter{Sucursales: sucursales, Zetas: claves[inicio:fin]}
+		if estrategia == cruceCodigo {
+			stockFilter = repository.StockFilter{Sucursales: sucursales, Codigos: claves[inicio:fin]}
+		}
+		parte, err := a.Stocks.Historial(ctx, stockFilter)
+		if err != nil {
+			return ds, err
+		}
+		stocks = append(stocks, parte...)
+	}
+	ds.DatosAl.Stocks = time.Now()
+
+	ds.StocksPorSucursal, ds.Colisiones = agruparStocksPorSucursal(estrategia, stocks)
+	ds.Resultados = fusionarDatos(estrategia, ds.StocksPorSucursal, sucursales, saldos)
+	if ds.Resultados == nil {
+		ds.Resultados = []models.CombinedData{}
+	}
+	return ds, nil
+}
+
+// saldosSinCorrespondencia devuelve los saldos cuya zeta no existe en SQL
+// Server para ninguna de las sucursales y cuya zeta o nombre contiene cada una
+// de las palabras.
+func saldosSinCorrespondencia(ds combinedDataset, palabras []string) []models.SaldoData {
+	missing := make([]models.SaldoData, 0)
+	for _, saldo := range ds.Saldos {
+		if !ds.tieneStock(saldo) && repository.ContienePalabras(palabras, saldo.Zeta, saldo.NombreProducto) {
+			missing = append(missing, saldo)
+		}
+	}
+	return missing
+}
+
+// CombinedDataHandler maneja /api/combined y devuelve los datos combinados
+// paginados con los mismos parámetros que la vista /combined. Por defecto
+// pagina los saldos en MySQL y responde con combinedPageResponse; si el orden
+// o los filtros usan datos de SQL Server, o con paginar=filas, pagina las
+// filas en memoria y responde con combinedFilasResponse.
+func (a *App) CombinedDataHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	params, err := parseListParams(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	stocks, err := getStocksFromSQLServer(db.SQLServerDB)
+	sucursales, err := parseSucursales(query, a.Config.SucursalPorDefecto)
 	if err != nil {
-		http.Error(w, "Error obteniendo stocks", http.StatusInternalServerError)
-		log.Println("Error obteniendo stocks:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	year, err := parseYear(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	stocksMap := agruparStocksPorZeta(stocks)
 
-	// Utilizar conexión global a MySQL
-	if db.MySQLDB == nil {
-		http.Error(w, "Conexión a MySQL no inicializada", http.StatusInternalServerError)
-		log.Println("Conexión a MySQL no inicializada")
+	margen, err := parseFiltroMargen(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	saldos, err := getSaldosFromMySQL(db.MySQLDB, "2025")
+	orden, err := params.ordenCombinado()
 	if err != nil {
-		http.Error(w, "Error obteniendo saldos", http.StatusInternalServerError)
-		log.Println("Error obteniendo saldos:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	expr, err := params.filtroCombinado()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	modo, motivo, err := modoCombinado(query, orden, margen, expr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if modo == paginadoPorSaldos {
+		ds, total, ok := a.loadCombinedPage(w, r, year, sucursales, params, expr, orden)
+		if !ok {
+			return
+		}
+		colisiones := ds.Colisiones
+		if colisiones == nil {
+			colisiones = []models.ColisionCruce{}
+		}
+		writeJSON(w, combinedPageResponse{
+			Data:             ds.Resultados,
+			PaginadoPor:      paginadoPorSaldos,
+			TotalSaldos:      total,
+			Page:             params.Page,
+			PageSize:         params.PageSize,
+			TotalPages:       params.TotalPages(total),
+			MissingPagina:    saldosSinCorrespondencia(ds, nil),
+			ColisionesPagina: colisiones,
+			DatosAl:          &ds.DatosAl,
+		})
 		return
 	}
 
-	// Fusionar datos y devolver JSON
-	resultados := fusionarDatos(stocksMap, saldos)
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resultados); err != nil {
-		http.Error(w, "Error codificando la respuesta", http.StatusInternalServerError)
-		log.Println("Error codificando la respuesta:", err)
+	ds, ok := a.loadCombined(w, r, year, sucursales)
+	if !ok {
+		return
 	}
+
+	filtered := filterAndSortResults(ds.Resultados, expr, margen, orden)
+	start, end := params.Bounds(len(filtered))
+
+	writeJSON(w, combinedFilasResponse{
+		Data:        filtered[start:end],
+		PaginadoPor: paginadoPorFilas,
+		EnMemoria:   true,
+		Motivo:      motivo,
+		Total:       len(filtered),
+		Page:        params.Page,
+		PageSize:    params.PageSize,
+		TotalPages:  params.TotalPages(len(filtered)),
+		DatosAl:     &ds.DatosAl,
+	})
 }
 
-// CombinedViewHandler ahora envuelve los datos paginados en una estructura con campos para la plantilla.
-func CombinedViewHandler(w http.ResponseWriter, r *http.Request) {
-	// Obtener parámetros de la URL
+// CombinedMissingHandler maneja /api/combined/missing y devuelve los saldos
+// del año cuya zeta no tiene correspondencia en SQL Server.
+func (a *App) CombinedMissingHandler(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
-	page, _ := strconv.Atoi(query.Get("page"))
-	if page < 1 {
-		page = 1
+	params, err := parseListParams(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sucursales, err := parseSucursales(query, a.Config.SucursalPorDefecto)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	year, err := parseYear(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	pageSize, _ := strconv.Atoi(query.Get("pageSize"))
-	if pageSize < 1 {
-		pageSize = 25
+	ds, ok := a.loadCombined(w, r, year, sucursales)
+	if !ok {
+		return
 	}
 
-	search := query.Get("search")
-	missingSearch := query.Get("missingSearch")
-	sortField := query.Get("sort")
-	sortDir := query.Get("dir")
+	missing := saldosSinCorrespondencia(ds, strings.Fields(params.Search))
+	start, end := params.Bounds(len(missing))
 
-	if sortDir != "desc" {
-		sortDir = "asc"
-	}
+	writeJSON(w, paginatedResponse{
+		Data:       missing[start:end],
+		Total:      len(missing),
+		Page:       params.Page,
+		PageSize:   params.PageSize,
+		TotalPages: params.TotalPages(len(missing)),
+		DatosAl:    &ds.DatosAl,
+	})
+}
 
-	// Obtener año de la URL, default 2025
-	year := r.URL.Query().Get("year")
-	if year == "" {
-		year = "2025"
+// CombinedCollisionsHandler maneja /api/combined/colisiones y devuelve las
+// claves de cruce que corresponden a más de un registro de SQL Server.
+func (a *App) CombinedCollisionsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	params, err := parseListParams(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-
-	// Obtener datos...
-	stocks, err := getStocksFromSQLServer(db.SQLServerDB)
+	sucursales, err := parseSucursales(query, a.Config.SucursalPorDefecto)
 	if err != nil {
-		http.Error(w, "Error obteniendo stocks", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	// Pasar el año a la función getSaldosFromMySQL
-	saldos, err := getSaldosFromMySQL(db.MySQLDB, year)
+	year, err := parseYear(query)
 	if err != nil {
-		http.Error(w, "Error obteniendo saldos", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	stocksMap := agruparStocksPorZeta(stocks)
-	resultados := fusionarDatos(stocksMap, saldos)
+	ds, ok := a.loadCombined(w, r, year, sucursales)
+	if !ok {
+		return
+	}
 
-	// Filtrar y ordenar resultados
-	filteredResults := filterAndSortResults(resultados, search, sortField, sortDir)
+	colisiones := ds.Colisiones
+	if colisiones == nil {
+		colisiones = []models.ColisionCruce{}
+	}
+	start, end := params.Bounds(len(colisiones))
+
+	writeJSON(w, paginatedResponse{
+		Data:       colisiones[start:end],
+		Total:      len(colisiones),
+		Page:       params.Page,
+		PageSize:   params.PageSize,
+		TotalPages: params.TotalPages(len(colisiones)),
+		DatosAl:    &ds.DatosAl,
+	})
+}
+
+// CombinedViewHandler ahora envuelve los datos paginados en una estructura con campos para la plantilla.
+func (a *App) CombinedViewHandler(w http.ResponseWriter, r *http.Request) {
+	// Obtener parámetros de la URL
+	query := r.URL.Query()
+	params, err := parseListParams(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	missingSearch := query.Get("missingSearch")
+	sucursales, err := parseSucursales(query, a.Config.SucursalPorDefecto)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	year, err := parseYear(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Calcular paginación
-	total := len(filteredResults)
-	totalPages := (total + pageSize - 1) / pageSize
-	start := (page - 1) * pageSize
-	end := start + pageSize
-	if end > total {
-		end = total
+	margen, err := parseFiltroMargen(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	orden, err := params.ordenCombinado()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Obtener registros faltantes
-	var missing []models.SaldoData
-	for _, saldo := range saldos {
-		if _, ok := stocksMap[saldo.Zeta]; !ok {
-			if missingSearch == "" ||
-				strings.Contains(strings.ToLower(saldo.Zeta), strings.ToLower(missingSearch)) ||
-				strings.Contains(strings.ToLower(saldo.NombreProducto), strings.ToLower(missingSearch)) {
-				missing = append(missing, saldo)
-			}
+	// Obtener datos del año y las sucursales indicadas: solo la página, salvo
+	// que el orden o los filtros usen datos de SQL Server o se pida paginar
+	// las filas; entonces todo el año para filtrar en memoria, como
+	// /api/combined. Con un error en la expresión de filtro se muestra el
+	// error sin datos.
+	var ds combinedDataset
+	var data []models.CombinedData
+	var total int
+	var errorFiltro string
+	expr, errExpr := params.filtroCombinado()
+	modo, motivo, err := modoCombinado(query, orden, margen, expr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch {
+	case errExpr != nil:
+		ds.Sucursales = sucursales
+		errorFiltro = errExpr.Error()
+	case modo == paginadoPorSaldos:
+		var ok bool
+		if ds, total, ok = a.loadCombinedPage(w, r, year, sucursales, params, expr, orden); !ok {
+			return
 		}
+		data = ds.Resultados
+	default:
+		var ok bool
+		if ds, ok = a.loadCombined(w, r, year, sucursales); !ok {
+			return
+		}
+		filteredResults := filterAndSortResults(ds.Resultados, expr, margen, orden)
+		total = len(filteredResults)
+		start, end := params.Bounds(total)
+		data = filteredResults[start:end]
+	}
+
+	// Sucursales disponibles para el selector; si falla se muestran solo las pedidas
+	disponibles, err := a.Stocks.Sucursales(r.Context())
+	if err != nil {
+		log.Println("Error obteniendo sucursales:", err)
 	}
 
 	viewData := views.CombinedViewData{
-		Data:          filteredResults[start:end],
-		Missing:       missing,
-		CurrentPage:   page,
-		TotalPages:    totalPages,
-		PageSize:      pageSize,
-		Search:        search,
+		Data:          data,
+		Missing:       saldosSinCorrespondencia(ds, strings.Fields(missingSearch)),
+		PaginarFilas:  query.Get("paginar") == paginadoPorFilas,
+		PorSaldo:      modo == paginadoPorSaldos,
+		MotivoMemoria: motivo,
+		CurrentPage:   params.Page,
+		TotalPages:    params.TotalPages(total),
+		PageSize:      params.PageSize,
+		Search:        params.Search,
+		ErrorFiltro:   errorFiltro,
 		MissingSearch: missingSearch,
-		SortField:     sortField,
-		SortDir:       sortDir,
+		SortField:     params.SortField,
+		SortDir:       params.SortDir,
 		Year:          year, // Agregar el año a los datos de la vista
+		Cruce:         ds.Estrategia,
+		Estrategias:   estrategiasCruce,
+		Colisiones:    ds.Colisiones,
+		DatosAl:       ds.DatosAl,
+		Margen:        margen.Campo,
+		MargenMin:     query.Get("margenMin"),
+		MargenMax:     query.Get("margenMax"),
+		BajoCosto:     margen.BajoCosto,
+		SucursalesFiltro: views.SucursalesFiltro{
+			Sucursales:  sucursales,
+			Disponibles: disponibles,
+		},
+	}
+
+	a.Views.RenderCombined(w, viewData)
+}
+
+// camposNumericosCombinados son los campos de precio, costo y margen por los
+// que se pueden ordenar y filtrar los datos combinados.
+var camposNumericosCombinados = map[string]func(models.CombinedData) float64{
+	"PrecioVenta":         func(c models.CombinedData) float64 { return c.PrecioVenta },
+	"PrecioOferta":        func(c models.CombinedData) float64 { return c.PrecioOferta },
+	"CostoCIF":            func(c models.CombinedData) float64 { return c.CostoCIF },
+	"CostoReal":           func(c models.CombinedData) float64 { return c.CostoReal },
+	"MargenVentaReal":     func(c models.CombinedData) float64 { return c.MargenVentaReal },
+	"MargenVentaCIF":      func(c models.CombinedData) float64 { return c.MargenVentaCIF },
+	"MargenOfertaReal":    func(c models.CombinedData) float64 { return c.MargenOfertaReal },
+	"MargenOfertaCIF":     func(c models.CombinedData) float64 { return c.MargenOfertaCIF },
+	"MargenPctVentaReal":  func(c models.CombinedData) float64 { return c.MargenPctVentaReal },
+	"MargenPctVentaCIF":   func(c models.CombinedData) float64 { return c.MargenPctVentaCIF },
+	"MargenPctOfertaReal": func(c models.CombinedData) float64 { return c.MargenPctOfertaReal },
+	"MargenPctOfertaCIF":  func(c models.CombinedData) float64 { return c.MargenPctOfertaCIF },
+	"MarkupVentaReal":     func(c models.CombinedData) float64 { return c.MarkupVentaReal },
+	"MarkupVentaCIF":      func(c models.CombinedData) float64 { return c.MarkupVentaCIF },
+	"MarkupOfertaReal":    func(c models.CombinedData) float64 { return c.MarkupOfertaReal },
+	"MarkupOfertaCIF":     func(c models.CombinedData) float64 { return c.MarkupOfertaCIF },
+}
+
+// filtroMargen restringe los datos combinados por el valor de uno de los
+// campos numéricos y, opcionalmente, a las ofertas por debajo del costo real.
+type filtroMargen struct {
+	Campo     string // clave de camposNumericosCombinados
+	Min, Max  *float64
+	BajoCosto bool
+}
+
+// activo indica si el filtro restringe alguna fila.
+func (f filtroMargen) activo() bool {
+	return f.BajoCosto || f.Min != nil || f.Max != nil
+}
+
+// match indica si la fila cumple el filtro.
+func (f filtroMargen) match(c models.CombinedData) bool {
+	if f.BajoCosto && !c.OfertaBajoCosto() {
+		return false
+	}
+	if f.Min == nil && f.Max == nil {
+		return true
+	}
+	valor := camposNumericosCombinados[f.Campo](c)
+	if f.Min != nil && valor < *f.Min {
+		return false
+	}
+	if f.Max != nil && valor > *f.Max {
+		return false
+	}
+	return true
+}
+
+// camposFiltroCombinado son los campos de las expresiones de filtro de los
+// datos combinados: los de saldos, la sucursal y los precios y márgenes, con
+// el nombre en minúsculas separado por guiones bajos (margen_pct_venta_real).
+var camposFiltroCombinado = func() map[string]repository.CampoFiltro {
+	campos := map[string]repository.CampoFiltro{
+		"sucursal": {Campo: "IDSucursal", Tipo: repository.CampoNumero},
+	}
+	for nombre, campo := range repository.CamposFiltroSaldo {
+		campos[nombre] = campo
+	}
+	for campo := range camposNumericosCombinados {
+		campos[nombreFiltro(campo)] = repository.CampoFiltro{Campo: campo, Tipo: repository.CampoNumero}
+	}
+	return campos
+}()
+
+// nombreFiltro pasa un nombre de campo como MarkupVentaCIF a markup_venta_cif.
+func nombreFiltro(campo string) string {
+	var b strings.Builder
+	anterior := ' '
+	for _, c := range campo {
+		if unicode.IsUpper(c) && unicode.IsLower(anterior) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(c))
+		anterior = c
 	}
+	return b.String()
+}
+
+// esCampoCombinado indica si nombre es un campo ordenable de los datos
+// combinados: la sucursal, los precios y márgenes o un campo de saldos.
+func esCampoCombinado(nombre string) bool {
+	_, numerico := camposNumericosCombinados[nombre]
+	return nombre == "IDSucursal" || numerico || repository.EsCampoSaldo(nombre)
+}
+
+// valorCombinado devuelve el valor del campo ordenable nombre en la fila. Los
+// campos de saldos se leen con la misma tabla que usan las consultas.
+func valorCombinado(c models.CombinedData, nombre string) interface{} {
+	if nombre == "IDSucursal" {
+		return c.IDSucursal
+	}
+	if valor, ok := camposNumericosCombinados[nombre]; ok {
+		return valor(c)
+	}
+	return repository.ValorCampoSaldo(nombre, models.Saldo{
+		CodigoProducto:     c.CodigoProducto,
+		Zeta:               c.Zeta,
+		AnioProduccion:     c.AnioProduccion,
+		NombreProducto:     c.NombreProducto,
+		UnidadCaja:         c.UnidadCaja,
+		CostoCIF:           c.CostoCIF,
+		CostoReal:          c.CostoReal,
+		FechaIngreso:       c.FechaIngreso,
+		CantidadIngresada:  c.CantidadIngresada,
+		SaldoAnterior:      c.SaldoAnterior,
+		DiasDesdeIngreso:   c.DiasDesdeIngreso,
+		SaldoFinEnero:      c.SaldoFinEnero,
+		SaldoFinFebrero:    c.SaldoFinFebrero,
+		SaldoFinMarzo:      c.SaldoFinMarzo,
+		SaldoFinAbril:      c.SaldoFinAbril,
+		SaldoFinMayo:       c.SaldoFinMayo,
+		SaldoFinJunio:      c.SaldoFinJunio,
+		SaldoFinJulio:      c.SaldoFinJulio,
+		SaldoFinAgosto:     c.SaldoFinAgosto,
+		SaldoFinSeptiembre: c.SaldoFinSeptiembre,
+		SaldoFinOctubre:    c.SaldoFinOctubre,
+		SaldoFinNoviembre:  c.SaldoFinNoviembre,
+		SaldoFinDiciembre:  c.SaldoFinDiciembre,
+	})
+}
 
-	views.RenderCombined(w, viewData)
+// cumpleCondiciones indica si la fila cumple todas las condiciones.
+func cumpleCondiciones(c models.CombinedData, condiciones []repository.CondicionFiltro) bool {
+	for _, cond := range condiciones {
+		if !cond.Cumple(valorCombinado(c, cond.Campo)) {
+			return false
+		}
+	}
+	return true
 }
 
-// Función auxiliar para filtrar y ordenar resultados
-func filterAndSortResults(results []models.CombinedData, search, sortField, sortDir string) []models.CombinedData {
+// Función auxiliar para filtrar y ordenar resultados. Las condiciones de la
+// expresión se evalúan sobre los valores de valorCombinado. Sin orden se
+// ordena por código.
+func filterAndSortResults(results []models.CombinedData, expr repository.ExpresionFiltro, margen filtroMargen, orden repository.Orden) []models.CombinedData {
 	// Filtrar primero
 	filtered := make([]models.CombinedData, 0)
-	searchLower := strings.ToLower(search)
-
 	for _, item := range results {
-		if search == "" ||
-			strings.Contains(strings.ToLower(item.CodigoProducto), searchLower) ||
-			strings.Contains(strings.ToLower(item.NombreProducto), searchLower) ||
-			strings.Contains(strings.ToLower(item.Zeta), searchLower) {
+		if !margen.match(item) || !cumpleCondiciones(item, expr.Condiciones) {
+			continue
+		}
+		if repository.ContienePalabras(expr.Palabras, item.CodigoProducto, item.NombreProducto, item.Zeta) {
 			filtered = append(filtered, item)
 		}
 	}
 
-	// Ordenar después
-	sort.Slice(filtered, func(i, j int) bool {
-		asc := sortDir != "desc"
-		switch sortField {
-		case "CodigoProducto":
-			if asc {
-				return filtered[i].CodigoProducto < filtered[j].CodigoProducto
-			}
-			return filtered[i].CodigoProducto > filtered[j].CodigoProducto
-		case "Zeta":
-			if asc {
-				return filtered[i].Zeta < filtered[j].Zeta
-			}
-			return filtered[i].Zeta > filtered[j].Zeta
-		case "AnioProduccion":
-			if asc {
-				return filtered[i].AnioProduccion < filtered[j].AnioProduccion
-			}
-			return filtered[i].AnioProduccion > filtered[j].AnioProduccion
-		case "NombreProducto":
-			if asc {
-				return filtered[i].NombreProducto < filtered[j].NombreProducto
+	// Ordenar después (estable para conservar el orden por sucursal)
+	if len(orden) == 0 {
+		orden = repository.Orden{{Campo: "CodigoProducto"}}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		for _, c := range orden {
+			cmp := repository.CompararValores(valorCombinado(filtered[i], c.Campo), valorCombinado(filtered[j], c.Campo))
+			if cmp != 0 {
+				return (cmp < 0) != c.Desc
 			}
-			return filtered[i].NombreProducto > filtered[j].NombreProducto
-		default:
-			return filtered[i].CodigoProducto < filtered[j].CodigoProducto
 		}
+		return false
 	})
 
 	return filtered
 }
 
-// ExportCombinedHandler exporta los datos fusionados de la página solicitada a Excel.
-func ExportCombinedHandler(w http.ResponseWriter, r *http.Request) {
-	// Obtener los parámetros de filtrado de la URL
-	year := r.URL.Query().Get("year")
-	if year == "" {
-		year = "2025"
+// columnasCombinadas devuelve las columnas de la exportación de datos
+// combinados.
+func columnasCombinadas() []columna {
+	columnas := []columna{
+		{"Cruce", colTexto, 0, false, true},
+		{"Clave Cruce", colTexto, 0, false, true},
+		{"Sucursal", colEntero, 0, false, false},
+		{"Código", colTexto, 0, false, false},
+		{"Zeta", colTexto, 0, false, false},
+		{"Año Producción", colEntero, 0, false, false},
+		{"Precio Venta", colMoneda, 0, false, false},
+		{"Precio Oferta", colMoneda, 0, false, false},
+		{"Nombre Producto", colTexto, 40, false, false},
+		{"Fecha Ingreso", colFecha, 0, false, false},
+		{"Costo CIF", colMoneda, 0, false, false},
+		{"Costo Real", colMoneda, 0, false, false},
+		{"Cant. Ingresada", colDecimal, 0, true, false},
+		{"Saldo Anterior", colDecimal, 0, true, false},
+		{"Días Desde Ingreso", colEntero, 0, false, false},
 	}
-	search := r.URL.Query().Get("search")
-	sortField := r.URL.Query().Get("sort")
-	sortDir := r.URL.Query().Get("dir")
+	columnas = append(columnas, columnasSaldosMensuales()...)
+	return append(columnas,
+		columna{"Margen Venta Real", colMoneda, 0, false, true},
+		columna{"Margen Venta CIF", colMoneda, 0, false, true},
+		columna{"Margen Oferta Real", colMoneda, 0, false, true},
+		columna{"Margen Oferta CIF", colMoneda, 0, false, true},
+		columna{"Margen % Venta Real", colDecimal, 0, false, false},
+		columna{"Margen % Venta CIF", colDecimal, 0, false, true},
+		columna{"Margen % Oferta Real", colDecimal, 0, false, false},
+		columna{"Margen % Oferta CIF", colDecimal, 0, false, true},
+		columna{"Markup % Venta Real", colDecimal, 0, false, true},
+		columna{"Markup % Venta CIF", colDecimal, 0, false, true},
+		columna{"Markup % Oferta Real", colDecimal, 0, false, true},
+		columna{"Markup % Oferta CIF", colDecimal, 0, false, true},
+	)
+}
+
+// filaCombinada devuelve los valores de una fila combinada en el orden de
+// columnasCombinadas.
+func filaCombinada(c models.CombinedData) []any {
+	fila := []any{
+		c.EstrategiaCruce, c.ClaveCruce, c.IDSucursal, c.CodigoProducto, c.Zeta,
+		c.AnioProduccion, c.PrecioVenta, c.PrecioOferta, c.NombreProducto,
+		c.FechaIngreso, c.CostoCIF, c.CostoReal, c.CantidadIngresada,
+		c.SaldoAnterior, c.DiasDesdeIngreso,
+	}
+	for _, saldo := range c.SaldosMensuales() {
+		fila = append(fila, saldo)
+	}
+	return append(fila,
+		c.MargenVentaReal, c.MargenVentaCIF, c.MargenOfertaReal, c.MargenOfertaCIF,
+		c.MargenPctVentaReal, c.MargenPctVentaCIF, c.MargenPctOfertaReal, c.MargenPctOfertaCIF,
+		c.MarkupVentaReal, c.MarkupVentaCIF, c.MarkupOfertaReal, c.MarkupOfertaCIF,
+	)
+}
 
-	// Obtener datos
-	stocks, err := getStocksFromSQLServer(db.SQLServerDB)
+// ExportCombinedHandler exporta los datos fusionados a Excel, CSV
+// (format=csv), NDJSON (format=ndjson) o PDF (format=pdf). Como /api/combined,
+// recorre por lotes los saldos en MySQL y lee solo los stocks de cada lote,
+// salvo que el orden o los filtros usen datos de SQL Server o se pida
+// paginar=filas; entonces carga todo el año y filtra en memoria.
+func (a *App) ExportCombinedHandler(w http.ResponseWriter, r *http.Request) {
+	// Obtener los parámetros de filtrado de la URL
+	year, err := parseYear(r.URL.Query())
 	if err != nil {
-		http.Error(w, "Error obteniendo stocks", http.StatusInternalServerError)
-		log.Println("Error obteniendo stocks:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	stocksMap := agruparStocksPorZeta(stocks)
-
-	saldos, err := getSaldosFromMySQL(db.MySQLDB, year)
+	params, err := parseListParams(r.URL.Query())
 	if err != nil {
-		http.Error(w, "Error obteniendo saldos", http.StatusInternalServerError)
-		log.Println("Error obteniendo saldos:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	resultados := fusionarDatos(stocksMap, saldos)
-
-	// Aplicar filtros si existen
-	if search != "" || sortField != "" {
-		resultados = filterAndSortResults(resultados, search, sortField, sortDir)
+	search := params.Search
+	orden, err := params.ordenCombinado()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-
-	// Crear archivo Excel
-	file := xlsx.NewFile()
-	sheet, err := file.AddSheet("Datos Combinados")
+	expr, err := params.filtroCombinado()
 	if err != nil {
-		http.Error(w, "Error al crear el Excel", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	// Agregar encabezados
-	row := sheet.AddRow()
-	headers := []string{
-		"Código", "Zeta", "Año Producción", "Precio Venta", "Precio Oferta",
-		"Nombre Producto", "Fecha Ingreso", "Costo CIF", "Costo Real",
-		"Cant. Ingresada", "Saldo Anterior", "Días Desde Ingreso",
+	sucursales, err := parseSucursales(r.URL.Query(), a.Config.SucursalPorDefecto)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	margen, err := parseFiltroMargen(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	for _, h := range headers {
-		cell := row.AddCell()
-		cell.Value = h
+	opts, err := parseExportOptions(r.URL.Query(), formatoXLSX, formatoCSV, formatoNDJSON, formatoPDF)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Agregar datos filtrados
-	for _, c := range resultados {
-		row := sheet.AddRow()
-		row.AddCell().Value = c.CodigoProducto
-		row.AddCell().Value = c.Zeta
-		row.AddCell().SetInt(c.AnioProduccion)
-		row.AddCell().SetFloat(c.PrecioVenta)
-		row.AddCell().SetFloat(c.PrecioOferta)
-		row.AddCell().Value = c.NombreProducto
-		row.AddCell().Value = c.FechaIngreso.Format("2006-01-02")
-		row.AddCell().SetFloat(c.CostoCIF)
-		row.AddCell().SetFloat(c.CostoReal)
-		row.AddCell().SetFloat(c.CantidadIngresada)
-		row.AddCell().SetFloat(c.SaldoAnterior)
-		row.AddCell().SetInt(c.DiasDesdeIngreso)
+	modo, _, err := modoCombinado(r.URL.Query(), orden, margen, expr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	// Generar nombre del archivo con los filtros aplicados
@@ -431,13 +879,247 @@ func ExportCombinedHandler(w http.ResponseWriter, r *http.Request) {
 	if search != "" {
 		filename += "_filtrado"
 	}
-	filename += ".xlsx"
 
-	// Enviar archivo
-	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	if err := file.Write(w); err != nil {
-		http.Error(w, "Error al generar el Excel", http.StatusInternalServerError)
-		log.Println("Error al escribir el Excel:", err)
+	// El export se crea con el primer lote para poder responder con un error
+	// normal si la consulta falla antes de enviar nada.
+	var ew exportWriter
+	var sinCorrespondencia []models.SaldoData
+	resumen := newResumenPorAnio()
+	escribirLote := func(resultados []models.CombinedData, sinStock []models.SaldoData) error {
+		if ew == nil {
+			var err error
+			if ew, err = newExportWriter(w, opts, filename, "Datos Combinados", columnasCombinadas()); err != nil {
+				return err
+			}
+		}
+		for _, c := range resultados {
+			if err := ew.Write(c, filaCombinada(c)); err != nil {
+				return err
+			}
+		}
+		resumen.agregar(resultados, sinStock)
+		sinCorrespondencia = append(sinCorrespondencia, sinStock...)
+		return nil
+	}
+
+	if modo == paginadoPorSaldos {
+		estrategia, ok := a.prepararCombinado(w, r)
+		if !ok {
+			return
+		}
+		err = a.recorrerCombinadoPorSaldos(r.Context(), estrategia, year, sucursales, expr, orden, func(ds combinedDataset) error {
+			return escribirLote(ds.Resultados, saldosSinCorrespondencia(ds, expr.Palabras))
+		})
+	} else {
+		ds, ok := a.loadCombined(w, r, year, sucursales)
+		if !ok {
+			return
+		}
+		resultados := ds.Resultados
+		if search != "" || len(orden) > 0 || margen.activo() {
+			resultados = filterAndSortResults(resultados, expr, margen, orden)
+		}
+		err = escribirLote(resultados, saldosSinCorrespondencia(ds, expr.Palabras))
+	}
+	if err != nil {
+		if ew == nil {
+			respondDataError(w, "Error al exportar los datos combinados", err)
+			return
+		}
+		errorExportacion(w, ew, err)
+		return
+	}
+
+	// El Excel y el PDF llevan además los saldos sin correspondencia y el
+	// resumen por año
+	if libro, ok := ew.(libroExport); ok {
+		if err := libro.NuevaHoja("Sin Correspondencia", columnasSinCorrespondencia()); err != nil {
+			log.Println("Error al escribir la exportación:", err)
+			return
+		}
+		for _, s := range sinCorrespondencia {
+			if err := ew.Write(s, filaSaldoData(s)); err != nil {
+				errorExportacion(w, ew, err)
+				return
+			}
+		}
+		if err := libro.NuevaHoja("Resumen por Año", columnasResumenAnio); err != nil {
+			log.Println("Error al escribir la exportación:", err)
+			return
+		}
+		for _, r := range resumen.filas() {
+			if err := ew.Write(r, r.valores()); err != nil {
+				errorExportacion(w, ew, err)
+				return
+			}
+		}
+	}
+	if err := ew.Close(); err != nil {
+		log.Println("Error al escribir la exportación:", err)
+	}
+}
+
+// loteExportacion es la cantidad de saldos que lee de MySQL cada consulta de
+// la exportación combinada por saldos.
+const loteExportacion = 1000
+
+// recorrerCombinadoPorSaldos recorre por lotes los saldos del año que cumplen
+// la expresión de filtro, en el orden pedido, y llama a fn con cada lote
+// fusionado con sus stocks. Llama a fn al menos una vez, aunque el primer
+// lote esté vacío.
+func (a *App) recorrerCombinadoPorSaldos(ctx context.Context, estrategia, year string, sucursales []int, expr repository.ExpresionFiltro, orden repository.Orden, fn func(combinedDataset) error) error {
+	filter := repository.SaldoFilter{Palabras: expr.Palabras, Condiciones: expr.Condiciones}
+	for offset := 0; ; offset += loteExportacion {
+		saldos, total, err := a.Saldos.ListByYearPaginated(ctx, year, offset, loteExportacion, filter, orden)
+		if err != nil {
+			return err
+		}
+		ds, err := a.combinarSaldos(ctx, estrategia, sucursales, saldos)
+		if err != nil {
+			return err
+		}
+		if err := fn(ds); err != nil {
+			return err
+		}
+		if len(saldos) == 0 || offset+len(saldos) >= total {
+			return nil
+		}
+	}
+}
+
+// columnasSinCorrespondencia son las columnas de la hoja de saldos sin stock
+// en las sucursales consultadas.
+func columnasSinCorrespondencia() []columna {
+	columnas := []columna{
+		{"Código", colTexto, 0, false, false},
+		{"Zeta", colTexto, 0, false, false},
+		{"Año Producción", colEntero, 0, false, false},
+		{"Nombre Producto", colTexto, 40, false, false},
+		{"Unidad Caja", colDecimal, 0, false, false},
+		{"Costo CIF", colMoneda, 0, false, false},
+		{"Costo Real", colMoneda, 0, false, false},
+		{"Fecha Ingreso", colFecha, 0, false, false},
+		{"Cant. Ingresada", colDecimal, 0, true, false},
+		{"Saldo Anterior", colDecimal, 0, true, false},
+		{"Días Desde Ingreso", colEntero, 0, false, false},
+	}
+	return append(columnas, columnasSaldosMensuales()...)
+}
+
+// filaSaldoData devuelve los valores de un saldo en el orden de
+// columnasSinCorrespondencia.
+func filaSaldoData(s models.SaldoData) []any {
+	fila := []any{
+		s.CodigoProducto, s.Zeta, s.AnioProduccion, s.NombreProducto, s.UnidadCaja,
+		s.CostoCIF, s.CostoReal, s.FechaIngreso, s.CantidadIngresada, s.SaldoAnterior,
+		s.DiasDesdeIngreso,
+	}
+	for _, saldo := range s.SaldosMensuales() {
+		fila = append(fila, saldo)
+	}
+	return fila
+}
+
+// resumenAnio es una fila de la hoja "Resumen por Año" de la exportación
+// combinada. Los saldos y valores se suman una vez por zeta aunque la zeta
+// aparezca en varias sucursales.
+type resumenAnio struct {
+	AnioProduccion     int
+	Filas              int
+	Zetas              int
+	SaldoAnterior      float64
+	ValorCostoReal     float64
+	ValorCostoCIF      float64
+	MargenPctVentaReal float64 // promedio simple de las filas
+	OfertasBajoCosto   int
+	SinCorrespondencia int
+}
+
+// columnasResumenAnio son las columnas de la hoja de resumen por año.
+var columnasResumenAnio = []columna{
+	{"Año Producción", colEntero, 0, false, false},
+	{"Filas", colEntero, 0, true, false},
+	{"Zetas", colEntero, 0, true, false},
+	{"Saldo Anterior", colDecimal, 0, true, false},
+	{"Valor Costo Real", colMoneda, 18, true, false},
+	{"Valor Costo CIF", colMoneda, 18, true, false},
+	{"Margen % Venta Real Prom.", colDecimal, 0, false, false},
+	{"Ofertas Bajo Costo", colEntero, 0, true, false},
+	{"Sin Correspondencia", colEntero, 0, true, false},
+}
+
+// valores devuelve el resumen en el orden de columnasResumenAnio.
+func (r resumenAnio) valores() []any {
+	return []any{r.AnioProduccion, r.Filas, r.Zetas, r.SaldoAnterior, r.ValorCostoReal,
+		r.ValorCostoCIF, r.MargenPctVentaReal, r.OfertasBajoCosto, r.SinCorrespondencia}
+}
+
+// saldoResumido identifica un saldo en el resumen por año, para sumarlo una
+// sola vez aunque dé filas en varias sucursales.
+type saldoResumido struct {
+	zeta, codigo string
+	anio         int
+}
+
+// resumenPorAnio acumula por año de producción las filas exportadas y los
+// saldos sin correspondencia. Se puede alimentar por lotes.
+type resumenPorAnio struct {
+	porAnio map[int]*resumenAnio
+	vistos  map[saldoResumido]bool
+}
+
+func newResumenPorAnio() *resumenPorAnio {
+	return &resumenPorAnio{
+		porAnio: make(map[int]*resumenAnio),
+		vistos:  make(map[saldoResumido]bool),
+	}
+}
+
+func (res *resumenPorAnio) anio(anio int) *resumenAnio {
+	r, ok := res.porAnio[anio]
+	if !ok {
+		r = &resumenAnio{AnioProduccion: anio}
+		res.porAnio[anio] = r
+	}
+	return r
+}
+
+// agregar suma las filas exportadas y los saldos sin correspondencia.
+func (res *resumenPorAnio) agregar(resultados []models.CombinedData, sinCorrespondencia []models.SaldoData) {
+	for _, c := range resultados {
+		r := res.anio(c.AnioProduccion)
+		r.Filas++
+		r.MargenPctVentaReal += c.MargenPctVentaReal
+		if c.OfertaBajoCosto() {
+			r.OfertasBajoCosto++
+		}
+		clave := saldoResumido{c.Zeta, c.CodigoProducto, c.AnioProduccion}
+		if res.vistos[clave] {
+			continue
+		}
+		res.vistos[clave] = true
+		r.Zetas++
+		r.SaldoAnterior += c.SaldoAnterior
+		r.ValorCostoReal += c.SaldoAnterior * c.CostoReal
+		r.ValorCostoCIF += c.SaldoAnterior * c.CostoCIF
+	}
+	for _, s := range sinCorrespondencia {
+		res.anio(s.AnioProduccion).SinCorrespondencia++
+	}
+}
+
+// filas devuelve el resumen ordenado por año, con el margen promediado.
+func (res *resumenPorAnio) filas() []resumenAnio {
+	resumen := make([]resumenAnio, 0, len(res.porAnio))
+	for _, r := range res.porAnio {
+		fila := *r
+		if fila.Filas > 0 {
+			fila.MargenPctVentaReal /= float64(fila.Filas)
+		}
+		resumen = append(resumen, fila)
 	}
+	sort.Slice(resumen, func(i, j int) bool {
+		return resumen[i].AnioProduccion < resumen[j].AnioProduccion
+	})
+	return resumen
 }
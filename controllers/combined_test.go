@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+
+	"go_api/config"
+	"go_api/models"
+	"go_api/repository"
+)
+
+// appCombinada crea una instancia de prueba con dos sucursales por defecto.
+func appCombinada(t *testing.T) *App {
+	t.Helper()
+	return newTestApp(t, config.Config{SucursalPorDefecto: repository.SucursalPorDefecto})
+}
+
+// pedirCombinados llama a /api/combined y decodifica la respuesta en un mapa
+// para leer los campos de cualquiera de los dos sobres.
+func pedirCombinados(t *testing.T, app *App, query url.Values) (int, map[string]json.RawMessage) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	app.CombinedDataHandler(rec, httptest.NewRequest(http.MethodGet, "/api/combined?"+query.Encode(), nil))
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+	var resp map[string]json.RawMessage
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, resp
+}
+
+func TestModoCombinado(t *testing.T) {
+	tests := []struct {
+		nombre  string
+		query   url.Values
+		modo    string
+		motivo  bool
+		wantErr bool
+	}{
+		{"por defecto", url.Values{}, paginadoPorSaldos, false, false},
+		{"orden de saldos", url.Values{"sort": {"CostoReal"}, "search": {"vino costo_real>0"}}, paginadoPorSaldos, false, false},
+		{"filtro de saldo agrupado", url.Values{"search": {"saldo>0"}}, paginadoPorFilas, true, false},
+		{"paginar=saldos", url.Values{"paginar": {"saldos"}}, paginadoPorSaldos, false, false},
+		{"orden de stock", url.Values{"sort": {"PrecioVenta"}}, paginadoPorFilas, true, false},
+		{"orden por año", url.Values{"sort": {"AnioProduccion"}}, paginadoPorFilas, true, false},
+		{"filtro de stock", url.Values{"search": {"sucursal:211"}}, paginadoPorFilas, true, false},
+		{"filtro de margen", url.Values{"bajoCosto": {"true"}}, paginadoPorFilas, true, false},
+		{"paginar=saldos con orden de stock", url.Values{"paginar": {"saldos"}, "sort": {"PrecioVenta"}}, paginadoPorFilas, true, false},
+		{"paginar=filas", url.Values{"paginar": {"filas"}}, paginadoPorFilas, false, false},
+		{"paginar inválido", url.Values{"paginar": {"todo"}}, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nombre, func(t *testing.T) {
+			params, err := parseListParams(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			orden, err := params.ordenCombinado()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expr, err := params.filtroCombinado()
+			if err != nil {
+				t.Fatal(err)
+			}
+			margen, err := parseFiltroMargen(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			modo, motivo, err := modoCombinado(tt.query, orden, margen, expr)
+			if (err != nil) != tt.wantErr || modo != tt.modo || (motivo != "") != tt.motivo {
+				t.Errorf("modoCombinado = %q, %q, %v; se esperaba %q (motivo %v, error %v)",
+					modo, motivo, err, tt.modo, tt.motivo, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApiCombinedModos(t *testing.T) {
+	app := appCombinada(t)
+	tests := []struct {
+		query     url.Values
+		modo      string
+		enMemoria bool
+		motivo    bool
+	}{
+		{url.Values{}, paginadoPorSaldos, false, false},
+		{url.Values{"sort": {"PrecioVenta"}}, paginadoPorFilas, true, true},
+		{url.Values{"paginar": {"saldos"}, "sort": {"PrecioVenta"}}, paginadoPorFilas, true, true},
+		{url.Values{"paginar": {"filas"}}, paginadoPorFilas, true, false},
+	}
+	for _, tt := range tests {
+		code, resp := pedirCombinados(t, app, tt.query)
+		if code != http.StatusOK {
+			t.Fatalf("%s: status = %d", tt.query.Encode(), code)
+		}
+		var modo, motivo string
+		var enMemoria bool
+		json.Unmarshal(resp["paginadoPor"], &modo)
+		json.Unmarshal(resp["enMemoria"], &enMemoria)
+		json.Unmarshal(resp["motivo"], &motivo)
+		if modo != tt.modo || enMemoria != tt.enMemoria || (motivo != "") != tt.motivo {
+			t.Errorf("%s: paginadoPor = %q, enMemoria = %v, motivo = %q", tt.query.Encode(), modo, enMemoria, motivo)
+		}
+	}
+
+	// El mismo parámetro inválido se rechaza en la API y en la vista
+	for ruta, h := range map[string]http.HandlerFunc{"/api/combined": app.CombinedDataHandler, "/combined": app.CombinedViewHandler} {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, ruta+"?paginar=todo", nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s?paginar=todo: status = %d, se esperaba 400", ruta, rec.Code)
+		}
+	}
+}
+
+func TestCombinedViewAvisaPaginadoEnMemoria(t *testing.T) {
+	app := appCombinada(t)
+	tests := []struct {
+		query string
+		aviso bool
+	}{
+		{"", false},
+		{"sort=PrecioVenta", true},
+		{"paginar=saldos&sort=PrecioVenta", true},
+		{"paginar=filas", false},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.CombinedViewHandler(rec, httptest.NewRequest(http.MethodGet, "/combined?"+tt.query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", tt.query, rec.Code)
+		}
+		if got := strings.Contains(rec.Body.String(), "Se pagina en memoria"); got != tt.aviso {
+			t.Errorf("%s: aviso = %v, se esperaba %v", tt.query, got, tt.aviso)
+		}
+	}
+}
+
+// exportarCombinados exporta en NDJSON y devuelve las claves de las filas
+// ordenadas.
+func exportarCombinados(t *testing.T, app *App, query url.Values) []string {
+	t.Helper()
+	query.Set("format", "ndjson")
+	rec := httptest.NewRecorder()
+	app.ExportCombinedHandler(rec, httptest.NewRequest(http.MethodGet, "/export/combined?"+query.Encode(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: status = %d: %s", query.Encode(), rec.Code, rec.Body.String())
+	}
+	var claves []string
+	sc := bufio.NewScanner(rec.Body)
+	for sc.Scan() {
+		var c models.CombinedData
+		if err := json.Unmarshal(sc.Bytes(), &c); err != nil {
+			t.Fatal(err)
+		}
+		claves = append(claves, c.Zeta+"|"+c.CodigoProducto)
+	}
+	sort.Strings(claves)
+	return claves
+}
+
+// TestExportCombinadoPorSaldosIgualAMemoria compara la exportación por lotes
+// de saldos con la que carga todo en memoria.
+func TestExportCombinadoPorSaldosIgualAMemoria(t *testing.T) {
+	app := appCombinada(t)
+	for _, search := range []string{"", "vino", "costo_real>2000", "nada-coincide"} {
+		porSaldos := exportarCombinados(t, app, url.Values{"search": {search}, "sucursal": {"211", "212"}})
+		enMemoria := exportarCombinados(t, app, url.Values{"search": {search}, "sucursal": {"211", "212"}, "paginar": {"filas"}})
+		if strings.Join(porSaldos, ",") != strings.Join(enMemoria, ",") {
+			t.Errorf("search %q: por saldos = %v, en memoria = %v", search, porSaldos, enMemoria)
+		}
+		if search == "" && len(porSaldos) == 0 {
+			t.Error("la exportación no tiene filas")
+		}
+	}
+}
+
+func TestRecorrerCombinadoPorLotes(t *testing.T) {
+	saldos, stocks := repository.DemoData()
+	base := saldos[0]
+	for i := 0; i < 2*loteExportacion+5; i++ {
+		s := base
+		s.Zeta = fmt.Sprintf("ZL%05d", i)
+		s.CodigoProducto = fmt.Sprintf("LT-%05d", i)
+		saldos = append(saldos, s)
+	}
+	app, err := NewAppWithRepositories(config.Config{SucursalPorDefecto: repository.SucursalPorDefecto},
+		repository.NewMemorySaldoRepository(saldos), repository.NewMemoryStockRepository(stocks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lotes, vistos := 0, 0
+	err = app.recorrerCombinadoPorSaldos(context.Background(), cruceZeta, "2024", []int{repository.SucursalPorDefecto},
+		repository.ExpresionFiltro{}, nil, func(ds combinedDataset) error {
+			lotes++
+			vistos += len(ds.Saldos)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total, _, _ := app.Saldos.ListByYearPaginated(context.Background(), "2024", 0, -1, repository.SaldoFilter{}, nil)
+	if vistos != len(total) || lotes != 3 {
+		t.Errorf("se recorrieron %d saldos en %d lotes, se esperaban %d en 3", vistos, lotes, len(total))
+	}
+
+	lotes = 0
+	err = app.recorrerCombinadoPorSaldos(context.Background(), cruceZeta, "1999", []int{repository.SucursalPorDefecto},
+		repository.ExpresionFiltro{}, nil, func(combinedDataset) error { lotes++; return nil })
+	if err != nil || lotes != 1 {
+		t.Errorf("sin saldos: %d lotes, %v; se esperaba un lote vacío", lotes, err)
+	}
+}
+
+// TestCombinadoSaldosMensuales comprueba que los doce saldos de fin de mes
+// llegan a la API, a la vista y al Excel de los datos combinados.
+func TestCombinadoSaldosMensuales(t *testing.T) {
+	app := appCombinada(t)
+	rec := httptest.NewRecorder()
+	app.CombinedDataHandler(rec, httptest.NewRequest(http.MethodGet, "/api/combined?year=2025&pageSize=500", nil))
+	var resp struct {
+		Data []models.CombinedData `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) == 0 {
+		t.Fatal("sin datos combinados")
+	}
+	conMeses := 0
+	for _, c := range resp.Data {
+		saldos, err := app.Saldos.List(context.Background(), repository.SaldoFilter{Zeta: c.Zeta})
+		if err != nil || len(saldos) == 0 {
+			t.Fatalf("zeta %s: %v", c.Zeta, err)
+		}
+		var want [12]float64
+		for _, s := range saldos {
+			if s.AnioProduccion != c.AnioProduccion {
+				continue
+			}
+			for i, v := range s.SaldosMensuales() {
+				want[i] += v
+			}
+		}
+		if c.SaldosMensuales() != want {
+			t.Errorf("zeta %s: saldos mensuales = %v, se esperaba %v", c.Zeta, c.SaldosMensuales(), want)
+		}
+		if want != ([12]float64{}) {
+			conMeses++
+		}
+	}
+	if conMeses == 0 {
+		t.Error("ninguna fila tiene saldos de fin de mes")
+	}
+
+	rec = httptest.NewRecorder()
+	app.CombinedViewHandler(rec, httptest.NewRequest(http.MethodGet, "/combined?year=2025", nil))
+	if n := strings.Count(rec.Body.String(), `<th class="px-4 py-2 col-mes`); n != 12 {
+		t.Errorf("columnas de mes en la vista = %d, se esperaban 12", n)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ExportCombinedHandler(rec, httptest.NewRequest(http.MethodGet, "/exportCombined?year=2025", nil))
+	f := abrirLibro(t, rec.Body.Bytes())
+	filas, err := f.GetRows(f.GetSheetName(0), excelize.Options{RawCellValue: true})
+	if err != nil || len(filas) < 2 {
+		t.Fatalf("filas = %d, %v", len(filas), err)
+	}
+	col := -1
+	for i, titulo := range filas[0] {
+		if titulo == "Saldo Fin Enero" {
+			col = i
+		}
+	}
+	if col < 0 || filas[0][col+11] != "Saldo Fin Diciembre" {
+		t.Fatalf("encabezados = %q, faltan los saldos de fin de mes", filas[0])
+	}
+}
@@ -0,0 +1,281 @@
+package controllers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_api/models"
+	"go_api/repository"
+	"go_api/views"
+)
+
+// compararSaldoStock devuelve las discrepancias de código, año y costo entre
+// un saldo de MySQL y el stock vigente de la misma zeta en una sucursal.
+func compararSaldoStock(s models.Saldo, stock models.StockData, tol models.ToleranciasConciliacion) []models.Discrepancia {
+	base := models.Discrepancia{
+		Zeta:            s.Zeta,
+		IDSucursal:      stock.IDSucursal,
+		NombreProducto:  s.NombreProducto,
+		CodigoMySQL:     s.CodigoProducto,
+		CodigoSQLServer: stock.CodigoProducto,
+		AnioMySQL:       s.AnioProduccion,
+		AnioSQLServer:   stock.Anio,
+		CostoMySQL:      s.CostoReal,
+		CostoSQLServer:  stock.CostoUnitario,
+		Diferencia:      stock.CostoUnitario - s.CostoReal,
+	}
+
+	var discrepancias []models.Discrepancia
+	if !strings.EqualFold(strings.TrimSpace(s.CodigoProducto), strings.TrimSpace(stock.CodigoProducto)) {
+		d := base
+		d.Tipo = models.DiscrepanciaCodigo
+		discrepancias = append(discrepancias, d)
+	}
+	if diff := s.AnioProduccion - stock.Anio; diff > tol.Anio || -diff > tol.Anio {
+		d := base
+		d.Tipo = models.DiscrepanciaAnio
+		discrepancias = append(discrepancias, d)
+	}
+	diff := math.Abs(base.Diferencia)
+	if diff > tol.Costo && (s.CostoReal == 0 || diff/math.Abs(s.CostoReal)*100 > tol.CostoPct) {
+		d := base
+		d.Tipo = models.DiscrepanciaCosto
+		discrepancias = append(discrepancias, d)
+	}
+	return discrepancias
+}
+
+// construirConciliacion compara los saldos de MySQL con el stock vigente de
+// cada sucursal por zeta. Un par saldo/stock se compara si cualquiera de los
+// dos es del año pedido, para que un año distinto en una base no oculte la
+// zeta; las zetas de un solo lado se reportan si son del año pedido.
+func construirConciliacion(saldos []models.Saldo, stocksPorSucursal map[int]map[string]models.StockData, sucursales []int, anio int, tol models.ToleranciasConciliacion) []models.Discrepancia {
+	discrepancias := []models.Discrepancia{}
+	enMySQL := make(map[string]bool, len(saldos))
+
+	for _, s := range saldos {
+		enMySQL[s.Zeta] = true
+		encontrado := false
+		for _, sucursal := range sucursales {
+			stock, ok := stocksPorSucursal[sucursal][s.Zeta]
+			if !ok {
+				continue
+			}
+			encontrado = true
+			if s.AnioProduccion == anio || stock.Anio == anio {
+				discrepancias = append(discrepancias, compararSaldoStock(s, stock, tol)...)
+			}
+		}
+		if !encontrado && s.AnioProduccion == anio {
+			discrepancias = append(discrepancias, models.Discrepancia{
+				Tipo:           models.DiscrepanciaSoloMySQL,
+				Zeta:           s.Zeta,
+				NombreProducto: s.NombreProducto,
+				CodigoMySQL:    s.CodigoProducto,
+				AnioMySQL:      s.AnioProduccion,
+				CostoMySQL:     s.CostoReal,
+			})
+		}
+	}
+
+	for _, sucursal := range sucursales {
+		for _, stock := range stocksPorSucursal[sucursal] {
+			if enMySQL[stock.Zeta] || stock.Anio != anio {
+				continue
+			}
+			discrepancias = append(discrepancias, models.Discrepancia{
+				Tipo:            models.DiscrepanciaSoloSQLServer,
+				Zeta:            stock.Zeta,
+				IDSucursal:      stock.IDSucursal,
+				NombreProducto:  stock.NombreProducto,
+				CodigoSQLServer: stock.CodigoProducto,
+				AnioSQLServer:   stock.Anio,
+				CostoSQLServer:  stock.CostoUnitario,
+			})
+		}
+	}
+
+	// Ordenar por tipo (en el orden de TiposDiscrepancia), zeta y sucursal
+	orden := make(map[string]int, len(models.TiposDiscrepancia))
+	for i, t := range models.TiposDiscrepancia {
+		orden[t] = i
+	}
+	sort.SliceStable(discrepancias, func(i, j int) bool {
+		a, b := discrepancias[i], discrepancias[j]
+		if a.Tipo != b.Tipo {
+			return orden[a.Tipo] < orden[b.Tipo]
+		}
+		if a.Zeta != b.Zeta {
+			return a.Zeta < b.Zeta
+		}
+		return a.IDSucursal < b.IDSucursal
+	})
+	return discrepancias
+}
+
+// loadConciliacion obtiene todos los saldos de MySQL y los stocks de las
+// sucursales pedidas y arma el reporte del año, filtrado por "tipo" si se
+// indicó. Responde con el error y devuelve false si los parámetros son
+// inválidos o falla alguna de las bases.
+func (a *App) loadConciliacion(w http.ResponseWriter, r *http.Request) (models.ReporteConciliacion, bool) {
+	query := r.URL.Query()
+	reporte := models.ReporteConciliacion{}
+
+	var err error
+	if reporte.Anio, err = parseYear(query); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return reporte, false
+	}
+	anio, _ := strconv.Atoi(reporte.Anio)
+	if reporte.Sucursales, err = parseSucursales(query, a.Config.SucursalPorDefecto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return reporte, false
+	}
+	if reporte.Tolerancias, err = parseTolerancias(query); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return reporte, false
+	}
+	tipos, err := parseTiposDiscrepancia(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return reporte, false
+	}
+
+	// La conciliación compara siempre por zeta
+	stocks, stocksAl, err := a.stocksAgrupados(r.Context(), cruceZeta, reporte.Sucursales)
+	if err != nil {
+		respondDataError(w, "Error obteniendo stocks", err)
+		return reporte, false
+	}
+	// Todos los años: una zeta puede estar en otro año de producción en MySQL
+	saldosAl := time.Now()
+	saldos, err := a.Saldos.List(r.Context(), repository.SaldoFilter{})
+	if err != nil {
+		respondDataError(w, "Error obteniendo saldos", err)
+		return reporte, false
+	}
+	reporte.DatosAl = models.DatosAl{Stocks: stocksAl, Saldos: saldosAl}
+
+	todas := construirConciliacion(saldos, stocks.PorSucursal, reporte.Sucursales, anio, reporte.Tolerancias)
+	reporte.Resumen = make(map[string]int, len(models.TiposDiscrepancia))
+	for _, t := range models.TiposDiscrepancia {
+		reporte.Resumen[t] = 0
+	}
+	reporte.Discrepancias = make([]models.Discrepancia, 0, len(todas))
+	for _, d := range todas {
+		reporte.Resumen[d.Tipo]++
+		if tipos == nil || tipos[d.Tipo] {
+			reporte.Discrepancias = append(reporte.Discrepancias, d)
+		}
+	}
+	return reporte, true
+}
+
+// ConciliacionApiHandler maneja /api/reportes/conciliacion y devuelve las
+// discrepancias entre MySQL y SQL Server en JSON.
+func (a *App) ConciliacionApiHandler(w http.ResponseWriter, r *http.Request) {
+	reporte, ok := a.loadConciliacion(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, reporte)
+}
+
+// ConciliacionViewHandler maneja /reportes/conciliacion.
+func (a *App) ConciliacionViewHandler(w http.ResponseWriter, r *http.Request) {
+	reporte, ok := a.loadConciliacion(w, r)
+	if !ok {
+		return
+	}
+
+	// Sucursales disponibles para el selector; si falla se muestran solo las pedidas
+	disponibles, err := a.Stocks.Sucursales(r.Context())
+	if err != nil {
+		log.Println("Error obteniendo sucursales:", err)
+	}
+
+	query := r.URL.Query()
+	a.Views.RenderConciliacion(w, views.ConciliacionViewData{
+		Reporte: reporte,
+		Tipos:   query["tipo"],
+		SucursalesFiltro: views.SucursalesFiltro{
+			Sucursales:  reporte.Sucursales,
+			Disponibles: disponibles,
+		},
+	})
+}
+
+// columnasConciliacion son las columnas de las exportaciones de la
+// conciliación.
+var columnasConciliacion = []columna{
+	{"Tipo", colTexto, 0, false, false},
+	{"Zeta", colTexto, 0, false, false},
+	{"Sucursal", colEntero, 0, false, false},
+	{"Nombre Producto", colTexto, 40, false, false},
+	{"Código MySQL", colTexto, 0, false, false},
+	{"Código SQL Server", colTexto, 0, false, false},
+	{"Año MySQL", colEntero, 0, false, false},
+	{"Año SQL Server", colEntero, 0, false, false},
+	{"Costo MySQL", colMoneda, 0, false, false},
+	{"Costo SQL Server", colMoneda, 0, false, false},
+	{"Diferencia", colMoneda, 0, false, false},
+}
+
+// filaConciliacion devuelve los valores de una discrepancia en el orden de
+// columnasConciliacion.
+func filaConciliacion(d models.Discrepancia) []any {
+	// Los valores en cero corresponden al lado que no existe y quedan vacíos
+	entero := func(n int) any {
+		if n == 0 {
+			return ""
+		}
+		return n
+	}
+	num := func(f float64) any {
+		if f == 0 {
+			return ""
+		}
+		return f
+	}
+	return []any{
+		d.Tipo, d.Zeta, entero(d.IDSucursal), d.NombreProducto,
+		d.CodigoMySQL, d.CodigoSQLServer, entero(d.AnioMySQL), entero(d.AnioSQLServer),
+		num(d.CostoMySQL), num(d.CostoSQLServer), num(d.Diferencia),
+	}
+}
+
+// ExportConciliacionHandler exporta la lista de discrepancias en Excel (por
+// defecto), CSV (format=csv) o NDJSON (format=ndjson).
+func (a *App) ExportConciliacionHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseExportOptions(r.URL.Query(), formatoXLSX, formatoCSV, formatoNDJSON)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reporte, ok := a.loadConciliacion(w, r)
+	if !ok {
+		return
+	}
+
+	ew, err := newExportWriter(w, opts, "conciliacion_"+reporte.Anio, "Discrepancias", columnasConciliacion)
+	if err != nil {
+		http.Error(w, "Error al crear la exportación", http.StatusInternalServerError)
+		log.Println("Error al crear la exportación:", err)
+		return
+	}
+	for _, d := range reporte.Discrepancias {
+		if err := ew.Write(d, filaConciliacion(d)); err != nil {
+			errorExportacion(w, ew, err)
+			return
+		}
+	}
+	if err := ew.Close(); err != nil {
+		log.Println("Error al escribir la exportación:", err)
+	}
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"go_api/models"
+)
+
+// tiposDe devuelve los tipos de las discrepancias, en orden.
+func tiposDe(discrepancias []models.Discrepancia) []string {
+	tipos := []string{}
+	for _, d := range discrepancias {
+		tipos = append(tipos, d.Tipo)
+	}
+	return tipos
+}
+
+func TestCompararSaldoStockTolerancias(t *testing.T) {
+	saldo := models.Saldo{Zeta: "Z1", CodigoProducto: "P-1", AnioProduccion: 2024, CostoReal: 100}
+	stock := func(codigo string, anio int, costo float64) models.StockData {
+		return models.StockData{Zeta: "Z1", IDSucursal: 211, CodigoProducto: codigo, Anio: anio, CostoUnitario: costo}
+	}
+	tests := []struct {
+		nombre string
+		stock  models.StockData
+		tol    models.ToleranciasConciliacion
+		want   []string
+	}{
+		{"iguales", stock("P-1", 2024, 100), models.ToleranciasConciliacion{}, []string{}},
+		// El código se compara sin mayúsculas ni espacios
+		{"código con espacios", stock(" p-1 ", 2024, 100), models.ToleranciasConciliacion{}, []string{}},
+		{"código distinto", stock("P-2", 2024, 100), models.ToleranciasConciliacion{}, []string{models.DiscrepanciaCodigo}},
+		{"año distinto", stock("P-1", 2023, 100), models.ToleranciasConciliacion{}, []string{models.DiscrepanciaAnio}},
+		{"año dentro de la tolerancia", stock("P-1", 2023, 100), models.ToleranciasConciliacion{Anio: 1}, []string{}},
+		{"año fuera de la tolerancia", stock("P-1", 2026, 100), models.ToleranciasConciliacion{Anio: 1}, []string{models.DiscrepanciaAnio}},
+		{"costo dentro de la absoluta", stock("P-1", 2024, 100.005), models.ToleranciasConciliacion{Costo: 0.01}, []string{}},
+		{"costo fuera de la absoluta", stock("P-1", 2024, 99), models.ToleranciasConciliacion{Costo: 0.01}, []string{models.DiscrepanciaCosto}},
+		// Debe superar ambas tolerancias: 2 de 100 es el 2 %
+		{"costo dentro de la porcentual", stock("P-1", 2024, 102), models.ToleranciasConciliacion{Costo: 0.01, CostoPct: 5}, []string{}},
+		{"costo fuera de ambas", stock("P-1", 2024, 110), models.ToleranciasConciliacion{Costo: 0.01, CostoPct: 5}, []string{models.DiscrepanciaCosto}},
+		{"costo dentro de la absoluta, fuera de la porcentual", stock("P-1", 2024, 104), models.ToleranciasConciliacion{Costo: 5, CostoPct: 1}, []string{}},
+		{"todas", stock("P-9", 2020, 50), models.ToleranciasConciliacion{}, []string{models.DiscrepanciaCodigo, models.DiscrepanciaAnio, models.DiscrepanciaCosto}},
+	}
+	for _, tt := range tests {
+		got := compararSaldoStock(saldo, tt.stock, tt.tol)
+		if !reflect.DeepEqual(tiposDe(got), tt.want) {
+			t.Errorf("%s: %v, se esperaba %v", tt.nombre, tiposDe(got), tt.want)
+		}
+	}
+
+	// Sin costo en MySQL la porcentual no aplica: basta la absoluta
+	sinCosto := saldo
+	sinCosto.CostoReal = 0
+	got := compararSaldoStock(sinCosto, stock("P-1", 2024, 1), models.ToleranciasConciliacion{Costo: 0.01, CostoPct: 50})
+	if !reflect.DeepEqual(tiposDe(got), []string{models.DiscrepanciaCosto}) {
+		t.Errorf("sin costo en MySQL: %v", tiposDe(got))
+	}
+	if d := got[0]; d.Diferencia != 1 || d.IDSucursal != 211 || d.CostoSQLServer != 1 {
+		t.Errorf("discrepancia = %+v", d)
+	}
+}
+
+func TestConstruirConciliacion(t *testing.T) {
+	saldos := []models.Saldo{
+		{Zeta: "Z1", CodigoProducto: "P-1", AnioProduccion: 2024, CostoReal: 10},
+		{Zeta: "Z2", CodigoProducto: "P-2", AnioProduccion: 2024},                // solo en MySQL
+		{Zeta: "Z3", CodigoProducto: "P-3", AnioProduccion: 2023},                // otro año, solo en MySQL
+		{Zeta: "Z4", CodigoProducto: "P-4", AnioProduccion: 2023, CostoReal: 10}, // año distinto en SQL Server
+	}
+	stocks := map[int]map[string]models.StockData{
+		211: {
+			"Z1": {Zeta: "Z1", IDSucursal: 211, CodigoProducto: "P-1", Anio: 2024, CostoUnitario: 12},
+			"Z4": {Zeta: "Z4", IDSucursal: 211, CodigoProducto: "P-4", Anio: 2024, CostoUnitario: 10},
+			"Z5": {Zeta: "Z5", IDSucursal: 211, Anio: 2024}, // solo en SQL Server
+			"Z6": {Zeta: "Z6", IDSucursal: 211, Anio: 2023}, // otro año
+		},
+		212: {
+			"Z1": {Zeta: "Z1", IDSucursal: 212, CodigoProducto: "P-1", Anio: 2024, CostoUnitario: 10},
+		},
+	}
+	got := construirConciliacion(saldos, stocks, []int{211, 212}, 2024, models.ToleranciasConciliacion{Costo: 0.01})
+
+	type clave struct {
+		tipo, zeta string
+		sucursal   int
+	}
+	var claves []clave
+	for _, d := range got {
+		claves = append(claves, clave{d.Tipo, d.Zeta, d.IDSucursal})
+	}
+	want := []clave{
+		{models.DiscrepanciaSoloMySQL, "Z2", 0},
+		{models.DiscrepanciaSoloSQLServer, "Z5", 211},
+		{models.DiscrepanciaAnio, "Z4", 211},
+		{models.DiscrepanciaCosto, "Z1", 211},
+	}
+	if !reflect.DeepEqual(claves, want) {
+		t.Errorf("discrepancias = %v, se esperaba %v", claves, want)
+	}
+}
+
+func TestParseTolerancias(t *testing.T) {
+	tol, err := parseTolerancias(url.Values{})
+	if err != nil || tol != (models.ToleranciasConciliacion{Costo: 0.01}) {
+		t.Errorf("por defecto = %+v, %v", tol, err)
+	}
+	tol, err = parseTolerancias(url.Values{"tolCosto": {"0.5"}, "tolCostoPct": {"2"}, "tolAnio": {"1"}})
+	if err != nil || tol != (models.ToleranciasConciliacion{Costo: 0.5, CostoPct: 2, Anio: 1}) {
+		t.Errorf("tolerancias = %+v, %v", tol, err)
+	}
+	for _, q := range []url.Values{
+		{"tolCosto": {"-1"}},
+		{"tolCostoPct": {"x"}},
+		{"tolAnio": {"-1"}},
+		{"tolAnio": {"1.5"}},
+	} {
+		if _, err := parseTolerancias(q); err == nil {
+			t.Errorf("%v: se esperaba error", q)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"go_api/models"
+)
+
+// Estrategias de cruce entre saldos de MySQL y stocks de SQL Server.
+const (
+	cruceZeta       = "zeta"        // solo la zeta (comportamiento original)
+	cruceZetaCodigo = "zeta_codigo" // zeta y código de producto
+	cruceZetaAnio   = "zeta_anio"   // zeta y año de producción
+	cruceCodigo     = "codigo"      // solo el código de producto
+)
+
+// estrategiasCruce son las estrategias válidas para el parámetro "cruce".
+var estrategiasCruce = []string{cruceZeta, cruceZetaCodigo, cruceZetaAnio, cruceCodigo}
+
+// parseCruce lee la estrategia de cruce pedida en "cruce"; por defecto zeta.
+func parseCruce(query url.Values) (string, error) {
+	cruce := query.Get("cruce")
+	if cruce == "" {
+		return cruceZeta, nil
+	}
+	for _, e := range estrategiasCruce {
+		if e == cruce {
+			return cruce, nil
+		}
+	}
+	return "", fmt.Errorf("cruce inválido: %q", cruce)
+}
+
+// claveCruce arma la clave de cruce de un registro según la estrategia.
+func claveCruce(estrategia, codigo, zeta string, anio int) string {
+	switch estrategia {
+	case cruceZetaCodigo:
+		return zeta + "|" + codigo
+	case cruceZetaAnio:
+		return zeta + "|" + strconv.Itoa(anio)
+	case cruceCodigo:
+		return codigo
+	default:
+		return zeta
+	}
+}
+
+// claveStock devuelve la clave de cruce de un stock de SQL Server.
+func claveStock(estrategia string, s models.StockData) string {
+	return claveCruce(estrategia, s.CodigoProducto, s.Zeta, s.Anio)
+}
+
+// claveSaldo devuelve la clave de cruce de un saldo de MySQL.
+func claveSaldo(estrategia string, s models.SaldoData) string {
+	return claveCruce(estrategia, s.CodigoProducto, s.Zeta, s.AnioProduccion)
+}
+
+// agruparStocks agrupa los stocks de una sucursal por clave de cruce,
+// quedándose con el más reciente de cada clave. Si una clave corresponde a
+// más de un registro de SQL Server (distinto código, zeta o año) lo informa
+// como colisión con el stock más reciente de cada registro.
+func agruparStocks(estrategia string, stocks []models.StockData) (map[string]models.StockData, []models.ColisionCruce) {
+	type registro struct {
+		codigo, zeta string
+		anio         int
+	}
+	agrupados := make(map[string]models.StockData)
+	registros := make(map[string]map[registro]models.StockData)
+	for _, s := range stocks {
+		clave := claveStock(estrategia, s)
+		if actual, existe := agrupados[clave]; !existe || s.Fecha.After(actual.Fecha) {
+			agrupados[clave] = s
+		}
+		if registros[clave] == nil {
+			registros[clave] = make(map[registro]models.StockData)
+		}
+		reg := registro{s.CodigoProducto, s.Zeta, s.Anio}
+		if actual, existe := registros[clave][reg]; !existe || s.Fecha.After(actual.Fecha) {
+			registros[clave][reg] = s
+		}
+	}
+
+	var colisiones []models.ColisionCruce
+	for clave, porRegistro := range registros {
+		if len(porRegistro) < 2 {
+			continue
+		}
+		colision := models.ColisionCruce{
+			Clave:   clave,
+			Elegido: agrupados[clave],
+		}
+		for _, s := range porRegistro {
+			colision.IDSucursal = s.IDSucursal
+			colision.Candidatos = append(colision.Candidatos, s)
+		}
+		sort.Slice(colision.Candidatos, func(i, j int) bool {
+			return colision.Candidatos[i].Fecha.After(colision.Candidatos[j].Fecha)
+		})
+		colisiones = append(colisiones, colision)
+	}
+	sort.Slice(colisiones, func(i, j int) bool { return colisiones[i].Clave < colisiones[j].Clave })
+	return agrupados, colisiones
+}
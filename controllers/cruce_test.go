@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"go_api/models"
+)
+
+func TestParseCruce(t *testing.T) {
+	if cruce, err := parseCruce(url.Values{}); err != nil || cruce != cruceZeta {
+		t.Errorf("por defecto = %q, %v", cruce, err)
+	}
+	for _, e := range estrategiasCruce {
+		if cruce, err := parseCruce(url.Values{"cruce": {e}}); err != nil || cruce != e {
+			t.Errorf("%s = %q, %v", e, cruce, err)
+		}
+	}
+	if _, err := parseCruce(url.Values{"cruce": {"ZETA"}}); err == nil {
+		t.Error("se esperaba error para una estrategia desconocida")
+	}
+}
+
+func TestClaveCruce(t *testing.T) {
+	saldo := models.SaldoData{CodigoProducto: "P-1", Zeta: "Z1", AnioProduccion: 2024}
+	stock := models.StockData{CodigoProducto: "P-1", Zeta: "Z1", Anio: 2024}
+	want := map[string]string{
+		cruceZeta:       "Z1",
+		cruceZetaCodigo: "Z1|P-1",
+		cruceZetaAnio:   "Z1|2024",
+		cruceCodigo:     "P-1",
+	}
+	for estrategia, clave := range want {
+		if got := claveSaldo(estrategia, saldo); got != clave {
+			t.Errorf("claveSaldo(%s) = %q, se esperaba %q", estrategia, got, clave)
+		}
+		if got := claveStock(estrategia, stock); got != clave {
+			t.Errorf("claveStock(%s) = %q, se esperaba %q", estrategia, got, clave)
+		}
+	}
+}
+
+func TestAgruparStocksColisiones(t *testing.T) {
+	dia := func(d int) time.Time { return time.Date(2025, time.March, d, 0, 0, 0, 0, time.UTC) }
+	stocks := []models.StockData{
+		// Misma zeta con dos códigos: colisiona al cruzar solo por zeta
+		{IDSucursal: 211, Zeta: "Z1", CodigoProducto: "P-1", Anio: 2024, Fecha: dia(1)},
+		{IDSucursal: 211, Zeta: "Z1", CodigoProducto: "P-1", Anio: 2024, Fecha: dia(5)},
+		{IDSucursal: 211, Zeta: "Z1", CodigoProducto: "P-2", Anio: 2024, Fecha: dia(3)},
+		// Historial de un mismo registro: no es colisión
+		{IDSucursal: 211, Zeta: "Z2", CodigoProducto: "P-3", Anio: 2024, Fecha: dia(1)},
+		{IDSucursal: 211, Zeta: "Z2", CodigoProducto: "P-3", Anio: 2024, Fecha: dia(2)},
+	}
+
+	tests := []struct {
+		estrategia string
+		claves     int
+		colisiones []string
+	}{
+		{cruceZeta, 2, []string{"Z1"}},
+		{cruceZetaCodigo, 3, nil},
+		{cruceZetaAnio, 2, []string{"Z1|2024"}},
+		// Por código P-1 no colisiona: sus dos filas son el mismo registro
+		{cruceCodigo, 3, nil},
+	}
+	for _, tt := range tests {
+		agrupados, colisiones := agruparStocks(tt.estrategia, stocks)
+		if len(agrupados) != tt.claves {
+			t.Errorf("%s: %d claves, se esperaban %d", tt.estrategia, len(agrupados), tt.claves)
+		}
+		if len(colisiones) != len(tt.colisiones) {
+			t.Errorf("%s: colisiones = %+v", tt.estrategia, colisiones)
+			continue
+		}
+		for i, c := range colisiones {
+			if c.Clave != tt.colisiones[i] || c.IDSucursal != 211 {
+				t.Errorf("%s: colisión %d = %+v", tt.estrategia, i, c)
+			}
+		}
+	}
+
+	// Se elige el stock más reciente de la clave, y los candidatos son el más
+	// reciente de cada registro, del más nuevo al más viejo
+	agrupados, colisiones := agruparStocks(cruceZeta, stocks)
+	if got := agrupados["Z1"]; got.CodigoProducto != "P-1" || !got.Fecha.Equal(dia(5)) {
+		t.Errorf("elegido Z1 = %+v", got)
+	}
+	if got := agrupados["Z2"]; !got.Fecha.Equal(dia(2)) {
+		t.Errorf("elegido Z2 = %+v", got)
+	}
+	c := colisiones[0]
+	if len(c.Candidatos) != 2 || !c.Candidatos[0].Fecha.Equal(dia(5)) || c.Candidatos[1].CodigoProducto != "P-2" ||
+		!c.Elegido.Fecha.Equal(dia(5)) {
+		t.Errorf("colisión = %+v", c)
+	}
+}
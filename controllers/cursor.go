@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"go_api/models"
+	"go_api/repository"
+)
+
+// Modos de conteo del listado por cursor (parámetro "count").
+const (
+	conteoExacto     = "exact"
+	conteoAproximado = "approx"
+	conteoSinConteo  = "none"
+)
+
+// conteoPorDefecto es el modo de conteo sin "count": el exacto recorre todos
+// los grupos del filtro, por eso hay que pedirlo.
+const conteoPorDefecto = conteoAproximado
+
+// campoCursorDefecto es el orden del listado por cursor sin campo de orden.
+const campoCursorDefecto = "CodigoProducto"
+
+// cursorToken es el contenido del cursor opaco que reciben los clientes. Lleva
+// el orden con el que se generó para rechazarlo si se usa con otro, y si
+// apunta a la página anterior (Atras) o a la siguiente.
+type cursorToken struct {
+	Orden string                 `json:"o"`
+	Atras bool                   `json:"b,omitempty"`
+	Clave repository.SaldoCursor `json:"k"`
+}
+
+// codificarCursor serializa el token en base64 apto para URL.
+func codificarCursor(t cursorToken) string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodificarCursor lee el cursor pedido y verifica que corresponda al orden
+// actual. Los números de la clave se leen como float64.
+func decodificarCursor(valor string, orden repository.Orden) (cursorToken, error) {
+	var t cursorToken
+	b, err := base64.RawURLEncoding.DecodeString(valor)
+	if err == nil {
+		err = json.Unmarshal(b, &t)
+	}
+	if err != nil {
+		return t, fmt.Errorf("cursor inválido")
+	}
+	if t.Orden != orden.String() || !t.Clave.Valido(orden) {
+		return t, fmt.Errorf("cursor inválido: no corresponde al orden pedido")
+	}
+	return t, nil
+}
+
+// cursorParams reúne el cursor y el modo de conteo del listado por cursor.
+type cursorParams struct {
+	Orden  repository.Orden
+	Token  *cursorToken // nil para la primera página
+	Conteo string
+}
+
+// parseCursorParams lee "cursor" (vacío para la primera página) y "count"
+// (exact, approx o none; por defecto approx). Sin orden se ordena por código
+// de forma explícita, para que la página anterior pueda pedirse invirtiéndolo.
+func parseCursorParams(query url.Values, orden repository.Orden) (cursorParams, error) {
+	cp := cursorParams{Orden: orden, Conteo: query.Get("count")}
+	if len(cp.Orden) == 0 {
+		cp.Orden = repository.Orden{{Campo: campoCursorDefecto}}
+	}
+	switch cp.Conteo {
+	case "":
+		cp.Conteo = conteoPorDefecto
+	case conteoExacto, conteoAproximado, conteoSinConteo:
+	default:
+		return cp, fmt.Errorf("count inválido: %q (use exact, approx o none)", cp.Conteo)
+	}
+	if v := query.Get("cursor"); v != "" {
+		t, err := decodificarCursor(v, cp.Orden)
+		if err != nil {
+			return cp, err
+		}
+		cp.Token = &t
+	}
+	return cp, nil
+}
+
+// paginaCursor es una página del listado de saldos por cursor.
+type paginaCursor struct {
+	Items      []models.Saldo
+	Siguiente  string // cursor de la página siguiente, vacío si no hay más
+	Anterior   string // cursor de la página anterior, vacío en la primera
+	Total      int
+	ConTotal   bool
+	Aproximado bool
+}
+
+// loadSaldosCursor obtiene la página de saldos posterior (o anterior) al
+// cursor. Pide una fila de más para saber si hay otra página en esa
+// dirección; la anterior se lee en orden inverso y se invierte.
+func (a *App) loadSaldosCursor(ctx context.Context, cp cursorParams, pageSize int, filter repository.SaldoFilter) (paginaCursor, error) {
+	var pagina paginaCursor
+	var desde *repository.SaldoCursor
+	atras := false
+	if cp.Token != nil {
+		desde, atras = &cp.Token.Clave, cp.Token.Atras
+	}
+	orden := cp.Orden
+	if atras {
+		orden = orden.Invertir()
+	}
+
+	items, err := a.Saldos.ListAfter(ctx, desde, pageSize+1, filter, orden)
+	if err != nil {
+		return pagina, err
+	}
+	hayMas := len(items) > pageSize
+	if hayMas {
+		items = items[:pageSize]
+	}
+	if atras {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	if items == nil {
+		items = []models.Saldo{}
+	}
+	pagina.Items = items
+
+	cursor := func(s models.Saldo, atras bool) string {
+		return codificarCursor(cursorToken{Orden: cp.Orden.String(), Atras: atras,
+			Clave: repository.CursorDeSaldo(s, cp.Orden)})
+	}
+	switch {
+	case len(items) > 0:
+		if (!atras && hayMas) || (atras && desde != nil) {
+			pagina.Siguiente = cursor(items[len(items)-1], false)
+		}
+		if (atras && hayMas) || (!atras && desde != nil) {
+			pagina.Anterior = cursor(items[0], true)
+		}
+	case desde != nil && !atras:
+		// Se pasó del final: volver desde el mismo punto.
+		pagina.Anterior = codificarCursor(cursorToken{Orden: cp.Orden.String(), Atras: true, Clave: *desde})
+	}
+
+	switch cp.Conteo {
+	case conteoExacto:
+		pagina.Total, err = a.Saldos.Count(ctx, filter)
+		pagina.ConTotal = true
+	case conteoAproximado:
+		pagina.Total, err = a.Saldos.EstimateCount(ctx, filter)
+		pagina.ConTotal, pagina.Aproximado = true, true
+	}
+	return pagina, err
+}
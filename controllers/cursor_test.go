@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"go_api/config"
+	"go_api/models"
+	"go_api/repository"
+)
+
+// newTestApp crea una instancia sobre los datos de demostración en memoria.
+func newTestApp(t *testing.T, cfg config.Config) *App {
+	t.Helper()
+	saldos, stocks := repository.DemoData()
+	app, err := NewAppWithRepositories(cfg,
+		repository.NewMemorySaldoRepository(saldos),
+		repository.NewMemoryStockRepository(stocks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+// pedirSaldos llama a /api/saldos con query y decodifica la respuesta por
+// cursor.
+func pedirSaldos(t *testing.T, app *App, query url.Values) (int, cursorResponse, []models.Saldo) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	app.ApiSaldosHandler(rec, httptest.NewRequest(http.MethodGet, "/api/saldos?"+query.Encode(), nil))
+	var resp cursorResponse
+	var items []models.Saldo
+	if rec.Code == http.StatusOK {
+		resp.Data = &items
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("respuesta inválida: %v", err)
+		}
+	}
+	return rec.Code, resp, items
+}
+
+func TestApiSaldosCursorRecorrido(t *testing.T) {
+	app := newTestApp(t, config.Config{})
+	for _, sort := range []string{"", "-CostoReal", "FechaIngreso,-NombreProducto", "SaldoAnterior"} {
+		t.Run(sort, func(t *testing.T) {
+			query := url.Values{"cursor": {""}, "pageSize": {"3"}, "sort": {sort}}
+			var adelante [][]string
+			var total int
+			for {
+				code, resp, items := pedirSaldos(t, app, query)
+				if code != http.StatusOK {
+					t.Fatalf("status = %d", code)
+				}
+				if resp.Total == nil {
+					t.Fatal("falta el total con count=approx por defecto")
+				}
+				total = *resp.Total
+				adelante = append(adelante, clavesSaldos(items))
+				if resp.NextCursor == "" {
+					break
+				}
+				query.Set("cursor", resp.NextCursor)
+			}
+			vistos := 0
+			for _, p := range adelante {
+				vistos += len(p)
+			}
+			if vistos != total || total == 0 {
+				t.Fatalf("se recorrieron %d saldos, total = %d", vistos, total)
+			}
+
+			// Hacia atrás con prevCursor hasta la primera página
+			_, resp, _ := pedirSaldos(t, app, query)
+			for i := len(adelante) - 2; i >= 0; i-- {
+				if resp.PrevCursor == "" {
+					t.Fatalf("falta prevCursor en la página %d", i+2)
+				}
+				query.Set("cursor", resp.PrevCursor)
+				var items []models.Saldo
+				var code int
+				code, resp, items = pedirSaldos(t, app, query)
+				if code != http.StatusOK {
+					t.Fatalf("status = %d", code)
+				}
+				if got := clavesSaldos(items); !reflect.DeepEqual(got, adelante[i]) {
+					t.Errorf("página %d hacia atrás = %v, se esperaba %v", i+1, got, adelante[i])
+				}
+			}
+			if resp.PrevCursor != "" {
+				t.Errorf("la primera página no debería tener prevCursor")
+			}
+		})
+	}
+}
+
+func clavesSaldos(items []models.Saldo) []string {
+	claves := make([]string, len(items))
+	for i, s := range items {
+		claves[i] = s.CodigoProducto + "|" + s.Zeta
+	}
+	return claves
+}
+
+func TestApiSaldosCursorInvalido(t *testing.T) {
+	app := newTestApp(t, config.Config{})
+	orden := repository.Orden{{Campo: "CostoReal", Desc: true}}
+	valido := codificarCursor(cursorToken{Orden: orden.String(),
+		Clave: repository.SaldoCursor{Valores: []interface{}{1500.0, "VT-001", "Z25001", 2025.0}}})
+
+	tests := []struct {
+		nombre string
+		cursor string
+		sort   string
+		want   int
+	}{
+		{"válido", valido, "-CostoReal", http.StatusOK},
+		{"otro orden", valido, "CostoReal", http.StatusBadRequest},
+		{"no es base64", "%%%", "-CostoReal", http.StatusBadRequest},
+		{"no es JSON", "bm8tanNvbg", "-CostoReal", http.StatusBadRequest},
+		{"tipo incorrecto", codificarCursor(cursorToken{Orden: orden.String(),
+			Clave: repository.SaldoCursor{Valores: []interface{}{"1500", "VT-001", "Z25001", 2025.0}}}), "-CostoReal", http.StatusBadRequest},
+		{"valores de menos", codificarCursor(cursorToken{Orden: orden.String(),
+			Clave: repository.SaldoCursor{Valores: []interface{}{1500.0}}}), "-CostoReal", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nombre, func(t *testing.T) {
+			code, _, _ := pedirSaldos(t, app, url.Values{"cursor": {tt.cursor}, "sort": {tt.sort}})
+			if code != tt.want {
+				t.Errorf("status = %d, se esperaba %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodificarCursor(t *testing.T) {
+	orden := repository.Orden{{Campo: "FechaIngreso"}, {Campo: "NombreProducto", Desc: true}}
+	s := models.Saldo{CodigoProducto: "VT-001", Zeta: "Z25001", AnioProduccion: 2025, NombreProducto: "VINO"}
+	for _, atras := range []bool{false, true} {
+		token := cursorToken{Orden: orden.String(), Atras: atras, Clave: repository.CursorDeSaldo(s, orden)}
+		got, err := decodificarCursor(codificarCursor(token), orden)
+		if err != nil {
+			t.Fatalf("decodificarCursor: %v", err)
+		}
+		// Los números vuelven como float64
+		token.Clave.Valores[len(token.Clave.Valores)-1] = 2025.0
+		if !reflect.DeepEqual(got, token) {
+			t.Errorf("decodificarCursor = %+v, se esperaba %+v", got, token)
+		}
+	}
+}
+
+func TestApiSaldosPageSizeMaximo(t *testing.T) {
+	app := newTestApp(t, config.Config{})
+	for _, query := range []url.Values{
+		{"cursor": {""}, "pageSize": {"9223372036854775807"}},
+		{"cursor": {""}, "pageSize": {"100000"}, "count": {"none"}},
+		{"pageSize": {"9223372036854775807"}},
+	} {
+		rec := httptest.NewRecorder()
+		app.ApiSaldosHandler(rec, httptest.NewRequest(http.MethodGet, "/api/saldos?"+query.Encode(), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", query.Encode(), rec.Code)
+		}
+		var resp struct {
+			PageSize int `json:"pageSize"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.PageSize != maxPageSize {
+			t.Errorf("%s: pageSize = %d, se esperaba %d", query.Encode(), resp.PageSize, maxPageSize)
+		}
+	}
+}
+
+// TestApiSaldosModosAgrupados comprueba que el listado por página y el
+// listado por cursor cuentan y devuelven los mismos saldos agrupados.
+func TestApiSaldosModosAgrupados(t *testing.T) {
+	saldos, stocks := repository.DemoData()
+	// Una fila repetida de la misma clave se suma en un solo saldo
+	saldos = append(saldos, saldos[0])
+	app, err := NewAppWithRepositories(config.Config{},
+		repository.NewMemorySaldoRepository(saldos),
+		repository.NewMemoryStockRepository(stocks))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	app.ApiSaldosHandler(rec, httptest.NewRequest(http.MethodGet, "/api/saldos?pageSize=500&sort=CodigoProducto", nil))
+	var porPagina []models.Saldo
+	resp := paginatedResponse{Data: &porPagina}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	code, conCursor, porCursor := pedirSaldos(t, app, url.Values{"cursor": {""}, "pageSize": {"500"}, "sort": {"CodigoProducto"}, "count": {"exact"}})
+	if code != http.StatusOK || conCursor.Total == nil {
+		t.Fatalf("status = %d, total = %v", code, conCursor.Total)
+	}
+	if resp.Total != len(saldos)-1 || *conCursor.Total != resp.Total {
+		t.Errorf("total por página = %d, por cursor = %d; se esperaban %d grupos", resp.Total, *conCursor.Total, len(saldos)-1)
+	}
+	if !reflect.DeepEqual(clavesSaldos(porPagina), clavesSaldos(porCursor)) {
+		t.Errorf("por página = %v, por cursor = %v", clavesSaldos(porPagina), clavesSaldos(porCursor))
+	}
+	for _, s := range porPagina {
+		if s.Zeta == saldos[0].Zeta && s.CantidadIngresada != 2*saldos[0].CantidadIngresada {
+			t.Errorf("la clave repetida suma %v, se esperaba %v", s.CantidadIngresada, 2*saldos[0].CantidadIngresada)
+		}
+	}
+}
+
+func TestApiSaldosConteoPorDefecto(t *testing.T) {
+	app := newTestApp(t, config.Config{})
+	tests := []struct {
+		count      string
+		conTotal   bool
+		aproximado bool
+	}{
+		{"", true, true},
+		{"approx", true, true},
+		{"exact", true, false},
+		{"none", false, false},
+	}
+	for _, tt := range tests {
+		code, resp, _ := pedirSaldos(t, app, url.Values{"cursor": {""}, "count": {tt.count}})
+		if code != http.StatusOK {
+			t.Fatalf("count=%q: status = %d", tt.count, code)
+		}
+		if (resp.Total != nil) != tt.conTotal || resp.TotalAproximado != tt.aproximado {
+			t.Errorf("count=%q: total = %v, aproximado = %v", tt.count, resp.Total, resp.TotalAproximado)
+		}
+	}
+}
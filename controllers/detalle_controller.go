@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_api/models"
+	"go_api/repository"
+	"go_api/views"
+)
+
+// detalleRoute identifica el tipo y la clave de las rutas de detalle:
+// {prefix}productos/{codigo} y {prefix}zetas/{zeta}.
+func detalleRoute(path, prefix string) (tipo, clave string, ok bool) {
+	if clave, ok = pathParam(path, prefix+"productos/", ""); ok {
+		return "producto", clave, true
+	}
+	if clave, ok = pathParam(path, prefix+"zetas/", ""); ok {
+		return "zeta", clave, true
+	}
+	return "", "", false
+}
+
+// loadDetalle obtiene los lotes de MySQL y el historial de STOCKS del producto
+// o zeta. Responde 404 si la clave no existe en ninguna de las dos bases.
+func (a *App) loadDetalle(w http.ResponseWriter, r *http.Request, tipo, clave string) (models.Detalle, bool) {
+	detalle := models.Detalle{Tipo: tipo, Clave: clave}
+
+	sucursales, err := parseSucursales(r.URL.Query(), a.Config.SucursalPorDefecto)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return detalle, false
+	}
+
+	saldoFilter := repository.SaldoFilter{Codigo: clave}
+	stockFilter := repository.StockFilter{Sucursales: sucursales, Codigo: clave}
+	if tipo == "zeta" {
+		saldoFilter = repository.SaldoFilter{Zeta: clave}
+		stockFilter = repository.StockFilter{Sucursales: sucursales, Zeta: clave}
+	}
+
+	detalle.Lotes, err = a.Saldos.List(r.Context(), saldoFilter)
+	if err != nil {
+		respondDataError(w, "Error obteniendo los lotes", err)
+		return detalle, false
+	}
+	detalle.Stocks, err = a.Stocks.Historial(r.Context(), stockFilter)
+	if err != nil {
+		respondDataError(w, "Error obteniendo el historial de stocks", err)
+		return detalle, false
+	}
+
+	if len(detalle.Lotes) == 0 && len(detalle.Stocks) == 0 {
+		http.Error(w, "No hay datos para "+tipo+" "+clave, http.StatusNotFound)
+		return detalle, false
+	}
+	if detalle.Lotes == nil {
+		detalle.Lotes = []models.Saldo{}
+	}
+	if detalle.Stocks == nil {
+		detalle.Stocks = []models.StockData{}
+	}
+
+	if len(detalle.Lotes) > 0 {
+		detalle.Nombre = detalle.Lotes[0].NombreProducto
+	} else {
+		detalle.Nombre = detalle.Stocks[0].NombreProducto
+	}
+	return detalle, true
+}
+
+// DetalleApiHandler maneja /api/productos/{codigo} y /api/zetas/{zeta}; las
+// rutas /api/zetas/{zeta}/series se delegan a SeriesApiHandler.
+func (a *App) DetalleApiHandler(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := serieRoute(r.URL.Path, "/api/"); ok {
+		a.SeriesApiHandler(w, r)
+		return
+	}
+	tipo, clave, ok := detalleRoute(r.URL.Path, "/api/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	detalle, ok := a.loadDetalle(w, r, tipo, clave)
+	if !ok {
+		return
+	}
+	writeJSON(w, detalle)
+}
+
+// DetalleViewHandler maneja /productos/{codigo} y /zetas/{zeta}; las rutas
+// /zetas/{zeta}/series se delegan a SeriesViewHandler.
+func (a *App) DetalleViewHandler(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := serieRoute(r.URL.Path, "/"); ok {
+		a.SeriesViewHandler(w, r)
+		return
+	}
+	tipo, clave, ok := detalleRoute(r.URL.Path, "/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	detalle, ok := a.loadDetalle(w, r, tipo, clave)
+	if !ok {
+		return
+	}
+	a.Views.RenderDetalle(w, views.DetalleViewData{Detalle: detalle})
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_api/config"
+	"go_api/models"
+	"go_api/repository"
+)
+
+func TestDetalleRoute(t *testing.T) {
+	tests := []struct {
+		path        string
+		tipo, clave string
+		ok          bool
+	}{
+		{"/api/productos/P-1", "producto", "P-1", true},
+		{"/api/zetas/Z-9", "zeta", "Z-9", true},
+		{"/api/productos/", "", "", false},
+		{"/api/productos/P-1/otro", "", "", false},
+		{"/api/otros/P-1", "", "", false},
+	}
+	for _, tt := range tests {
+		tipo, clave, ok := detalleRoute(tt.path, "/api/")
+		if tipo != tt.tipo || clave != tt.clave || ok != tt.ok {
+			t.Errorf("detalleRoute(%q) = %q, %q, %v", tt.path, tipo, clave, ok)
+		}
+	}
+}
+
+// pedirDetalle llama a la API de detalle y decodifica la respuesta.
+func pedirDetalle(t *testing.T, app *App, ruta string) (int, models.Detalle) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	app.DetalleApiHandler(rec, httptest.NewRequest(http.MethodGet, ruta, nil))
+	var d models.Detalle
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, d
+}
+
+func TestDetalleApi(t *testing.T) {
+	app := newTestApp(t, config.Config{SucursalPorDefecto: repository.SucursalPorDefecto})
+	saldos, stocks := repository.DemoData()
+	codigo := saldos[0].CodigoProducto
+
+	code, d := pedirDetalle(t, app, "/api/productos/"+codigo+"?sucursal=211,212")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if d.Tipo != "producto" || d.Clave != codigo || d.Nombre == "" || len(d.Lotes) == 0 {
+		t.Errorf("detalle = %+v", d)
+	}
+	for _, l := range d.Lotes {
+		if l.CodigoProducto != codigo {
+			t.Errorf("lote de otro producto: %+v", l)
+		}
+	}
+	// El historial lista todas las filas de STOCKS, de la más reciente a la más antigua
+	want := 0
+	for _, s := range stocks {
+		if s.CodigoProducto == codigo {
+			want++
+		}
+	}
+	if len(d.Stocks) != want {
+		t.Errorf("historial = %d filas, se esperaban %d", len(d.Stocks), want)
+	}
+	for i := 1; i < len(d.Stocks); i++ {
+		if d.Stocks[i].Fecha.After(d.Stocks[i-1].Fecha) {
+			t.Errorf("historial desordenado en la fila %d", i)
+		}
+	}
+
+	code, d = pedirDetalle(t, app, "/api/zetas/"+saldos[0].Zeta)
+	if code != http.StatusOK || d.Tipo != "zeta" || len(d.Lotes) == 0 || d.Lotes[0].Zeta != saldos[0].Zeta {
+		t.Errorf("detalle de zeta: status = %d, %+v", code, d)
+	}
+
+	for ruta, status := range map[string]int{
+		"/api/productos/NO-EXISTE":                 http.StatusNotFound,
+		"/api/productos/" + codigo + "?sucursal=x": http.StatusBadRequest,
+	} {
+		if code, _ := pedirDetalle(t, app, ruta); code != status {
+			t.Errorf("%s: status = %d, se esperaba %d", ruta, code, status)
+		}
+	}
+
+	// /api/zetas/{zeta}/series se delega a la serie
+	rec := httptest.NewRecorder()
+	app.DetalleApiHandler(rec, httptest.NewRequest(http.MethodGet, "/api/zetas/"+saldos[0].Zeta+"/series", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"lotes"`) || !strings.Contains(rec.Body.String(), `"puntos"`) {
+		t.Errorf("serie de zeta: status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	app.DetalleViewHandler(rec, httptest.NewRequest(http.MethodGet, "/productos/"+codigo, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), codigo) {
+		t.Errorf("vista de detalle: status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,340 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_api/models"
+)
+
+// Formatos de exportación.
+const (
+	formatoXLSX   = "xlsx"
+	formatoCSV    = "csv"
+	formatoNDJSON = "ndjson"
+	formatoPDF    = "pdf"
+)
+
+// filasPorFlush es cada cuántas filas se vacía el buffer en los formatos que
+// se envían fila a fila.
+const filasPorFlush = 500
+
+// utf8BOM es la marca de orden de bytes que Excel necesita para reconocer un
+// CSV como UTF-8.
+const utf8BOM = "\xEF\xBB\xBF"
+
+// exportOptions son las opciones de formato de una exportación.
+type exportOptions struct {
+	Format    string   // xlsx (por defecto), csv, ndjson o pdf
+	Delimiter rune     // separador de campos del CSV
+	Decimal   string   // separador decimal del CSV: "." o ","
+	BOM       bool     // anteponer la marca UTF-8 al CSV
+	Filtros   []string // filtros de la consulta, para imprimirlos en el PDF
+}
+
+// etiquetasFiltro son los nombres legibles de los parámetros de filtro que se
+// imprimen en el PDF, en el orden en que se muestran.
+var etiquetasFiltro = []struct{ param, etiqueta string }{
+	{"year", "Año"},
+	{"anio", "Año"},
+	{"sucursal", "Sucursales"},
+	{"search", "Búsqueda"},
+	{"codigo", "Código"},
+	{"zeta", "Zeta"},
+	{"fechaDesde", "Ingreso desde"},
+	{"fechaHasta", "Ingreso hasta"},
+	{"saldoMin", "Saldo mínimo"},
+	{"saldoMax", "Saldo máximo"},
+	{"cruce", "Cruce"},
+	{"margen", "Margen"},
+	{"margenMin", "Margen mínimo"},
+	{"margenMax", "Margen máximo"},
+	{"bajoCosto", "Solo ofertas bajo costo"},
+	{"tramos", "Tramos (días)"},
+	{"sort", "Orden"},
+	{"dir", "Dirección"},
+	{"page", "Página"},
+	{"pageSize", "Filas por página"},
+}
+
+// filtrosAplicados describe los filtros presentes en la consulta como
+// "Etiqueta: valor".
+func filtrosAplicados(query url.Values) []string {
+	var filtros []string
+	for _, f := range etiquetasFiltro {
+		if valores := query[f.param]; len(valores) > 0 && strings.Join(valores, "") != "" {
+			filtros = append(filtros, f.etiqueta+": "+strings.Join(valores, ", "))
+		}
+	}
+	return filtros
+}
+
+// parseExportOptions lee format, delimiter (",", ";", "tab" o "|"), decimal
+// ("." o ",") y bom=true. formatos son los formatos que admite la ruta.
+func parseExportOptions(query url.Values, formatos ...string) (exportOptions, error) {
+	opts := exportOptions{
+		Format:    query.Get("format"),
+		Delimiter: ',',
+		Decimal:   query.Get("decimal"),
+		BOM:       query.Get("bom") == "true",
+		Filtros:   filtrosAplicados(query),
+	}
+	if opts.Format == "" {
+		opts.Format = formatoXLSX
+	}
+	valido := false
+	for _, f := range formatos {
+		valido = valido || f == opts.Format
+	}
+	if !valido {
+		return opts, fmt.Errorf("format inválido: %q (admite %s)", opts.Format, strings.Join(formatos, ", "))
+	}
+
+	switch d := query.Get("delimiter"); d {
+	case "", ",":
+	case ";":
+		opts.Delimiter = ';'
+	case "tab", "\t":
+		opts.Delimiter = '\t'
+	case "|":
+		opts.Delimiter = '|'
+	default:
+		return opts, fmt.Errorf("delimiter inválido: %q", d)
+	}
+
+	switch opts.Decimal {
+	case "":
+		opts.Decimal = "."
+	case ".", ",":
+	default:
+		return opts, fmt.Errorf("decimal inválido: %q", opts.Decimal)
+	}
+	if opts.Decimal == "," && opts.Delimiter == ',' {
+		return opts, fmt.Errorf("decimal y delimiter no pueden ser ambos \",\"")
+	}
+	return opts, nil
+}
+
+// texto convierte un valor de una fila de exportación a texto para el CSV.
+func (o exportOptions) texto(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if o.Decimal == "," {
+			s = strings.Replace(s, ".", ",", 1)
+		}
+		return s
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format("2006-01-02")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// tipoColumna indica cómo se escriben y formatean los valores de una columna
+// en el Excel.
+type tipoColumna int
+
+const (
+	colTexto tipoColumna = iota
+	colEntero
+	colDecimal
+	colMoneda
+	colFecha
+)
+
+// columna describe una columna de una exportación. Ancho se mide en
+// caracteres (cero usa el del tipo), Total agrega la columna a la fila de
+// totales y SoloDatos la omite del PDF, que no tiene espacio para el detalle
+// completo.
+type columna struct {
+	Titulo    string
+	Tipo      tipoColumna
+	Ancho     float64
+	Total     bool
+	SoloDatos bool
+}
+
+// titulos devuelve los encabezados de las columnas.
+func titulos(columnas []columna) []string {
+	t := make([]string, len(columnas))
+	for i, c := range columnas {
+		t[i] = c.Titulo
+	}
+	return t
+}
+
+// columnasSaldosMensuales son las doce columnas "Saldo Fin <Mes>", con total
+// y fuera del PDF.
+func columnasSaldosMensuales() []columna {
+	columnas := make([]columna, 0, len(models.Meses))
+	for _, mes := range models.Meses {
+		columnas = append(columnas, columna{"Saldo Fin " + mes, colDecimal, 0, true, true})
+	}
+	return columnas
+}
+
+// exportWriter escribe una exportación fila a fila. Write recibe el registro
+// original (para NDJSON) y sus valores en el orden de los encabezados (para
+// CSV y Excel).
+type exportWriter interface {
+	Write(registro any, fila []any) error
+	Close() error
+}
+
+// libroExport lo implementan los formatos que admiten varias hojas (Excel).
+// NuevaHoja cierra la hoja actual y las filas siguientes van a la nueva.
+type libroExport interface {
+	NuevaHoja(nombre string, columnas []columna) error
+}
+
+// newExportWriter envía las cabeceras de la respuesta para el formato pedido
+// y devuelve el writer con los encabezados ya escritos. filename no lleva
+// extensión; hoja es el nombre de la primera hoja del Excel.
+func newExportWriter(w http.ResponseWriter, opts exportOptions, filename, hoja string, columnas []columna) (exportWriter, error) {
+	switch opts.Format {
+	case formatoCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename+".csv")
+		if opts.BOM {
+			if _, err := w.Write([]byte(utf8BOM)); err != nil {
+				return nil, err
+			}
+		}
+		cw := csv.NewWriter(w)
+		cw.Comma = opts.Delimiter
+		e := &csvExport{w: cw, opts: opts, flusher: flusherDe(w)}
+		return e, cw.Write(titulos(columnas))
+
+	case formatoNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename+".ndjson")
+		bw := bufio.NewWriter(w)
+		return &ndjsonExport{bw: bw, enc: json.NewEncoder(bw), flusher: flusherDe(w)}, nil
+
+	case formatoPDF:
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename+".pdf")
+		return newPDFExport(w, hoja, opts.Filtros, columnas), nil
+
+	default:
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename+".xlsx")
+		return newXLSXExport(w, hoja, columnas)
+	}
+}
+
+// exportDescartable lo implementan los formatos que arman el archivo entero
+// antes de enviarlo (Excel). Descartar libera lo escrito sin enviar nada.
+type exportDescartable interface {
+	Descartar()
+}
+
+// errorExportacion informa un error al escribir una exportación ya
+// comenzada. Si el PDF superó maxFilasPDF todavía no se envió nada y responde
+// 413 con el motivo; el Excel tampoco envió nada y responde 500. En otro caso
+// ya se enviaron filas y solo queda cortar la descarga.
+func errorExportacion(w http.ResponseWriter, ew exportWriter, err error) {
+	if errors.Is(err, errLimitePDF) {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	log.Println("Error al escribir la exportación:", err)
+	if d, ok := ew.(exportDescartable); ok {
+		d.Descartar()
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Error al escribir la exportación", http.StatusInternalServerError)
+	}
+}
+
+// flusherDe devuelve el http.Flusher de la respuesta o nil si no lo tiene.
+func flusherDe(w http.ResponseWriter) http.Flusher {
+	f, _ := w.(http.Flusher)
+	return f
+}
+
+// csvExport envía el CSV a medida que se escriben las filas.
+type csvExport struct {
+	w       *csv.Writer
+	opts    exportOptions
+	flusher http.Flusher
+	filas   int
+}
+
+func (e *csvExport) Write(_ any, fila []any) error {
+	valores := make([]string, len(fila))
+	for i, v := range fila {
+		valores[i] = e.opts.texto(v)
+	}
+	if err := e.w.Write(valores); err != nil {
+		return err
+	}
+	e.filas++
+	if e.filas%filasPorFlush == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *csvExport) flush() error {
+	e.w.Flush()
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+	return e.w.Error()
+}
+
+func (e *csvExport) Close() error {
+	return e.flush()
+}
+
+// ndjsonExport envía un objeto JSON por línea a medida que se escriben las
+// filas.
+type ndjsonExport struct {
+	bw      *bufio.Writer
+	enc     *json.Encoder
+	flusher http.Flusher
+	filas   int
+}
+
+func (e *ndjsonExport) Write(registro any, _ []any) error {
+	if err := e.enc.Encode(registro); err != nil {
+		return err
+	}
+	e.filas++
+	if e.filas%filasPorFlush == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *ndjsonExport) flush() error {
+	if err := e.bw.Flush(); err != nil {
+		return err
+	}
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+	return nil
+}
+
+func (e *ndjsonExport) Close() error {
+	return e.flush()
+}
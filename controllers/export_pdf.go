@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"go_api/views"
+)
+
+// Medidas del PDF, en milímetros y puntos.
+const (
+	pdfMargen       = 10.0
+	pdfMargenSup    = 18.0
+	pdfMargenInf    = 15.0 // espacio reservado para el pie
+	pdfAltoFila     = 5.0
+	pdfFuenteTabla  = 7.0
+	pdfFuenteTitulo = 11.0
+	pdfAltoFiltro   = 4.0
+	// encabezado de la tabla, en dos líneas
+	pdfAltoEncabezado = 2 * pdfAltoFila * 0.7
+)
+
+// maxFilasPDF limita las filas de un PDF: el documento se arma en memoria y
+// un reporte más largo no es imprimible. Los demás formatos no tienen límite.
+const maxFilasPDF = 5000
+
+// errLimitePDF es el error de Write al superar maxFilasPDF. Como el PDF se
+// envía al cerrar, todavía se puede responder con el error.
+var errLimitePDF = fmt.Errorf("el PDF admite hasta %d filas: acote los filtros o exporte en xlsx o csv", maxFilasPDF)
+
+// pdfExport arma un reporte imprimible en A4 apaisado: encabezado con el
+// título y la fecha de generación, pie con el número de página, los filtros
+// en la primera página y una tabla por sección con su fila de totales. Las
+// columnas SoloDatos se omiten y el resto se reparte el ancho de la página.
+// gofpdf arma el documento en memoria; se envía al cerrar.
+type pdfExport struct {
+	w        io.Writer
+	pdf      *gofpdf.Fpdf
+	tr       func(string) string // UTF-8 a la codificación de las fuentes base
+	columnas []columna           // columnas impresas
+	indices  []int               // posición de cada columna impresa en la fila
+	anchos   []float64
+	totales  []float64
+	filas    int // filas de la sección actual
+	total    int // filas de todo el documento
+}
+
+// newPDFExport crea el documento con su primera página y la tabla de la
+// primera sección.
+func newPDFExport(w io.Writer, titulo string, filtros []string, columnas []columna) *pdfExport {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(pdfMargen, pdfMargenSup, pdfMargen)
+	pdf.SetAutoPageBreak(false, pdfMargenInf)
+	pdf.AliasNbPages("")
+	e := &pdfExport{w: w, pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+
+	generado := time.Now().Format("02-01-2006 15:04")
+	pdf.SetHeaderFunc(func() {
+		pdf.SetY(8)
+		pdf.SetFont("Helvetica", "B", pdfFuenteTitulo)
+		pdf.CellFormat(0, 6, e.tr(titulo), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 6, e.tr("Generado el "+generado), "B", 1, "R", false, 0, "")
+		pdf.SetY(pdfMargenSup)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargenInf + 3)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 5, e.tr(fmt.Sprintf("Página %d de {nb}", pdf.PageNo())), "T", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.CellFormat(0, 5, e.tr("Filtros aplicados"), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	if len(filtros) == 0 {
+		filtros = []string{"Ninguno: se incluyen todos los registros"}
+	}
+	ancho, _ := pdf.GetPageSize()
+	for _, f := range filtros {
+		// Los valores largos ocupan varias líneas y pueden pasar de página
+		for _, l := range pdf.SplitLines([]byte(e.tr(f)), ancho-2*pdfMargen-2*pdf.GetCellMargin()) {
+			if !e.cabe(pdfAltoFiltro) {
+				pdf.AddPage()
+			}
+			pdf.CellFormat(0, pdfAltoFiltro, string(l), "", 1, "L", false, 0, "")
+		}
+	}
+	pdf.Ln(3)
+
+	e.comenzarSeccion(columnas)
+	return e
+}
+
+// comenzarSeccion elige las columnas impresas, reparte el ancho de la página
+// según el ancho de cada una e imprime el encabezado de la tabla, en una
+// página nueva si no cabe junto con una fila.
+func (e *pdfExport) comenzarSeccion(columnas []columna) {
+	e.columnas, e.indices, e.anchos, e.filas = nil, nil, nil, 0
+	suma := 0.0
+	for i, c := range columnas {
+		if c.SoloDatos {
+			continue
+		}
+		e.columnas = append(e.columnas, c)
+		e.indices = append(e.indices, i)
+		e.anchos = append(e.anchos, anchoColumna(c))
+		suma += anchoColumna(c)
+	}
+	ancho, _ := e.pdf.GetPageSize()
+	for i := range e.anchos {
+		e.anchos[i] *= (ancho - 2*pdfMargen) / suma
+	}
+	e.totales = make([]float64, len(e.columnas))
+	if !e.cabe(pdfAltoEncabezado + pdfAltoFila) {
+		e.pdf.AddPage()
+	}
+	e.encabezadoTabla()
+}
+
+// cabe indica si alto milímetros más caben en la página actual sin invadir
+// el pie.
+func (e *pdfExport) cabe(alto float64) bool {
+	_, altoPagina := e.pdf.GetPageSize()
+	return e.pdf.GetY()+alto <= altoPagina-pdfMargenInf
+}
+
+// encabezadoTabla imprime los títulos de las columnas en hasta dos líneas.
+func (e *pdfExport) encabezadoTabla() {
+	const altoLinea = pdfAltoEncabezado / 2
+	e.pdf.SetFont("Helvetica", "B", pdfFuenteTabla)
+	e.pdf.SetFillColor(217, 217, 217)
+	x, y := e.pdf.GetXY()
+	for i, c := range e.columnas {
+		e.pdf.Rect(x, y, e.anchos[i], 2*altoLinea, "FD")
+		lineas := e.pdf.SplitLines([]byte(e.tr(c.Titulo)), e.anchos[i]-2*e.pdf.GetCellMargin())
+		if len(lineas) > 2 {
+			lineas = lineas[:2]
+		}
+		arriba := y + float64(2-len(lineas))*altoLinea/2
+		for j, l := range lineas {
+			e.pdf.SetXY(x, arriba+float64(j)*altoLinea)
+			e.pdf.CellFormat(e.anchos[i], altoLinea, string(l), "", 0, "C", false, 0, "")
+		}
+		x += e.anchos[i]
+	}
+	e.pdf.SetXY(pdfMargen, y+2*altoLinea)
+}
+
+// saltoDePagina agrega una página con el encabezado de la tabla si la fila
+// siguiente no cabe en la actual.
+func (e *pdfExport) saltoDePagina() {
+	if !e.cabe(pdfAltoFila) {
+		e.pdf.AddPage()
+		e.encabezadoTabla()
+	}
+}
+
+func (e *pdfExport) Write(_ any, fila []any) error {
+	if e.total >= maxFilasPDF {
+		return errLimitePDF
+	}
+	e.saltoDePagina()
+	e.filas++
+	e.total++
+	e.pdf.SetFont("Helvetica", "", pdfFuenteTabla)
+	for k, i := range e.indices {
+		var v any = ""
+		if i < len(fila) {
+			v = fila[i]
+		}
+		texto, alineacion := e.celda(e.columnas[k], v)
+		if e.columnas[k].Total {
+			switch n := v.(type) {
+			case int:
+				e.totales[k] += float64(n)
+			case float64:
+				e.totales[k] += n
+			}
+		}
+		e.pdf.CellFormat(e.anchos[k], pdfAltoFila, e.recortar(texto, e.anchos[k]), "1", 0, alineacion, false, 0, "")
+	}
+	e.pdf.Ln(-1)
+	return e.pdf.Error()
+}
+
+// celda formatea un valor según el tipo de su columna y devuelve también su
+// alineación.
+func (e *pdfExport) celda(c columna, v any) (string, string) {
+	switch v := v.(type) {
+	case int:
+		return strconv.Itoa(v), "R"
+	case float64:
+		if c.Tipo == colMoneda && v < 0 {
+			return "-$" + views.FormatNumber(-v), "R"
+		}
+		if c.Tipo == colMoneda {
+			return "$" + views.FormatNumber(v), "R"
+		}
+		return views.FormatNumber(v), "R"
+	case time.Time:
+		if v.IsZero() {
+			return "", "C"
+		}
+		return v.Format("02-01-2006"), "C"
+	default:
+		return fmt.Sprint(v), "L"
+	}
+}
+
+// recortar traduce s a la codificación de la fuente y lo acorta para que
+// quepa en el ancho de la celda.
+func (e *pdfExport) recortar(s string, ancho float64) string {
+	s = e.tr(s)
+	disponible := ancho - 2*e.pdf.GetCellMargin()
+	if e.pdf.GetStringWidth(s) <= disponible {
+		return s
+	}
+	for len(s) > 0 && e.pdf.GetStringWidth(s+"..") > disponible {
+		s = s[:len(s)-1]
+	}
+	return s + ".."
+}
+
+// cerrarSeccion imprime la fila de totales de la tabla actual.
+func (e *pdfExport) cerrarSeccion() {
+	conTotales := false
+	for _, c := range e.columnas {
+		conTotales = conTotales || c.Total
+	}
+	if !conTotales || e.filas == 0 {
+		return
+	}
+	e.saltoDePagina()
+	e.pdf.SetFont("Helvetica", "B", pdfFuenteTabla)
+	for k, c := range e.columnas {
+		texto, alineacion := "", "L"
+		switch {
+		case c.Total && c.Tipo == colEntero:
+			texto, alineacion = strconv.FormatFloat(e.totales[k], 'f', -1, 64), "R"
+		case c.Total:
+			texto, alineacion = e.celda(c, e.totales[k])
+		case k == 0:
+			texto = "Total"
+		}
+		e.pdf.CellFormat(e.anchos[k], pdfAltoFila, e.recortar(texto, e.anchos[k]), "1", 0, alineacion, false, 0, "")
+	}
+	e.pdf.Ln(-1)
+}
+
+// NuevaHoja cierra la tabla actual y comienza otra sección en una página
+// nueva, con nombre como subtítulo.
+func (e *pdfExport) NuevaHoja(nombre string, columnas []columna) error {
+	e.cerrarSeccion()
+	e.pdf.AddPage()
+	e.pdf.SetFont("Helvetica", "B", 10)
+	e.pdf.CellFormat(0, 6, e.tr(nombre), "", 1, "L", false, 0, "")
+	e.pdf.Ln(1)
+	e.comenzarSeccion(columnas)
+	return e.pdf.Error()
+}
+
+// Close imprime los últimos totales y envía el documento.
+func (e *pdfExport) Close() error {
+	e.cerrarSeccion()
+	return e.pdf.Output(e.w)
+}
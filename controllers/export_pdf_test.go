@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/config"
+	"go_api/models"
+	"go_api/repository"
+)
+
+// saldosPrueba genera n saldos distintos.
+func saldosPrueba(n int) []models.Saldo {
+	saldos := make([]models.Saldo, n)
+	for i := range saldos {
+		saldos[i] = models.Saldo{
+			CodigoProducto: fmt.Sprintf("P-%05d", i),
+			Zeta:           fmt.Sprintf("Z%05d", i),
+			AnioProduccion: 2025,
+			NombreProducto: "PRODUCTO",
+			FechaIngreso:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	return saldos
+}
+
+func TestExportSaldosPDFLimite(t *testing.T) {
+	tests := []struct {
+		nombre string
+		filas  int
+		want   int
+	}{
+		{"dentro del límite", 10, http.StatusOK},
+		{"en el límite", maxFilasPDF, http.StatusOK},
+		{"sobre el límite", maxFilasPDF + 1, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nombre, func(t *testing.T) {
+			app, err := NewAppWithRepositories(config.Config{},
+				repository.NewMemorySaldoRepository(saldosPrueba(tt.filas)),
+				repository.NewMemoryStockRepository(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			app.ExportSaldosHandler(rec, httptest.NewRequest(http.MethodGet, "/export?format=pdf", nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, se esperaba %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				if rec.Header().Get("Content-Disposition") != "" {
+					t.Error("el error no debería llevar el archivo adjunto")
+				}
+				if !strings.Contains(rec.Body.String(), fmt.Sprint(maxFilasPDF)) {
+					t.Errorf("el mensaje no indica el límite: %q", rec.Body.String())
+				}
+				return
+			}
+			if !bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF")) {
+				t.Errorf("la respuesta no es un PDF: %.20q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestPDFFiltrosLargosPasanDePagina(t *testing.T) {
+	columnas := []columna{{"Código", colTexto, 0, false, false}, {"Saldo", colDecimal, 0, true, false}}
+	tests := []struct {
+		nombre  string
+		filtros []string
+		paginas int
+	}{
+		{"sin filtros", nil, 1},
+		{"pocos filtros", []string{"Año: 2025", "Búsqueda: vino"}, 1},
+		{"búsqueda muy larga", []string{"Búsqueda: " + strings.Repeat("palabra ", 3000)}, 2},
+		{"muchos filtros", strings.Split(strings.Repeat("Sucursales: 1\n", 80), "\n"), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nombre, func(t *testing.T) {
+			var buf bytes.Buffer
+			e := newPDFExport(&buf, "Saldos", tt.filtros, columnas)
+			if got := e.pdf.PageNo(); got < tt.paginas {
+				t.Errorf("páginas = %d, se esperaban al menos %d", got, tt.paginas)
+			}
+			_, alto := e.pdf.GetPageSize()
+			if e.pdf.GetY() > alto-pdfMargenInf {
+				t.Errorf("el encabezado de la tabla invade el pie: y = %v", e.pdf.GetY())
+			}
+			if err := e.Write(nil, []any{"P-1", 1.5}); err != nil {
+				t.Fatal(err)
+			}
+			if err := e.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestPDFCeldasYTotales(t *testing.T) {
+	e := newPDFExport(&bytes.Buffer{}, "Prueba", nil, []columna{
+		{"Texto", colTexto, 0, false, false},
+		{"Entero", colEntero, 0, true, false},
+		{"Moneda", colMoneda, 0, true, false},
+		{"Oculta", colTexto, 0, false, true},
+	})
+	if len(e.columnas) != 3 {
+		t.Fatalf("columnas impresas = %d, se esperaba omitir la de solo datos", len(e.columnas))
+	}
+	for _, fila := range [][]any{{"a", 2, 10.5, "x"}, {"b", 3, -0.5, "y"}} {
+		if err := e.Write(nil, fila); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e.totales[1] != 5 || e.totales[2] != 10 {
+		t.Errorf("totales = %v, se esperaba [0 5 10]", e.totales)
+	}
+
+	tests := []struct {
+		col   columna
+		valor any
+		texto string
+		alin  string
+	}{
+		{columna{Tipo: colMoneda}, -1234.5, "-$1.234,50", "R"},
+		{columna{Tipo: colMoneda}, 10.0, "$10,00", "R"},
+		{columna{Tipo: colEntero}, 7, "7", "R"},
+		{columna{Tipo: colFecha}, time.Date(2025, 3, 9, 0, 0, 0, 0, time.UTC), "09-03-2025", "C"},
+		{columna{Tipo: colFecha}, time.Time{}, "", "C"},
+		{columna{Tipo: colTexto}, "vino", "vino", "L"},
+	}
+	for _, tt := range tests {
+		texto, alin := e.celda(tt.col, tt.valor)
+		if texto != tt.texto || alin != tt.alin {
+			t.Errorf("celda(%v) = %q, %q; se esperaba %q, %q", tt.valor, texto, alin, tt.texto, tt.alin)
+		}
+	}
+}
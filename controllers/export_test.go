@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExportOptions(t *testing.T) {
+	opts, err := parseExportOptions(url.Values{}, formatoXLSX, formatoCSV)
+	if err != nil || opts.Format != formatoXLSX || opts.Delimiter != ',' || opts.Decimal != "." || opts.BOM {
+		t.Errorf("por defecto = %+v, %v", opts, err)
+	}
+
+	tests := []struct {
+		delimiter, decimal string
+		wantDelimiter      rune
+		wantDecimal        string
+	}{
+		{";", ",", ';', ","},
+		{"tab", ",", '\t', ","},
+		{"\t", "", '\t', "."},
+		{"|", ".", '|', "."},
+	}
+	for _, tt := range tests {
+		q := url.Values{"format": {"csv"}, "delimiter": {tt.delimiter}, "decimal": {tt.decimal}, "bom": {"true"}}
+		opts, err := parseExportOptions(q, formatoCSV)
+		if err != nil || opts.Delimiter != tt.wantDelimiter || opts.Decimal != tt.wantDecimal || !opts.BOM {
+			t.Errorf("%v = %+v, %v", q, opts, err)
+		}
+	}
+
+	for _, q := range []url.Values{
+		{"format": {"pdf"}}, // la ruta no lo admite
+		{"format": {"csv"}, "delimiter": {":"}},
+		{"format": {"csv"}, "decimal": {";"}},
+		{"format": {"csv"}, "decimal": {","}}, // la coma no puede ser separador y decimal
+	} {
+		if _, err := parseExportOptions(q, formatoXLSX, formatoCSV); err == nil {
+			t.Errorf("%v: se esperaba error", q)
+		}
+	}
+}
+
+func TestExportOptionsTexto(t *testing.T) {
+	punto := exportOptions{Decimal: "."}
+	coma := exportOptions{Decimal: ","}
+	fecha := time.Date(2025, time.March, 7, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		opts exportOptions
+		v    any
+		want string
+	}{
+		{punto, 1234.5, "1234.5"},
+		{coma, 1234.5, "1234,5"},
+		{coma, 3.0, "3"},
+		{coma, 12, "12"},
+		{coma, "1.5", "1.5"}, // los textos no se tocan
+		{punto, fecha, "2025-03-07"},
+		{punto, time.Time{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.texto(tt.v); got != tt.want {
+			t.Errorf("texto(%v) con decimal %q = %q, se esperaba %q", tt.v, tt.opts.Decimal, got, tt.want)
+		}
+	}
+}
+
+func TestCSVExport(t *testing.T) {
+	rec := httptest.NewRecorder()
+	opts := exportOptions{Format: formatoCSV, Delimiter: ';', Decimal: ",", BOM: true}
+	ew, err := newExportWriter(rec, opts, "saldos", "Saldos", columnasPrueba)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ew.Write(nil, []any{"VINO; TINTO", 2, 1.25, 10.5, time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rec.Header().Get("Content-Disposition"); got != "attachment; filename=saldos.csv" {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, utf8BOM) {
+		t.Fatal("falta la marca UTF-8")
+	}
+	lineas := strings.Split(strings.TrimSuffix(strings.TrimPrefix(body, utf8BOM), "\n"), "\n")
+	if len(lineas) != 2 || lineas[0] != strings.Join(titulos(columnasPrueba), ";") {
+		t.Fatalf("CSV = %q", body)
+	}
+	// El texto con el separador va entre comillas
+	if want := `"VINO; TINTO";2;1,25;10,5;2025-01-02`; lineas[1] != want {
+		t.Errorf("fila = %q, se esperaba %q", lineas[1], want)
+	}
+
+	// Sin bom=true no se antepone la marca
+	rec = httptest.NewRecorder()
+	ew, _ = newExportWriter(rec, exportOptions{Format: formatoCSV, Delimiter: ',', Decimal: "."}, "saldos", "Saldos", columnasPrueba)
+	ew.Close()
+	if strings.HasPrefix(rec.Body.String(), utf8BOM) {
+		t.Error("marca UTF-8 sin bom=true")
+	}
+}
+
+func TestNDJSONExport(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ew, err := newExportWriter(rec, exportOptions{Format: formatoNDJSON}, "saldos", "Saldos", columnasPrueba)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type registro struct {
+		Zeta  string  `json:"zeta"`
+		Saldo float64 `json:"saldo"`
+	}
+	// Más filas que filasPorFlush para pasar por el vaciado intermedio
+	n := filasPorFlush + 3
+	for i := 0; i < n; i++ {
+		if err := ew.Write(registro{"Z", float64(i)}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	sc := bufio.NewScanner(rec.Body)
+	filas := 0
+	for sc.Scan() {
+		var r registro
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("línea %d: %v", filas+1, err)
+		}
+		if r.Saldo != float64(filas) {
+			t.Errorf("línea %d = %+v", filas+1, r)
+		}
+		filas++
+	}
+	if filas != n {
+		t.Errorf("%d líneas, se esperaban %d", filas, n)
+	}
+}
@@ -0,0 +1,322 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// El Excel se arma con el StreamWriter de excelize: cada hoja se escribe fila
+// a fila a un buffer que pasa a un archivo temporal cuando crece, así que el
+// libro no queda entero en memoria. Como un zip no puede cerrarse hasta
+// tener todas sus partes, la respuesta se envía en Close, igual que el PDF.
+
+// anchoPorTipo es el ancho por defecto, en caracteres, de cada tipo de
+// columna.
+var anchoPorTipo = map[tipoColumna]float64{
+	colTexto:   14,
+	colEntero:  10,
+	colDecimal: 12,
+	colMoneda:  14,
+	colFecha:   12,
+}
+
+// Formatos numéricos de las celdas: los built-in de excelize para enteros y
+// decimales y formatos propios para moneda y fecha.
+const (
+	numFmtEntero  = 1
+	numFmtDecimal = 4
+	formatoMoneda = `"$"#,##0.00`
+	formatoFecha  = "dd-mm-yyyy"
+)
+
+// origenFechasExcel es el día cero de las fechas seriales de Excel.
+var origenFechasExcel = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// estilosXLSX son los ids de los estilos de celda registrados en el libro.
+type estilosXLSX struct {
+	encabezado int
+	entero     int
+	decimal    int
+	moneda     int
+	fecha      int
+	// Los de la fila de totales, en negrita.
+	totalTexto   int
+	totalEntero  int
+	totalDecimal int
+	totalMoneda  int
+}
+
+// hojaXLSX es el estado de la hoja que se está escribiendo.
+type hojaXLSX struct {
+	nombre   string
+	sw       *excelize.StreamWriter
+	columnas []columna
+	filas    int // filas de datos, sin el encabezado
+	totales  []float64
+}
+
+// xlsxExport escribe un libro de Excel con el encabezado fijo y en negrita,
+// filtro automático, anchos por columna, celdas de fecha y moneda reales y
+// una fila de totales al final de cada hoja.
+type xlsxExport struct {
+	w       http.ResponseWriter
+	f       *excelize.File
+	estilos estilosXLSX
+	hoja    *hojaXLSX
+	hojas   int // hojas comenzadas
+}
+
+// newXLSXExport crea el libro con su primera hoja.
+func newXLSXExport(w http.ResponseWriter, hoja string, columnas []columna) (*xlsxExport, error) {
+	e := &xlsxExport{w: w, f: excelize.NewFile()}
+	if err := e.registrarEstilos(); err != nil {
+		e.f.Close()
+		return nil, err
+	}
+	if err := e.NuevaHoja(hoja, columnas); err != nil {
+		e.f.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// registrarEstilos agrega al libro los estilos de celda de la exportación.
+func (e *xlsxExport) registrarEstilos() error {
+	moneda, fecha := formatoMoneda, formatoFecha
+	negrita := &excelize.Font{Bold: true}
+	estilos := []struct {
+		id    *int
+		style excelize.Style
+	}{
+		{&e.estilos.encabezado, excelize.Style{Font: negrita,
+			Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9D9D9"}}}},
+		{&e.estilos.entero, excelize.Style{NumFmt: numFmtEntero}},
+		{&e.estilos.decimal, excelize.Style{NumFmt: numFmtDecimal}},
+		{&e.estilos.moneda, excelize.Style{CustomNumFmt: &moneda}},
+		{&e.estilos.fecha, excelize.Style{CustomNumFmt: &fecha}},
+		{&e.estilos.totalTexto, excelize.Style{Font: negrita}},
+		{&e.estilos.totalEntero, excelize.Style{Font: negrita, NumFmt: numFmtEntero}},
+		{&e.estilos.totalDecimal, excelize.Style{Font: negrita, NumFmt: numFmtDecimal}},
+		{&e.estilos.totalMoneda, excelize.Style{Font: negrita, CustomNumFmt: &moneda}},
+	}
+	for _, s := range estilos {
+		id, err := e.f.NewStyle(&s.style)
+		if err != nil {
+			return err
+		}
+		*s.id = id
+	}
+	return nil
+}
+
+// NuevaHoja cierra la hoja actual y comienza otra con su fila de
+// encabezados.
+func (e *xlsxExport) NuevaHoja(nombre string, columnas []columna) error {
+	if err := e.cerrarHoja(); err != nil {
+		return err
+	}
+	nombre = nombreHoja(nombre)
+	if e.hojas == 0 {
+		if err := e.f.SetSheetName(e.f.GetSheetName(0), nombre); err != nil {
+			return err
+		}
+	} else if _, err := e.f.NewSheet(nombre); err != nil {
+		return err
+	}
+	e.hojas++
+
+	sw, err := e.f.NewStreamWriter(nombre)
+	if err != nil {
+		return err
+	}
+	if err := sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+	encabezado := make([]any, len(columnas))
+	for i, c := range columnas {
+		if err := sw.SetColWidth(i+1, i+1, anchoColumna(c)); err != nil {
+			return err
+		}
+		encabezado[i] = excelize.Cell{StyleID: e.estilos.encabezado, Value: c.Titulo}
+	}
+	if err := sw.SetRow("A1", encabezado); err != nil {
+		return err
+	}
+	e.hoja = &hojaXLSX{
+		nombre:   nombre,
+		sw:       sw,
+		columnas: columnas,
+		totales:  make([]float64, len(columnas)),
+	}
+	return nil
+}
+
+func (e *xlsxExport) Write(_ any, fila []any) error {
+	h := e.hoja
+	h.filas++
+	celdas := make([]any, len(fila))
+	for i, v := range fila {
+		var col columna
+		if i < len(h.columnas) {
+			col = h.columnas[i]
+		}
+		switch v := v.(type) {
+		case string:
+			if v != "" {
+				celdas[i] = v
+			}
+		case int:
+			celdas[i] = e.celdaNumero(float64(v), e.estiloNumerico(col.Tipo, colEntero, false), "")
+			e.sumar(i, float64(v))
+		case float64:
+			celdas[i] = e.celdaNumero(v, e.estiloNumerico(col.Tipo, colDecimal, false), "")
+			e.sumar(i, v)
+		case time.Time:
+			if !v.IsZero() {
+				celdas[i] = excelize.Cell{StyleID: e.estilos.fecha, Value: fechaSerial(v)}
+			}
+		default:
+			celdas[i] = fmt.Sprint(v)
+		}
+	}
+	return h.sw.SetRow(fmt.Sprintf("A%d", h.filas+1), celdas)
+}
+
+// sumar acumula el valor en el total de la columna si la columna lo lleva.
+func (e *xlsxExport) sumar(i int, v float64) {
+	if i < len(e.hoja.columnas) && e.hoja.columnas[i].Total {
+		e.hoja.totales[i] += v
+	}
+}
+
+// celdaNumero devuelve la celda de un número, o nil si no es finito; con
+// formula, valor queda como el resultado calculado.
+func (e *xlsxExport) celdaNumero(valor float64, estilo int, formula string) any {
+	if math.IsNaN(valor) || math.IsInf(valor, 0) {
+		return nil
+	}
+	return excelize.Cell{StyleID: estilo, Value: valor, Formula: formula}
+}
+
+// cerrarHoja escribe la fila de totales y el filtro de la hoja actual.
+func (e *xlsxExport) cerrarHoja() error {
+	h := e.hoja
+	if h == nil {
+		return nil
+	}
+	e.hoja = nil
+	ultima := h.filas + 1
+	conTotales := false
+	for _, c := range h.columnas {
+		conTotales = conTotales || c.Total
+	}
+	if conTotales && h.filas > 0 {
+		r := ultima + 1
+		celdas := make([]any, len(h.columnas))
+		for i, c := range h.columnas {
+			switch {
+			case c.Total:
+				letra, _ := excelize.ColumnNumberToName(i + 1)
+				formula := fmt.Sprintf("SUBTOTAL(109,%s2:%s%d)", letra, letra, ultima)
+				celdas[i] = e.celdaNumero(h.totales[i], e.estiloNumerico(c.Tipo, colDecimal, true), formula)
+			case i == 0:
+				celdas[i] = excelize.Cell{StyleID: e.estilos.totalTexto, Value: "Total"}
+			}
+		}
+		if err := h.sw.SetRow(fmt.Sprintf("A%d", r), celdas); err != nil {
+			return err
+		}
+	}
+	// El filtro se fija en la hoja antes de Flush, que lo escribe después de
+	// las filas.
+	if len(h.columnas) > 0 {
+		letra, _ := excelize.ColumnNumberToName(len(h.columnas))
+		if err := e.f.AutoFilter(h.nombre, fmt.Sprintf("A1:%s%d", letra, ultima), nil); err != nil {
+			return err
+		}
+	}
+	return h.sw.Flush()
+}
+
+// Close cierra la última hoja, envía el libro y borra los temporales.
+func (e *xlsxExport) Close() error {
+	defer e.f.Close()
+	if err := e.cerrarHoja(); err != nil {
+		return err
+	}
+	e.f.SetActiveSheet(0)
+	if _, err := e.f.WriteTo(e.w); err != nil {
+		return err
+	}
+	if f := flusherDe(e.w); f != nil {
+		f.Flush()
+	}
+	return nil
+}
+
+// Descartar borra los temporales del libro sin enviarlo.
+func (e *xlsxExport) Descartar() {
+	e.f.Close()
+}
+
+// estiloNumerico elige el estilo de un número según el tipo de su columna;
+// porDefecto se usa cuando la columna no es numérica.
+func (e *xlsxExport) estiloNumerico(tipo, porDefecto tipoColumna, total bool) int {
+	if tipo != colEntero && tipo != colDecimal && tipo != colMoneda {
+		tipo = porDefecto
+	}
+	switch {
+	case tipo == colEntero && total:
+		return e.estilos.totalEntero
+	case tipo == colEntero:
+		return e.estilos.entero
+	case tipo == colMoneda && total:
+		return e.estilos.totalMoneda
+	case tipo == colMoneda:
+		return e.estilos.moneda
+	case total:
+		return e.estilos.totalDecimal
+	default:
+		return e.estilos.decimal
+	}
+}
+
+// anchoColumna devuelve el ancho de la columna, suficiente para su título.
+func anchoColumna(c columna) float64 {
+	ancho := c.Ancho
+	if ancho == 0 {
+		ancho = anchoPorTipo[c.Tipo]
+	}
+	if minimo := float64(utf8.RuneCountInString(c.Titulo) + 2); ancho < minimo {
+		ancho = minimo
+	}
+	return ancho
+}
+
+// fechaSerial convierte t a fecha serial de Excel, conservando la fecha y
+// hora locales de t.
+func fechaSerial(t time.Time) float64 {
+	local := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	return float64(local.Sub(origenFechasExcel)) / float64(24*time.Hour)
+}
+
+// nombreHoja quita los caracteres que Excel no admite en el nombre de una
+// hoja y lo recorta a 31 caracteres.
+func nombreHoja(nombre string) string {
+	nombre = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, nombre)
+	if r := []rune(nombre); len(r) > 31 {
+		nombre = string(r[:31])
+	}
+	return nombre
+}
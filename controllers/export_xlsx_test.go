@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// escribirLibro arma un libro con las hojas y filas indicadas y devuelve los
+// bytes enviados.
+func escribirLibro(t *testing.T, hojas []string, columnas []columna, filas [][]any) []byte {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	e, err := newXLSXExport(rec, hojas[0], columnas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, nombre := range hojas {
+		if i > 0 {
+			if err := e.NuevaHoja(nombre, columnas); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, f := range filas {
+			if err := e.Write(nil, f); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Body.Bytes()
+}
+
+// abrirLibro lee el libro con excelize.
+func abrirLibro(t *testing.T, datos []byte) *excelize.File {
+	t.Helper()
+	f, err := excelize.OpenReader(bytes.NewReader(datos))
+	if err != nil {
+		t.Fatalf("el libro no es válido: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// parteLibro devuelve el XML de una parte del libro tal como se envió.
+func parteLibro(t *testing.T, datos []byte, nombre string) string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(datos), int64(len(datos)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := zr.Open(nombre)
+	if err != nil {
+		t.Fatalf("falta la parte %s: %v", nombre, err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+var columnasPrueba = []columna{
+	{"Nombre", colTexto, 30, false, false},
+	{"Cantidad", colEntero, 0, true, false},
+	{"Costo", colMoneda, 0, true, false},
+	{"Saldo", colDecimal, 0, false, false},
+	{"Ingreso", colFecha, 0, false, false},
+}
+
+func TestXLSXCeldas(t *testing.T) {
+	datos := escribirLibro(t, []string{"Saldos"}, columnasPrueba, [][]any{
+		{`Vino & "tinto" <reserva>`, 3, 1500.5, 2.25, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"AÑO ñandú", 2, 10.0, -1.5, time.Time{}},
+	})
+	f := abrirLibro(t, datos)
+	filas, err := f.GetRows("Saldos", excelize.Options{RawCellValue: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filas) != 4 {
+		t.Fatalf("filas = %d, se esperaban encabezado, 2 de datos y totales", len(filas))
+	}
+
+	tests := []struct {
+		celda   string
+		valor   string
+		formato string // código del formato propio o id del built-in
+		negrita bool
+		formula string
+	}{
+		{"A1", "Nombre", "", true, ""},
+		{"A2", `Vino & "tinto" <reserva>`, "", false, ""},
+		{"A3", "AÑO ñandú", "", false, ""},
+		{"B2", "3", "1", false, ""},
+		{"C2", "1500.5", formatoMoneda, false, ""},
+		{"D3", "-1.5", "4", false, ""},
+		{"E2", "45658", formatoFecha, false, ""},
+		{"E3", "", "", false, ""},
+		{"A4", "Total", "", true, ""},
+		{"B4", "5", "1", true, "SUBTOTAL(109,B2:B3)"},
+		{"C4", "1510.5", formatoMoneda, true, "SUBTOTAL(109,C2:C3)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.celda, func(t *testing.T) {
+			valor, err := f.GetCellValue("Saldos", tt.celda, excelize.Options{RawCellValue: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			formula, _ := f.GetCellFormula("Saldos", tt.celda)
+			if valor != tt.valor || formula != tt.formula {
+				t.Errorf("valor = %q, fórmula = %q; se esperaba %q, %q", valor, formula, tt.valor, tt.formula)
+			}
+			id, err := f.GetCellStyle("Saldos", tt.celda)
+			if err != nil {
+				t.Fatal(err)
+			}
+			estilo, err := f.GetStyle(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			formato := ""
+			if estilo.CustomNumFmt != nil {
+				formato = *estilo.CustomNumFmt
+			} else if estilo.NumFmt != 0 {
+				formato = strconv.Itoa(estilo.NumFmt)
+			}
+			negrita := estilo.Font != nil && estilo.Font.Bold
+			if formato != tt.formato || negrita != tt.negrita {
+				t.Errorf("formato = %q, negrita = %v; se esperaba %q, %v", formato, negrita, tt.formato, tt.negrita)
+			}
+		})
+	}
+
+	if fecha, _ := f.GetCellValue("Saldos", "E2"); fecha != "01-01-2025" {
+		t.Errorf("fecha con formato = %q, se esperaba 01-01-2025", fecha)
+	}
+	panes, err := f.GetPanes("Saldos")
+	if err != nil || !panes.Freeze || panes.YSplit != 1 || panes.TopLeftCell != "A2" {
+		t.Errorf("paneles = %+v, %v; se esperaba el encabezado fijo", panes, err)
+	}
+	if ancho, _ := f.GetColWidth("Saldos", "A"); ancho != 30 {
+		t.Errorf("ancho de A = %v, se esperaba 30", ancho)
+	}
+	if ancho, _ := f.GetColWidth("Saldos", "B"); ancho != anchoColumna(columnasPrueba[1]) {
+		t.Errorf("ancho de B = %v, se esperaba %v", ancho, anchoColumna(columnasPrueba[1]))
+	}
+	// El filtro cubre el encabezado y los datos, sin la fila de totales
+	if hoja := parteLibro(t, datos, "xl/worksheets/sheet1.xml"); !strings.Contains(hoja, `<autoFilter ref="$A$1:$E$3"`) {
+		t.Errorf("la hoja no tiene el filtro A1:E3")
+	}
+	// Los textos van en línea, sin tabla de strings compartidos
+	if hoja := parteLibro(t, datos, "xl/worksheets/sheet1.xml"); !strings.Contains(hoja, `t="inlineStr"`) {
+		t.Error("los textos no van en línea")
+	}
+}
+
+func TestXLSXHojas(t *testing.T) {
+	datos := escribirLibro(t, []string{"Saldos", "Sin: correspondencia/año"}, columnasPrueba, nil)
+	f := abrirLibro(t, datos)
+	if hojas := f.GetSheetList(); len(hojas) != 2 || hojas[0] != "Saldos" || hojas[1] != "Sin- correspondencia-año" {
+		t.Errorf("hojas = %q", hojas)
+	}
+	if f.GetActiveSheetIndex() != 0 {
+		t.Errorf("hoja activa = %d, se esperaba la primera", f.GetActiveSheetIndex())
+	}
+	for _, hoja := range f.GetSheetList() {
+		// Sin filas no hay fila de totales
+		filas, err := f.GetRows(hoja)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(filas) != 1 || len(filas[0]) != len(columnasPrueba) {
+			t.Errorf("%s: filas = %q, se esperaba solo el encabezado", hoja, filas)
+		}
+	}
+	filtros := 0
+	for _, n := range f.GetDefinedName() {
+		if n.Name == "_xlnm._FilterDatabase" {
+			filtros++
+		}
+	}
+	if filtros != 2 {
+		t.Errorf("rangos de filtro = %d, se esperaba uno por hoja", filtros)
+	}
+}
+
+func TestXLSXDescartar(t *testing.T) {
+	rec := httptest.NewRecorder()
+	e, err := newXLSXExport(rec, "Saldos", columnasPrueba)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Write(nil, []any{"a", 1, 1.0, 1.0, time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	errorExportacion(rec, e, io.ErrUnexpectedEOF)
+	if rec.Code != 500 || bytes.HasPrefix(rec.Body.Bytes(), []byte("PK")) {
+		t.Errorf("status = %d; el libro descartado no debería enviarse", rec.Code)
+	}
+}
+
+func TestFechaSerial(t *testing.T) {
+	tests := []struct {
+		fecha time.Time
+		want  float64
+	}{
+		{time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC), 1},
+		{time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC), 61},
+		{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 45658},
+		{time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), 45658.5},
+		// Conserva la hora local, sin pasar a UTC
+		{time.Date(2025, 1, 1, 0, 0, 0, 0, time.FixedZone("CLT", -3*3600)), 45658},
+	}
+	for _, tt := range tests {
+		if got := fechaSerial(tt.fecha); got != tt.want {
+			t.Errorf("fechaSerial(%v) = %v, se esperaba %v", tt.fecha, got, tt.want)
+		}
+	}
+}
+
+func TestNombreHoja(t *testing.T) {
+	tests := []struct {
+		entrada string
+		want    string
+	}{
+		{"Saldos", "Saldos"},
+		{`a[b]c:d*e?f/g\h`, "a-b-c-d-e-f-g-h"},
+		{strings.Repeat("ñ", 40), strings.Repeat("ñ", 31)},
+	}
+	for _, tt := range tests {
+		if got := nombreHoja(tt.entrada); got != tt.want {
+			t.Errorf("nombreHoja(%q) = %q, se esperaba %q", tt.entrada, got, tt.want)
+		}
+	}
+}
@@ -1,8 +1,55 @@
 package controllers
 
-import "net/http"
+import (
+	"log"
+	"net/http"
 
-func IndexHandler(w http.ResponseWriter, r *http.Request) {
-	// Puedes modificar este contenido según lo que necesites mostrar o redirigir.
-	w.Write([]byte("Bienvenido a la API de Saldos"))
+	"go_api/views"
+)
+
+// IndexHandler muestra el panel de inicio con las cifras resumidas de ambas
+// bases. Cualquier otra ruta no registrada responde 404.
+func (a *App) IndexHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var data views.IndexViewData
+
+	resumen, err := a.Saldos.Resumen(r.Context())
+	if err != nil {
+		log.Println("Error obteniendo resumen de saldos:", err)
+		data.Errores = append(data.Errores, "No se pudo obtener el resumen de saldos desde MySQL")
+	} else {
+		data.TotalSaldos = resumen.TotalRegistros
+		data.UltimoIngreso = resumen.UltimoIngreso
+		data.Valorizacion = resumen.Valorizacion
+	}
+
+	// Las zetas distintas recorren ambas tablas: se toman de la caché
+	zetasMySQL, errMySQL := a.zetasMySQL(r.Context())
+	zetasSQLServer, errSQLServer := a.zetasSQLServer(r.Context(), a.Config.SucursalPorDefecto)
+	switch {
+	case errMySQL != nil:
+		log.Println("Error obteniendo zetas de MySQL:", errMySQL)
+		data.Errores = append(data.Errores, "No se pudieron obtener las zetas desde MySQL")
+	case errSQLServer != nil:
+		log.Println("Error obteniendo zetas de SQL Server:", errSQLServer)
+		data.Errores = append(data.Errores, "No se pudieron obtener las zetas desde SQL Server")
+	default:
+		enSQLServer := make(map[string]bool, len(zetasSQLServer))
+		for _, z := range zetasSQLServer {
+			enSQLServer[z] = true
+		}
+		for _, z := range zetasMySQL {
+			if enSQLServer[z] {
+				data.ZetasCoincidentes++
+			} else {
+				data.ZetasSinCorrespondencia++
+			}
+		}
+	}
+
+	a.Views.RenderIndex(w, data)
 }
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/config"
+	"go_api/models"
+	"go_api/repository"
+)
+
+// stocksSinZetas es un repositorio de stocks cuya consulta de zetas falla.
+type stocksSinZetas struct {
+	repository.StockRepository
+}
+
+func (stocksSinZetas) Zetas(context.Context, []int) ([]string, error) {
+	return nil, errors.New("SQL Server no responde")
+}
+
+func TestIndexResumen(t *testing.T) {
+	saldos := []models.Saldo{
+		{Zeta: "Z1", CodigoProducto: "P1", SaldoAnterior: 2, CostoReal: 100, FechaIngreso: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Zeta: "Z2", CodigoProducto: "P2", SaldoAnterior: 1, CostoReal: 50.5, FechaIngreso: time.Date(2025, 7, 9, 0, 0, 0, 0, time.UTC)},
+		{Zeta: "Z3", CodigoProducto: "P3"},
+	}
+	stocks := []models.StockData{
+		{IDSucursal: 1, Zeta: "Z1"},
+		{IDSucursal: 1, Zeta: "Z9"},
+		{IDSucursal: 2, Zeta: "Z2"}, // otra sucursal: no cuenta
+	}
+	cfg := config.Config{SucursalPorDefecto: 1}
+	saldosRepo := repository.NewMemorySaldoRepository(saldos)
+	stocksRepo := repository.NewMemoryStockRepository(stocks)
+
+	app, err := NewAppWithRepositories(cfg, saldosRepo, stocksRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	app.IndexHandler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`text-gray-800">3</p>`,      // filas de saldos
+		`text-green-600">1</p>`,     // Z1 coincide
+		`text-red-600">2</p>`,       // Z2 y Z3 sin stock en la sucursal 1
+		"2025-07-09",                // último ingreso
+		"$ 250,50",                  // 2×100 + 1×50,5
+		`href="/reportes/margenes"`, // enlaces a los módulos
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("falta %q en la página de inicio", want)
+		}
+	}
+
+	// Si falla un origen se muestran las demás cifras con el aviso
+	app, err = NewAppWithRepositories(cfg, saldosRepo, stocksSinZetas{stocksRepo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	app.IndexHandler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "No se pudieron obtener las zetas desde SQL Server") {
+		t.Errorf("status = %d; se esperaba la página con el aviso de SQL Server", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	app.IndexHandler(rec, httptest.NewRequest(http.MethodGet, "/no-existe", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("ruta desconocida: status = %d, se esperaba 404", rec.Code)
+	}
+}
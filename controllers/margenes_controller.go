@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"go_api/models"
+	"go_api/repository"
+	"go_api/views"
+)
+
+// loadMargenes arma el reporte de ofertas bajo costo del año y las sucursales
+// pedidas, ordenado de mayor a menor pérdida. Responde con el error y
+// devuelve false si los parámetros son inválidos o falla alguna de las bases.
+func (a *App) loadMargenes(w http.ResponseWriter, r *http.Request) (models.ReporteMargenes, bool) {
+	query := r.URL.Query()
+	reporte := models.ReporteMargenes{}
+
+	var err error
+	if reporte.Anio, err = parseYear(query); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return reporte, false
+	}
+	reporte.Sucursales, err = parseSucursales(query, a.Config.SucursalPorDefecto)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return reporte, false
+	}
+
+	expr, err := listParams{Search: query.Get("search")}.filtroCombinado()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return reporte, false
+	}
+
+	ds, ok := a.loadCombined(w, r, reporte.Anio, reporte.Sucursales)
+	if !ok {
+		return reporte, false
+	}
+
+	reporte.DatosAl = ds.DatosAl
+	bajoCosto := filterAndSortResults(ds.Resultados, expr, filtroMargen{BajoCosto: true}, repository.Orden{{Campo: "MargenOfertaReal"}})
+	reporte.Productos = make([]models.OfertaBajoCosto, 0, len(bajoCosto))
+	for _, c := range bajoCosto {
+		o := models.NewOfertaBajoCosto(c)
+		reporte.Productos = append(reporte.Productos, o)
+		reporte.PerdidaPotencial += o.PerdidaOferta
+	}
+	return reporte, true
+}
+
+// MargenesApiHandler maneja /api/reportes/margenes y devuelve en JSON los
+// productos con precio de oferta bajo el costo real.
+func (a *App) MargenesApiHandler(w http.ResponseWriter, r *http.Request) {
+	reporte, ok := a.loadMargenes(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, reporte)
+}
+
+// MargenesViewHandler maneja /reportes/margenes.
+func (a *App) MargenesViewHandler(w http.ResponseWriter, r *http.Request) {
+	reporte, ok := a.loadMargenes(w, r)
+	if !ok {
+		return
+	}
+
+	// Sucursales disponibles para el selector; si falla se muestran solo las pedidas
+	disponibles, err := a.Stocks.Sucursales(r.Context())
+	if err != nil {
+		log.Println("Error obteniendo sucursales:", err)
+	}
+
+	a.Views.RenderMargenes(w, views.MargenesViewData{
+		Reporte: reporte,
+		Search:  r.URL.Query().Get("search"),
+		SucursalesFiltro: views.SucursalesFiltro{
+			Sucursales:  reporte.Sucursales,
+			Disponibles: disponibles,
+		},
+	})
+}
+
+// ExportMargenesHandler exporta el reporte de ofertas bajo costo en Excel
+// (por defecto), CSV (format=csv), NDJSON (format=ndjson) o PDF (format=pdf).
+func (a *App) ExportMargenesHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseExportOptions(r.URL.Query(), formatoXLSX, formatoCSV, formatoNDJSON, formatoPDF)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reporte, ok := a.loadMargenes(w, r)
+	if !ok {
+		return
+	}
+
+	filename := "ofertas_bajo_costo_" + reporte.Anio
+	for _, id := range reporte.Sucursales {
+		filename += "_" + strconv.Itoa(id)
+	}
+	ew, err := newExportWriter(w, opts, filename, "Ofertas Bajo Costo", columnasMargenes)
+	if err != nil {
+		http.Error(w, "Error al crear la exportación", http.StatusInternalServerError)
+		log.Println("Error al crear la exportación:", err)
+		return
+	}
+	for _, c := range reporte.Productos {
+		fila := []any{
+			c.IDSucursal, c.CodigoProducto, c.Zeta, c.AnioProduccion, c.NombreProducto,
+			c.PrecioVenta, c.PrecioOferta, c.CostoCIF, c.CostoReal,
+			c.MargenOfertaReal, c.MargenPctOfertaReal, c.MarkupOfertaReal,
+			c.SaldoAnterior, c.PerdidaOferta,
+		}
+		if err := ew.Write(c, fila); err != nil {
+			errorExportacion(w, ew, err)
+			return
+		}
+	}
+	if err := ew.Close(); err != nil {
+		log.Println("Error al escribir la exportación:", err)
+	}
+}
+
+// columnasMargenes son las columnas de la exportación de ofertas bajo costo.
+var columnasMargenes = []columna{
+	{"Sucursal", colEntero, 0, false, false},
+	{"Código", colTexto, 0, false, false},
+	{"Zeta", colTexto, 0, false, false},
+	{"Año Producción", colEntero, 0, false, false},
+	{"Nombre Producto", colTexto, 40, false, false},
+	{"Precio Venta", colMoneda, 0, false, false},
+	{"Precio Oferta", colMoneda, 0, false, false},
+	{"Costo CIF", colMoneda, 0, false, false},
+	{"Costo Real", colMoneda, 0, false, false},
+	{"Margen Oferta Real", colMoneda, 0, false, false},
+	{"Margen % Oferta Real", colDecimal, 0, false, false},
+	{"Markup % Oferta Real", colDecimal, 0, false, false},
+	{"Saldo", colDecimal, 0, true, false},
+	{"Pérdida Potencial", colMoneda, 18, true, false},
+}
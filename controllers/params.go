@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_api/models"
+	"go_api/repository"
+)
+
+// anioPorDefecto es el año de producción usado cuando no se indica "year".
+const anioPorDefecto = "2025"
+
+// listParams reúne los parámetros de búsqueda, orden y paginación que
+// comparten las vistas HTML y la API.
+type listParams struct {
+	Page      int
+	PageSize  int
+	Search    string
+	SortField string
+	SortDir   string
+}
+
+// maxPageSize es el mayor pageSize admitido; los valores mayores se recortan.
+const maxPageSize = 500
+
+// maxPagina es el mayor número de página admitido. Junto con maxPageSize
+// mantiene el desplazamiento lejos del desborde.
+const maxPagina = 1_000_000
+
+// parseListParams lee page, pageSize, search, sort y dir de la URL aplicando
+// los valores por defecto de las vistas. pageSize se limita a maxPageSize y
+// una página mayor que maxPagina es un error.
+func parseListParams(query url.Values) (listParams, error) {
+	p := listParams{
+		Search:    query.Get("search"),
+		SortField: query.Get("sort"),
+		SortDir:   query.Get("dir"),
+	}
+
+	var err error
+	p.Page, err = strconv.Atoi(query.Get("page"))
+	if errors.Is(err, strconv.ErrRange) || p.Page > maxPagina {
+		return p, fmt.Errorf("page fuera de rango: debe estar entre 1 y %d", maxPagina)
+	}
+	if p.Page < 1 {
+		p.Page = 1
+	}
+
+	p.PageSize, _ = strconv.Atoi(query.Get("pageSize"))
+	if p.PageSize < 1 {
+		p.PageSize = 25
+	}
+	if p.PageSize > maxPageSize {
+		p.PageSize = maxPageSize
+	}
+
+	if p.SortDir != "desc" {
+		p.SortDir = "asc"
+	}
+	return p, nil
+}
+
+// Offset devuelve el desplazamiento de la página actual.
+func (p listParams) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// TotalPages calcula el número de páginas para total registros.
+func (p listParams) TotalPages(total int) int {
+	return (total + p.PageSize - 1) / p.PageSize
+}
+
+// Bounds devuelve los índices [start, end) de la página actual dentro de un
+// listado de total elementos.
+func (p listParams) Bounds(total int) (int, int) {
+	start := p.Offset()
+	if start > total {
+		start = total
+	}
+	end := start + p.PageSize
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// ordenSaldos valida sort y dir contra los campos ordenables de saldos.
+func (p listParams) ordenSaldos() (repository.Orden, error) {
+	return repository.ParseOrden(p.SortField, p.SortDir, repository.EsCampoSaldo)
+}
+
+// ordenCombinado valida sort y dir contra los campos ordenables de los datos
+// combinados.
+func (p listParams) ordenCombinado() (repository.Orden, error) {
+	return repository.ParseOrden(p.SortField, p.SortDir, esCampoCombinado)
+}
+
+// filtroSaldos interpreta search con el lenguaje de filtros sobre los campos
+// de saldos: las palabras sueltas siguen buscando en nombre, código y zeta.
+func (p listParams) filtroSaldos() (repository.SaldoFilter, error) {
+	expr, err := repository.ParseFiltro(p.Search, repository.CamposFiltroSaldo)
+	return repository.SaldoFilter{Palabras: expr.Palabras, Condiciones: expr.Condiciones}, err
+}
+
+// filtroCombinado interpreta search con el lenguaje de filtros sobre los
+// campos de los datos combinados.
+func (p listParams) filtroCombinado() (repository.ExpresionFiltro, error) {
+	return repository.ParseFiltro(p.Search, camposFiltroCombinado)
+}
+
+// Rango de años de producción aceptados en "year". El año forma parte de la
+// clave de la caché de saldos, por lo que no se admiten valores arbitrarios.
+const (
+	anioMinimo = 1900
+	anioMaximo = 2100
+)
+
+// parseYear devuelve el año de producción pedido o el año por defecto.
+func parseYear(query url.Values) (string, error) {
+	year := query.Get("year")
+	if year == "" {
+		return anioPorDefecto, nil
+	}
+	anio, err := strconv.Atoi(year)
+	if err != nil || anio < anioMinimo || anio > anioMaximo {
+		return "", fmt.Errorf("year inválido: %q (entre %d y %d)", year, anioMinimo, anioMaximo)
+	}
+	return strconv.Itoa(anio), nil
+}
+
+// parseSaldoFilter lee los filtros estructurados de saldos: search (con el
+// lenguaje de filtros), codigo, zeta, anio, fechaDesde/fechaHasta
+// (AAAA-MM-DD) y saldoMin/saldoMax.
+func parseSaldoFilter(query url.Values) (repository.SaldoFilter, error) {
+	filter, err := listParams{Search: query.Get("search")}.filtroSaldos()
+	if err != nil {
+		return filter, err
+	}
+	filter.Codigo = query.Get("codigo")
+	filter.Zeta = query.Get("zeta")
+
+	if v := query.Get("anio"); v != "" {
+		anio, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, fmt.Errorf("anio inválido: %q", v)
+		}
+		filter.Anio = anio
+	}
+
+	if filter.FechaDesde, err = parseDateParam(query, "fechaDesde"); err != nil {
+		return filter, err
+	}
+	if filter.FechaHasta, err = parseDateParam(query, "fechaHasta"); err != nil {
+		return filter, err
+	}
+	if filter.SaldoMin, err = parseFloatParam(query, "saldoMin"); err != nil {
+		return filter, err
+	}
+	if filter.SaldoMax, err = parseFloatParam(query, "saldoMax"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// parseDateParam lee una fecha AAAA-MM-DD opcional.
+func parseDateParam(query url.Values, name string) (time.Time, error) {
+	v := query.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s inválida: %q (formato AAAA-MM-DD)", name, v)
+	}
+	return t, nil
+}
+
+// parseFloatParam lee un número opcional; devuelve nil si no se indicó.
+func parseFloatParam(query url.Values, name string) (*float64, error) {
+	v := query.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s inválido: %q", name, v)
+	}
+	return &f, nil
+}
+
+// maxSucursales limita las sucursales de una misma petición.
+const maxSucursales = 50
+
+// parseSucursales lee las sucursales pedidas en "sucursal", que puede
+// repetirse o llevar varios valores separados por comas, y las devuelve sin
+// repetir y ordenadas. Sin parámetro devuelve la sucursal por defecto.
+func parseSucursales(query url.Values, porDefecto int) ([]int, error) {
+	var sucursales []int
+	vistas := make(map[int]bool)
+	for _, v := range query["sucursal"] {
+		for _, parte := range strings.Split(v, ",") {
+			parte = strings.TrimSpace(parte)
+			if parte == "" {
+				continue
+			}
+			id, err := strconv.Atoi(parte)
+			if err != nil || id <= 0 {
+				return nil, fmt.Errorf("sucursal inválida: %q", parte)
+			}
+			if !vistas[id] {
+				vistas[id] = true
+				sucursales = append(sucursales, id)
+			}
+		}
+	}
+	if len(sucursales) == 0 {
+		sucursales = []int{porDefecto}
+	}
+	if len(sucursales) > maxSucursales {
+		return nil, fmt.Errorf("se pueden pedir hasta %d sucursales", maxSucursales)
+	}
+	sort.Ints(sucursales)
+	return sucursales, nil
+}
+
+// limitesAntiguedad son los límites superiores por defecto de los tramos del
+// reporte de antigüedad: 0–30, 31–90, 91–180, 181–365 y >365 días.
+var limitesAntiguedad = []int{30, 90, 180, 365}
+
+// parseTramos lee los límites superiores de los tramos de antigüedad en
+// "tramos", separados por comas y en orden creciente (por ejemplo
+// tramos=30,90,180,365). Sin parámetro devuelve los límites por defecto.
+func parseTramos(query url.Values) ([]int, error) {
+	v := query.Get("tramos")
+	if v == "" {
+		return limitesAntiguedad, nil
+	}
+	var limites []int
+	for _, parte := range strings.Split(v, ",") {
+		parte = strings.TrimSpace(parte)
+		limite, err := strconv.Atoi(parte)
+		if err != nil || limite < 0 {
+			return nil, fmt.Errorf("tramo inválido: %q", parte)
+		}
+		if len(limites) > 0 && limite <= limites[len(limites)-1] {
+			return nil, fmt.Errorf("los tramos deben ir en orden creciente: %q", v)
+		}
+		limites = append(limites, limite)
+	}
+	return limites, nil
+}
+
+// parseFiltroMargen lee el filtro por margen de los datos combinados: margen
+// (campo, por defecto MargenPctVentaReal), margenMin/margenMax y
+// bajoCosto=true para quedarse con las ofertas bajo el costo real.
+func parseFiltroMargen(query url.Values) (filtroMargen, error) {
+	filtro := filtroMargen{
+		Campo:     query.Get("margen"),
+		BajoCosto: query.Get("bajoCosto") == "true",
+	}
+	if filtro.Campo == "" {
+		filtro.Campo = "MargenPctVentaReal"
+	}
+	if _, ok := camposNumericosCombinados[filtro.Campo]; !ok {
+		return filtro, fmt.Errorf("margen inválido: %q", filtro.Campo)
+	}
+
+	var err error
+	if filtro.Min, err = parseFloatParam(query, "margenMin"); err != nil {
+		return filtro, err
+	}
+	if filtro.Max, err = parseFloatParam(query, "margenMax"); err != nil {
+		return filtro, err
+	}
+	return filtro, nil
+}
+
+// parseTolerancias lee las tolerancias de la conciliación: tolCosto
+// (absoluta, por defecto 0,01), tolCostoPct (por defecto 0) y tolAnio (por
+// defecto 0).
+func parseTolerancias(query url.Values) (models.ToleranciasConciliacion, error) {
+	tol := models.ToleranciasConciliacion{Costo: 0.01}
+	for _, p := range []struct {
+		name  string
+		value *float64
+	}{{"tolCosto", &tol.Costo}, {"tolCostoPct", &tol.CostoPct}} {
+		v, err := parseFloatParam(query, p.name)
+		if err != nil {
+			return tol, err
+		}
+		if v != nil {
+			if *v < 0 {
+				return tol, fmt.Errorf("%s no puede ser negativa", p.name)
+			}
+			*p.value = *v
+		}
+	}
+	if v := query.Get("tolAnio"); v != "" {
+		anio, err := strconv.Atoi(v)
+		if err != nil || anio < 0 {
+			return tol, fmt.Errorf("tolAnio inválida: %q", v)
+		}
+		tol.Anio = anio
+	}
+	return tol, nil
+}
+
+// parseTiposDiscrepancia lee los tipos de discrepancia pedidos en "tipo",
+// repetible o separado por comas. Sin parámetro devuelve nil (todos).
+func parseTiposDiscrepancia(query url.Values) (map[string]bool, error) {
+	var tipos map[string]bool
+	for _, v := range query["tipo"] {
+		for _, parte := range strings.Split(v, ",") {
+			parte = strings.TrimSpace(parte)
+			if parte == "" {
+				continue
+			}
+			valido := false
+			for _, t := range models.TiposDiscrepancia {
+				valido = valido || t == parte
+			}
+			if !valido {
+				return nil, fmt.Errorf("tipo de discrepancia inválido: %q", parte)
+			}
+			if tipos == nil {
+				tipos = make(map[string]bool)
+			}
+			tipos[parte] = true
+		}
+	}
+	return tipos, nil
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"go_api/config"
+	"go_api/repository"
+)
+
+func TestParseListParamsPaginacion(t *testing.T) {
+	tests := []struct {
+		page, pageSize string
+		wantPage       int
+		wantPageSize   int
+		wantErr        bool
+	}{
+		{"", "", 1, 25, false},
+		{"3", "10", 3, 10, false},
+		{"0", "-4", 1, 25, false},
+		{"abc", "xyz", 1, 25, false},
+		{"2", "100000", 2, maxPageSize, false},
+		{"1", fmt.Sprint(math.MaxInt64), 1, maxPageSize, false},
+		{fmt.Sprint(maxPagina), "", maxPagina, 25, false},
+		{fmt.Sprint(maxPagina + 1), "", 0, 0, true},
+		{"99999999999999999999", "", 0, 0, true},
+	}
+	for _, tt := range tests {
+		p, err := parseListParams(url.Values{"page": {tt.page}, "pageSize": {tt.pageSize}})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("page=%q pageSize=%q: se esperaba un error", tt.page, tt.pageSize)
+			}
+			continue
+		}
+		if err != nil || p.Page != tt.wantPage || p.PageSize != tt.wantPageSize {
+			t.Errorf("page=%q pageSize=%q: = %d, %d, %v; se esperaba %d, %d",
+				tt.page, tt.pageSize, p.Page, p.PageSize, err, tt.wantPage, tt.wantPageSize)
+		}
+	}
+}
+
+func TestListParamsBounds(t *testing.T) {
+	tests := []struct {
+		page, pageSize, total int
+		start, end, paginas   int
+	}{
+		{1, 10, 25, 0, 10, 3},
+		{3, 10, 25, 20, 25, 3},
+		{4, 10, 25, 25, 25, 3},
+		{maxPagina, maxPageSize, 7, 7, 7, 1},
+		{1, 10, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		p := listParams{Page: tt.page, PageSize: tt.pageSize}
+		start, end := p.Bounds(tt.total)
+		if start != tt.start || end != tt.end || p.TotalPages(tt.total) != tt.paginas {
+			t.Errorf("%+v de %d: Bounds = [%d, %d), TotalPages = %d; se esperaba [%d, %d), %d",
+				p, tt.total, start, end, p.TotalPages(tt.total), tt.start, tt.end, tt.paginas)
+		}
+	}
+}
+
+// TestPaginasFueraDeRango pide páginas enormes a los listados que paginan en
+// memoria: deben responder vacío o 400, nunca entrar en pánico.
+func TestPaginasFueraDeRango(t *testing.T) {
+	app := newTestApp(t, config.Config{SucursalPorDefecto: repository.SucursalPorDefecto})
+	handlers := map[string]http.HandlerFunc{
+		"/api/combined":            app.CombinedDataHandler,
+		"/api/combined/missing":    app.CombinedMissingHandler,
+		"/api/combined/colisiones": app.CombinedCollisionsHandler,
+		"/combined":                app.CombinedViewHandler,
+		"/saldos":                  app.SaldosHandler,
+	}
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"page=" + fmt.Sprint(maxPagina) + "&pageSize=100000", http.StatusOK},
+		{"page=" + fmt.Sprint(maxPagina+1), http.StatusBadRequest},
+		{"page=9223372036854775807&pageSize=9223372036854775807", http.StatusBadRequest},
+	}
+	for ruta, h := range handlers {
+		for _, tt := range tests {
+			rec := httptest.NewRecorder()
+			h(rec, httptest.NewRequest(http.MethodGet, ruta+"?"+tt.query, nil))
+			if rec.Code != tt.want {
+				t.Errorf("%s?%s: status = %d, se esperaba %d", ruta, tt.query, rec.Code, tt.want)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	app.CombinedDataHandler(rec, httptest.NewRequest(http.MethodGet, "/api/combined?pageSize=100000", nil))
+	var resp paginatedResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.PageSize != maxPageSize {
+		t.Errorf("pageSize = %d, se esperaba el máximo %d", resp.PageSize, maxPageSize)
+	}
+}
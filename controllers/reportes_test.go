@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"go_api/config"
+	"go_api/repository"
+)
+
+var nombreCamelCase = regexp.MustCompile(`^[a-z][A-Za-z0-9]*$`)
+
+// clavesJSON recorre el valor decodificado y llama a fn con la ruta y el
+// nombre de cada clave de objeto. Los mapas de datos (como el resumen de la
+// conciliación, cuyas claves son tipos de discrepancia) se omiten.
+func clavesJSON(ruta string, v any, omitir map[string]bool, fn func(ruta, clave string)) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, hijo := range v {
+			fn(ruta, k)
+			if !omitir[k] {
+				clavesJSON(ruta+"."+k, hijo, omitir, fn)
+			}
+		}
+	case []any:
+		for _, hijo := range v {
+			clavesJSON(ruta+"[]", hijo, omitir, fn)
+		}
+	}
+}
+
+// TestReportesJSONCamelCase comprueba que los reportes usan camelCase en
+// todos sus campos, también en las filas que listan.
+func TestReportesJSONCamelCase(t *testing.T) {
+	app := newTestApp(t, config.Config{SucursalPorDefecto: repository.SucursalPorDefecto})
+	reportes := map[string]http.HandlerFunc{
+		"/api/reportes/margenes?year=2025":     app.MargenesApiHandler,
+		"/api/reportes/valorizacion":           app.ValorizacionApiHandler,
+		"/api/reportes/conciliacion?year=2025": app.ConciliacionApiHandler,
+		"/api/reportes/antiguedad?year=2025":   app.AntiguedadApiHandler,
+	}
+	for ruta, h := range reportes {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, ruta, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", ruta, rec.Code)
+		}
+		var v any
+		if err := json.NewDecoder(rec.Body).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		claves := 0
+		clavesJSON("", v, map[string]bool{"resumen": true}, func(r, clave string) {
+			claves++
+			if !nombreCamelCase.MatchString(clave) {
+				t.Errorf("%s: la clave %s%s no es camelCase", ruta, r, "."+clave)
+			}
+		})
+		if claves == 0 {
+			t.Errorf("%s: la respuesta no tiene campos", ruta)
+		}
+	}
+}
+
+func TestReporteMargenesFilas(t *testing.T) {
+	app := newTestApp(t, config.Config{SucursalPorDefecto: repository.SucursalPorDefecto})
+	rec := httptest.NewRecorder()
+	app.MargenesApiHandler(rec, httptest.NewRequest(http.MethodGet, "/api/reportes/margenes?year=2025", nil))
+	var reporte struct {
+		Productos []map[string]any `json:"productos"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&reporte); err != nil {
+		t.Fatal(err)
+	}
+	if len(reporte.Productos) == 0 {
+		t.Fatal("el reporte de demostración no tiene ofertas bajo costo")
+	}
+	for _, campo := range []string{"codigoProducto", "precioOferta", "costoReal", "margenOfertaReal", "perdidaOferta"} {
+		if _, ok := reporte.Productos[0][campo]; !ok {
+			t.Errorf("falta el campo %s en %v", campo, reporte.Productos[0])
+		}
+	}
+}
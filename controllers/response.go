@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"go_api/models"
+	"go_api/repository"
+)
+
+// paginatedResponse es el sobre JSON de los listados paginados de la API.
+type paginatedResponse struct {
+	Data       interface{} `json:"data"`
+	Total      int         `json:"total"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"pageSize"`
+	TotalPages int         `json:"totalPages"`
+	// DatosAl se informa en los listados armados con datos de la caché.
+	DatosAl *models.DatosAl `json:"datosAl,omitempty"`
+}
+
+// combinedPageResponse es el sobre JSON de /api/combined paginado por saldos,
+// el modo por defecto. La página se arma paginando los saldos en MySQL, y
+// cada saldo puede dar una fila por sucursal o ninguna: TotalSaldos y
+// TotalPages cuentan saldos, no filas combinadas, y los saldos sin
+// correspondencia y las colisiones son solo los de la página.
+type combinedPageResponse struct {
+	Data             interface{}            `json:"data"`
+	PaginadoPor      string                 `json:"paginadoPor"`
+	EnMemoria        bool                   `json:"enMemoria"`
+	TotalSaldos      int                    `json:"totalSaldos"`
+	Page             int                    `json:"page"`
+	PageSize         int                    `json:"pageSize"`
+	TotalPages       int                    `json:"totalPages"`
+	MissingPagina    []models.SaldoData     `json:"missingPagina"`
+	ColisionesPagina []models.ColisionCruce `json:"colisionesPagina"`
+	DatosAl          *models.DatosAl        `json:"datosAl,omitempty"`
+}
+
+// combinedFilasResponse es el sobre JSON de /api/combined paginado por filas:
+// se cargan y filtran en memoria todas las filas combinadas del año y Total
+// las cuenta. Motivo explica por qué no se paginó por saldos, salvo que se
+// pidiera paginar=filas.
+type combinedFilasResponse struct {
+	Data        interface{}     `json:"data"`
+	PaginadoPor string          `json:"paginadoPor"`
+	EnMemoria   bool            `json:"enMemoria"`
+	Motivo      string          `json:"motivo,omitempty"`
+	Total       int             `json:"total"`
+	Page        int             `json:"page"`
+	PageSize    int             `json:"pageSize"`
+	TotalPages  int             `json:"totalPages"`
+	DatosAl     *models.DatosAl `json:"datosAl,omitempty"`
+}
+
+// cursorResponse es el sobre JSON de los listados paginados por cursor. Total
+// se omite con count=none.
+type cursorResponse struct {
+	Data            interface{} `json:"data"`
+	PageSize        int         `json:"pageSize"`
+	NextCursor      string      `json:"nextCursor,omitempty"`
+	PrevCursor      string      `json:"prevCursor,omitempty"`
+	Total           *int        `json:"total,omitempty"`
+	TotalAproximado bool        `json:"totalAproximado,omitempty"`
+}
+
+// writeJSON serializa v como respuesta JSON.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Error codificando la respuesta", http.StatusInternalServerError)
+		log.Println("Error codificando la respuesta:", err)
+	}
+}
+
+// respondDataError responde al fallo de una consulta. Si el origen de datos no
+// respondió dentro del plazo devuelve 504 indicando cuál; si el cliente cerró
+// la conexión solo se registra; en otro caso responde 500 con msg.
+func respondDataError(w http.ResponseWriter, msg string, err error) {
+	backend := "La base de datos"
+	var qe *repository.QueryError
+	if errors.As(err, &qe) {
+		backend = qe.Backend
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("%s (tiempo agotado): %v", msg, err)
+		http.Error(w, fmt.Sprintf("%s no respondió a tiempo. Intente nuevamente o acote los filtros.", backend), http.StatusGatewayTimeout)
+	case errors.Is(err, context.Canceled):
+		log.Printf("%s (petición cancelada por el cliente): %v", msg, err)
+	default:
+		log.Printf("%s: %v", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/config"
+	"go_api/repository"
+)
+
+func TestRespondDataError(t *testing.T) {
+	tests := []struct {
+		nombre string
+		err    error
+		status int
+		cuerpo string
+	}{
+		{"plazo vencido en SQL Server",
+			&repository.QueryError{Backend: repository.BackendSQLServer, Query: "stocks", Err: context.DeadlineExceeded},
+			http.StatusGatewayTimeout, "SQL Server no respondió a tiempo"},
+		{"plazo vencido sin origen", fmt.Errorf("envuelto: %w", context.DeadlineExceeded),
+			http.StatusGatewayTimeout, "La base de datos no respondió a tiempo"},
+		// El cliente se fue: no se escribe respuesta
+		{"cancelado", context.Canceled, http.StatusOK, ""},
+		{"otro error", errors.New("sin conexión"), http.StatusInternalServerError, "Error obteniendo saldos"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nombre, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			respondDataError(rec, "Error obteniendo saldos", tt.err)
+			if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.cuerpo) || (tt.cuerpo == "" && rec.Body.Len() > 0) {
+				t.Errorf("status = %d, cuerpo = %q; se esperaba %d con %q", rec.Code, rec.Body.String(), tt.status, tt.cuerpo)
+			}
+		})
+	}
+}
+
+// TestHandlersPlazoVencido pide los listados con el plazo de la petición ya
+// vencido: deben responder 504.
+func TestHandlersPlazoVencido(t *testing.T) {
+	app := newTestApp(t, config.Config{SucursalPorDefecto: repository.SucursalPorDefecto})
+	handlers := map[string]http.HandlerFunc{
+		"/api/saldos":   app.ApiSaldosHandler,
+		"/api/combined": app.CombinedDataHandler,
+		"/saldos":       app.SaldosHandler,
+	}
+	for ruta, h := range handlers {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, ruta, nil).WithContext(ctx))
+		cancel()
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("%s: status = %d, se esperaba 504", ruta, rec.Code)
+		}
+	}
+}
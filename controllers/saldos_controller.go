@@ -1,384 +1,282 @@
 package controllers
 
 import (
-	"database/sql"
-	"encoding/json"
-	"go_api/db"
 	"go_api/models"
+	"go_api/repository"
 	"go_api/views"
 	"log"
 	"net/http"
 	"strconv"
-	"time"
-
-	"github.com/tealeg/xlsx"
 )
 
-// getSaldos obtiene la lista de saldos desde la base de datos MySQL.
-// Se le pasa la conexión a la BD (esto permite reutilizar la función con otras conexiones si es necesario).
-func getSaldos(dbConn *sql.DB) ([]models.Saldo, error) {
-	query := `SELECT 
-    COD_ART AS Codigo_Producto,
-    ZET_ART AS Zeta,
-    ANIO_PRO AS Año_Produccion,
-    MAX(DES_INT) AS Nombre_Producto,
-    MAX(UNI_CAJ) AS Unidad_Caja,
-    MAX(CIF_UNI) AS Costo_CIF,
-    MAX(cos_uni) AS Costo_Real,
-    MAX(FEC_ING) AS Fecha_Ingreso,
-    SUM(CAN_ING) AS Cantidad_Ingresada,
-    MAX(SAL_ANT) AS Saldo_Anterior,
-    DATEDIFF(CURDATE(), MAX(FEC_ING)) AS Dias_Desde_Ingreso,
-    MAX(FIN_ENE) AS Saldo_Fin_Enero,
-    MAX(FIN_FEB) AS Saldo_Fin_Febrero,
-    MAX(FIN_MAR) AS Saldo_Fin_Marzo,
-    MAX(FIN_ABR) AS Saldo_Fin_Abril,
-    MAX(FIN_MAY) AS Saldo_Fin_Mayo,
-    MAX(FIN_JUN) AS Saldo_Fin_Junio,
-    MAX(FIN_JUL) AS Saldo_Fin_Julio,
-    MAX(FIN_AGO) AS Saldo_Fin_Agosto,
-    MAX(FIN_SEP) AS Saldo_Fin_Septiembre,
-    MAX(FIN_OCT) AS Saldo_Fin_Octubre,
-    MAX(FIN_NOV) AS Saldo_Fin_Noviembre,
-    MAX(FIN_DIC) AS Saldo_Fin_Diciembre
-FROM saldos
-GROUP BY COD_ART, ZET_ART, ANIO_PRO
-ORDER BY ANIO_PRO, COD_ART;`
+// Modificación en SaldosHandler para paginación
+func (a *App) SaldosHandler(w http.ResponseWriter, r *http.Request) {
+	// Obtener parámetros de la URL
+	query := r.URL.Query()
+	params, err := parseListParams(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	rows, err := dbConn.Query(query)
+	// Obtener datos con los filtros aplicados
+	orden, err := params.ordenSaldos()
 	if err != nil {
-		return nil, err
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Un error en la expresión de filtro se muestra junto al buscador
+	filter, err := params.filtroSaldos()
+	if err != nil {
+		conteo := query.Get("count")
+		if conteo == "" {
+			conteo = conteoPorDefecto
+		}
+		a.Views.RenderSaldos(w, views.ViewData{
+			CurrentPage: 1,
+			PageSize:    params.PageSize,
+			Search:      params.Search,
+			ErrorFiltro: err.Error(),
+			SortField:   params.SortField,
+			SortDir:     params.SortDir,
+			PorCursor:   query.Has("cursor"),
+			Conteo:      conteo,
+		})
+		return
 	}
-	defer rows.Close()
 
-	var saldos []models.Saldo
-	for rows.Next() {
-		var s models.Saldo
-		var fechaIngresoBytes []byte // variable temporal para Fecha_Ingreso
-		var dias sql.NullInt64       // variable temporal para Dias_Desde_Ingreso
-		err := rows.Scan(
-			&s.CodigoProducto,
-			&s.Zeta,
-			&s.AnioProduccion,
-			&s.NombreProducto,
-			&s.UnidadCaja,
-			&s.CostoCIF,
-			&s.CostoReal,
-			&fechaIngresoBytes,
-			&s.CantidadIngresada,
-			&s.SaldoAnterior,
-			&dias, // se lee como sql.NullInt64
-			&s.SaldoFinEnero,
-			&s.SaldoFinFebrero,
-			&s.SaldoFinMarzo,
-			&s.SaldoFinAbril,
-			&s.SaldoFinMayo,
-			&s.SaldoFinJunio,
-			&s.SaldoFinJulio,
-			&s.SaldoFinAgosto,
-			&s.SaldoFinSeptiembre,
-			&s.SaldoFinOctubre,
-			&s.SaldoFinNoviembre,
-			&s.SaldoFinDiciembre,
-		)
+	// Con ?cursor= se navega por clave, sin números de página
+	if query.Has("cursor") {
+		cp, err := parseCursorParams(query, orden)
 		if err != nil {
-			return nil, err
-		}
-		// Convertir fecha de []byte a time.Time si no está vacía
-		fechaStr := string(fechaIngresoBytes)
-		if fechaStr == "" {
-			s.FechaIngreso = time.Time{} // valor por defecto si está vacío
-		} else {
-			s.FechaIngreso, err = time.Parse("2006-01-02", fechaStr)
-			if err != nil {
-				s.FechaIngreso, err = time.Parse("2006-01-02 15:04:05", fechaStr)
-				if err != nil {
-					return nil, err
-				}
-			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-		if dias.Valid {
-			s.DiasDesdeIngreso = int(dias.Int64)
-		} else {
-			s.DiasDesdeIngreso = 0
+		pagina, err := a.loadSaldosCursor(r.Context(), cp, params.PageSize, filter)
+		if err != nil {
+			respondDataError(w, "Error al obtener los datos", err)
+			return
 		}
-		saldos = append(saldos, s)
+		a.Views.RenderSaldos(w, views.ViewData{
+			Items:           pagina.Items,
+			PageSize:        params.PageSize,
+			Search:          params.Search,
+			SortField:       params.SortField,
+			SortDir:         params.SortDir,
+			PorCursor:       true,
+			Siguiente:       pagina.Siguiente,
+			Anterior:        pagina.Anterior,
+			Conteo:          cp.Conteo,
+			Total:           pagina.Total,
+			ConTotal:        pagina.ConTotal,
+			TotalAproximado: pagina.Aproximado,
+		})
+		return
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+	saldos, total, err := a.Saldos.ListPaginated(r.Context(), params.Offset(), params.PageSize, filter, orden)
+	if err != nil {
+		respondDataError(w, "Error al obtener los datos", err)
+		return
 	}
 
-	return saldos, nil
-}
+	viewData := views.ViewData{
+		Items:       saldos,
+		CurrentPage: params.Page,
+		TotalPages:  params.TotalPages(total),
+		PageSize:    params.PageSize,
+		Search:      params.Search,
+		SortField:   params.SortField,
+		SortDir:     params.SortDir,
+	}
 
-// Nueva función para paginación: obtiene 'limit' registros con 'offset'
-func getSaldosPaginated(dbConn *sql.DB, offset, limit int, search, sortField, sortDir string) ([]models.Saldo, int, error) {
-	// Construir la consulta base
-	baseQuery := `SELECT 
-        COD_ART AS Codigo_Producto,
-        ZET_ART AS Zeta,
-        ANIO_PRO AS Año_Produccion,
-        DES_INT AS Nombre_Producto,
-        UNI_CAJ AS Unidad_Caja,
-        CIF_UNI AS Costo_CIF,
-        cos_uni AS Costo_Real,
-        FEC_ING AS Fecha_Ingreso,
-        CAN_ING AS Cantidad_Ingresada,
-        SAL_ANT AS Saldo_Anterior,
-        DATEDIFF(CURDATE(), FEC_ING) AS Dias_Desde_Ingreso,
-        FIN_ENE AS Saldo_Fin_Enero,
-        FIN_FEB AS Saldo_Fin_Febrero,
-        FIN_MAR AS Saldo_Fin_Marzo,
-        FIN_ABR AS Saldo_Fin_Abril,
-        FIN_MAY AS Saldo_Fin_Mayo,
-        FIN_JUN AS Saldo_Fin_Junio,
-        FIN_JUL AS Saldo_Fin_Julio,
-        FIN_AGO AS Saldo_Fin_Agosto,
-        FIN_SEP AS Saldo_Fin_Septiembre,
-        FIN_OCT AS Saldo_Fin_Octubre,
-        FIN_NOV AS Saldo_Fin_Noviembre,
-        FIN_DIC AS Saldo_Fin_Diciembre
-    FROM saldos`
+	a.Views.RenderSaldos(w, viewData)
+}
 
-	// Agregar condición WHERE si hay búsqueda
-	whereClause := ""
-	if search != "" {
-		whereClause = " WHERE DES_INT LIKE ? OR COD_ART LIKE ? OR ZET_ART LIKE ?"
+// columnasSaldos devuelve las columnas de la exportación de saldos,
+// incluidos los doce saldos de fin de mes.
+func columnasSaldos() []columna {
+	columnas := []columna{
+		{"Código", colTexto, 0, false, false},
+		{"Zeta", colTexto, 0, false, false},
+		{"Año Producción", colEntero, 0, false, false},
+		{"Nombre Producto", colTexto, 40, false, false},
+		{"Unidad Caja", colDecimal, 0, false, false},
+		{"Costo CIF", colMoneda, 0, false, false},
+		{"Costo Real", colMoneda, 0, false, false},
+		{"Fecha Ingreso", colFecha, 0, false, false},
+		{"Cant. Ingresada", colDecimal, 0, true, false},
+		{"Saldo Anterior", colDecimal, 0, true, false},
+		{"Días Desde Ingreso", colEntero, 0, false, false},
 	}
+	return append(columnas, columnasSaldosMensuales()...)
+}
 
-	// Agregar ORDER BY si hay campo de ordenamiento
-	orderClause := " ORDER BY "
-	if sortField != "" {
-		// Mapear nombres de campos
-		switch sortField {
-		case "CodigoProducto":
-			orderClause += "COD_ART"
-		case "Zeta":
-			orderClause += "ZET_ART"
-		case "AnioProduccion":
-			orderClause += "ANIO_PRO"
-		case "NombreProducto":
-			orderClause += "DES_INT"
-		default:
-			orderClause += "COD_ART"
-		}
-		orderClause += " " + sortDir
-	} else {
-		orderClause += "COD_ART ASC"
+// filaSaldo devuelve los valores de un saldo en el orden de
+// columnasSaldos.
+func filaSaldo(s models.Saldo) []any {
+	fila := []any{
+		s.CodigoProducto, s.Zeta, s.AnioProduccion, s.NombreProducto, s.UnidadCaja,
+		s.CostoCIF, s.CostoReal, s.FechaIngreso, s.CantidadIngresada, s.SaldoAnterior,
+		s.DiasDesdeIngreso,
 	}
-
-	// Construir consulta final con LIMIT y OFFSET
-	query := baseQuery + whereClause + orderClause + " LIMIT ? OFFSET ?"
-
-	log.Printf("Query ejecutada: %s", query)
-
-	// Ejecutar consulta
-	var rows *sql.Rows
-	var err error
-	if search != "" {
-		searchPattern := "%" + search + "%"
-		rows, err = dbConn.Query(query, searchPattern, searchPattern, searchPattern, limit, offset)
-	} else {
-		rows, err = dbConn.Query(query, limit, offset)
+	for _, saldo := range s.SaldosMensuales() {
+		fila = append(fila, saldo)
 	}
+	return fila
+}
 
+// ExportSaldosHandler exporta los saldos en Excel, CSV (format=csv), NDJSON
+// (format=ndjson) o PDF (format=pdf) con los mismos filtros (search, codigo,
+// zeta, anio, ...), orden (sort, dir) y agrupación por código, zeta y año
+// que /api/saldos. Por defecto exporta todos los saldos que cumplen el
+// filtro, recorriéndolos sin cargarlos en memoria (el PDF sí se arma en
+// memoria, hasta maxFilasPDF filas); con page se limita a esa página de
+// pageSize saldos.
+func (a *App) ExportSaldosHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts, err := parseExportOptions(query, formatoXLSX, formatoCSV, formatoNDJSON, formatoPDF)
 	if err != nil {
-		log.Printf("Error en la consulta: %v", err)
-		return nil, 0, err
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	defer rows.Close()
-
-	// Procesar resultados
-	var saldos []models.Saldo
-	for rows.Next() {
-		var s models.Saldo
-		var fechaIngresoBytes []byte
-		var dias sql.NullInt64
-		err := rows.Scan(
-			&s.CodigoProducto,
-			&s.Zeta,
-			&s.AnioProduccion,
-			&s.NombreProducto,
-			&s.UnidadCaja,
-			&s.CostoCIF,
-			&s.CostoReal,
-			&fechaIngresoBytes,
-			&s.CantidadIngresada,
-			&s.SaldoAnterior,
-			&dias,
-			&s.SaldoFinEnero,
-			&s.SaldoFinFebrero,
-			&s.SaldoFinMarzo,
-			&s.SaldoFinAbril,
-			&s.SaldoFinMayo,
-			&s.SaldoFinJunio,
-			&s.SaldoFinJulio,
-			&s.SaldoFinAgosto,
-			&s.SaldoFinSeptiembre,
-			&s.SaldoFinOctubre,
-			&s.SaldoFinNoviembre,
-			&s.SaldoFinDiciembre,
-		)
-		if err != nil {
-			log.Printf("Error al escanear fila: %v", err)
-			return nil, 0, err
-		}
-
-		// Procesar fecha
-		fechaStr := string(fechaIngresoBytes)
-		if fechaStr == "" {
-			s.FechaIngreso = time.Time{}
-		} else {
-			s.FechaIngreso, err = time.Parse("2006-01-02", fechaStr)
-			if err != nil {
-				s.FechaIngreso, err = time.Parse("2006-01-02 15:04:05", fechaStr)
-				if err != nil {
-					log.Printf("Error al parsear fecha: %v", err)
-					return nil, 0, err
-				}
-			}
-		}
-
-		if dias.Valid {
-			s.DiasDesdeIngreso = int(dias.Int64)
-		}
-
-		saldos = append(saldos, s)
+	params, err := parseListParams(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-
-	// Obtener total de registros
-	var total int
-	countQuery := "SELECT COUNT(*) FROM saldos" + whereClause
-	if search != "" {
-		searchPattern := "%" + search + "%"
-		err = dbConn.QueryRow(countQuery, searchPattern, searchPattern, searchPattern).Scan(&total)
-	} else {
-		err = dbConn.QueryRow("SELECT COUNT(*) FROM saldos").Scan(&total)
+	filter, err := parseSaldoFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	orden, err := params.ordenSaldos()
 	if err != nil {
-		log.Printf("Error al contar registros: %v", err)
-		return nil, 0, err
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	return saldos, total, nil
-}
-
-// Modificación en SaldosHandler para paginación
-func SaldosHandler(w http.ResponseWriter, r *http.Request) {
-	// Obtener parámetros de la URL
-	query := r.URL.Query()
-	page, _ := strconv.Atoi(query.Get("page"))
-	if page < 1 {
-		page = 1
+	filename := "saldos"
+	if query.Get("page") != "" {
+		filename += "_pagina_" + strconv.Itoa(params.Page)
 	}
 
-	pageSize, _ := strconv.Atoi(query.Get("pageSize"))
-	if pageSize < 1 {
-		pageSize = 25
+	// El export se crea con la primera fila para poder responder con un
+	// error normal si la consulta falla antes de enviar nada.
+	var ew exportWriter
+	escribir := func(s models.Saldo) error {
+		if ew == nil {
+			var err error
+			if ew, err = newExportWriter(w, opts, filename, "Saldos", columnasSaldos()); err != nil {
+				return err
+			}
+		}
+		return ew.Write(s, filaSaldo(s))
 	}
 
-	search := query.Get("search")
-	sortField := query.Get("sort")
-	sortDir := query.Get("dir")
-
-	if sortDir != "desc" {
-		sortDir = "asc"
+	if query.Get("page") != "" {
+		var saldos []models.Saldo
+		saldos, _, err = a.Saldos.ListPaginated(r.Context(), params.Offset(), params.PageSize, filter, orden)
+		for i := 0; err == nil && i < len(saldos); i++ {
+			err = escribir(saldos[i])
+		}
+	} else {
+		err = a.Saldos.Each(r.Context(), filter, orden, escribir)
 	}
 
-	// Obtener datos con los filtros aplicados
-	offset := (page - 1) * pageSize
-	saldos, total, err := getSaldosPaginated(db.MySQLDB, offset, pageSize, search, sortField, sortDir)
 	if err != nil {
-		http.Error(w, "Error al obtener los datos", http.StatusInternalServerError)
+		if ew == nil {
+			respondDataError(w, "Error al exportar los saldos", err)
+			return
+		}
+		errorExportacion(w, ew, err)
 		return
 	}
-
-	totalPages := (total + pageSize - 1) / pageSize
-
-	viewData := views.ViewData{
-		Items:       saldos,
-		CurrentPage: page,
-		TotalPages:  totalPages,
-		PageSize:    pageSize,
-		Search:      search,
-		SortField:   sortField,
-		SortDir:     sortDir,
+	if ew == nil {
+		// Sin filas: exportar solo los encabezados
+		if ew, err = newExportWriter(w, opts, filename, "Saldos", columnasSaldos()); err != nil {
+			http.Error(w, "Error al crear la exportación", http.StatusInternalServerError)
+			log.Println("Error al crear la exportación:", err)
+			return
+		}
+	}
+	if err := ew.Close(); err != nil {
+		log.Println("Error al escribir la exportación:", err)
 	}
-
-	views.RenderSaldos(w, viewData)
 }
 
-// Actualización en ExportSaldosHandler para exportar datos paginados.
-func ExportSaldosHandler(w http.ResponseWriter, r *http.Request) {
-	// Leer parámetro "page" para paginación
-	pageStr := r.URL.Query().Get("page")
-	page := 1
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
+// ApiSaldosHandler maneja la ruta /api/saldos y devuelve los datos en formato
+// JSON paginados y filtrados. Con ?all=true devuelve el listado agrupado completo.
+// Con ?cursor= pagina por clave: la respuesta trae nextCursor y prevCursor
+// para pedir las páginas vecinas y el total según count (exact, approx o none).
+func (a *App) ApiSaldosHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if query.Get("all") == "true" {
+		saldos, err := a.Saldos.List(r.Context(), repository.SaldoFilter{})
+		if err != nil {
+			respondDataError(w, "Error al obtener los saldos", err)
+			return
 		}
+		writeJSON(w, saldos)
+		return
 	}
-	limit := 25
-	offset := (page - 1) * limit
 
-	// Obtener la página solicitada con paginación
-	saldos, total, err := getSaldosPaginated(db.MySQLDB, offset, limit, "", "", "")
+	params, err := parseListParams(query)
 	if err != nil {
-		http.Error(w, "Error al obtener los datos", http.StatusInternalServerError)
-		log.Println("Error al exportar los saldos:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	log.Printf("Exportando %d registros de un total de %d", len(saldos), total)
-
-	// Crear un nuevo archivo Excel y exportar los datos de la página.
-	file := xlsx.NewFile()
-	sheet, err := file.AddSheet("Saldos")
+	filter, err := parseSaldoFilter(query)
 	if err != nil {
-		http.Error(w, "Error al crear el Excel", http.StatusInternalServerError)
-		log.Println("Error al crear hoja en Excel:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	// Agregar encabezado
-	row := sheet.AddRow()
-	headers := []string{"Código", "Zeta", "Año Producción", "Nombre Producto", "Unidad Caja", "Costo CIF", "Costo Real", "Fecha Ingreso", "Cant. Ingresada", "Saldo Anterior", "Días Desde Ingreso"}
-	for _, h := range headers {
-		cell := row.AddCell()
-		cell.Value = h
-	}
-	// Agregar datos de la página actual
-	for _, s := range saldos {
-		row := sheet.AddRow()
-		row.AddCell().Value = s.CodigoProducto
-		row.AddCell().Value = s.Zeta
-		row.AddCell().SetInt(s.AnioProduccion)
-		row.AddCell().Value = s.NombreProducto
-		row.AddCell().SetFloat(s.UnidadCaja)
-		row.AddCell().SetFloat(s.CostoCIF)
-		row.AddCell().SetFloat(s.CostoReal)
-		row.AddCell().Value = s.FechaIngreso.Format("2006-01-02")
-		row.AddCell().SetFloat(s.CantidadIngresada)
-		row.AddCell().SetFloat(s.SaldoAnterior)
-		row.AddCell().SetInt(s.DiasDesdeIngreso)
+	orden, err := params.ordenSaldos()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	// Enviar archivo Excel como respuesta
-	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	w.Header().Set("Content-Disposition", "attachment; filename=saldos.xlsx")
-	if err := file.Write(w); err != nil {
-		http.Error(w, "Error al generar el Excel", http.StatusInternalServerError)
-		log.Println("Error al escribir el Excel:", err)
+
+	if query.Has("cursor") {
+		cp, err := parseCursorParams(query, orden)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pagina, err := a.loadSaldosCursor(r.Context(), cp, params.PageSize, filter)
+		if err != nil {
+			respondDataError(w, "Error al obtener los saldos", err)
+			return
+		}
+		resp := cursorResponse{
+			Data:            pagina.Items,
+			PageSize:        params.PageSize,
+			NextCursor:      pagina.Siguiente,
+			PrevCursor:      pagina.Anterior,
+			TotalAproximado: pagina.Aproximado,
+		}
+		if pagina.ConTotal {
+			resp.Total = &pagina.Total
+		}
+		writeJSON(w, resp)
+		return
 	}
-}
 
-// ApiSaldosHandler maneja la ruta /api/saldos y devuelve los datos en formato JSON.
-func ApiSaldosHandler(w http.ResponseWriter, r *http.Request) {
-	saldos, err := getSaldos(db.MySQLDB)
+	saldos, total, err := a.Saldos.ListPaginated(r.Context(), params.Offset(), params.PageSize, filter, orden)
 	if err != nil {
-		http.Error(w, "Error al obtener los datos", http.StatusInternalServerError)
-		log.Println("Error al obtener los saldos:", err)
+		respondDataError(w, "Error al obtener los saldos", err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(saldos)
+	if saldos == nil {
+		saldos = []models.Saldo{}
+	}
+
+	writeJSON(w, paginatedResponse{
+		Data:       saldos,
+		Total:      total,
+		Page:       params.Page,
+		PageSize:   params.PageSize,
+		TotalPages: params.TotalPages(total),
+	})
 }
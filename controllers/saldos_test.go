@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"go_api/config"
+	"go_api/models"
+)
+
+// exportarSaldos pide la exportación de saldos en NDJSON y devuelve las
+// zetas exportadas, en orden.
+func exportarSaldos(t *testing.T, app *App, query url.Values) (*httptest.ResponseRecorder, []string) {
+	t.Helper()
+	query.Set("format", formatoNDJSON)
+	rec := httptest.NewRecorder()
+	app.ExportSaldosHandler(rec, httptest.NewRequest(http.MethodGet, "/export/saldos?"+query.Encode(), nil))
+	zetas := []string{}
+	if rec.Code != http.StatusOK {
+		return rec, zetas
+	}
+	sc := bufio.NewScanner(rec.Body)
+	for sc.Scan() {
+		var s models.Saldo
+		if err := json.Unmarshal(sc.Bytes(), &s); err != nil {
+			t.Fatalf("línea inválida: %v", err)
+		}
+		zetas = append(zetas, s.Zeta)
+	}
+	return rec, zetas
+}
+
+func TestExportSaldosBusquedaYOrden(t *testing.T) {
+	app := newTestApp(t, config.Config{})
+
+	// Sin page se exporta todo lo filtrado, sin importar pageSize, en el
+	// orden pedido
+	query := url.Values{"search": {"vino costo_real<1700"}, "sort": {"-CostoReal,Zeta"}, "pageSize": {"1"}}
+	rec, zetas := exportarSaldos(t, app, query)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if want := []string{"Z24002", "Z25002", "Z24003", "Z25003"}; !reflect.DeepEqual(zetas, want) {
+		t.Errorf("zetas = %v, se esperaba %v", zetas, want)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != "attachment; filename=saldos.ndjson" {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	// Con page solo la página, con el mismo filtro y orden
+	query.Set("page", "2")
+	rec, zetas = exportarSaldos(t, app, query)
+	if !reflect.DeepEqual(zetas, []string{"Z25002"}) {
+		t.Errorf("página 2 = %v", zetas)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != "attachment; filename=saldos_pagina_2.ndjson" {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	// Sin coincidencias se exportan solo los encabezados, sin error
+	if rec, zetas := exportarSaldos(t, app, url.Values{"search": {"NO-EXISTE"}}); rec.Code != http.StatusOK || len(zetas) != 0 {
+		t.Errorf("sin filas: status = %d, zetas = %v", rec.Code, zetas)
+	}
+
+	for _, q := range []url.Values{
+		{"sort": {"NoEsCampo"}},
+		{"search": {"costo_real>"}},
+	} {
+		if rec, _ := exportarSaldos(t, app, q); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: status = %d", q, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"go_api/models"
+	"go_api/repository"
+	"go_api/views"
+)
+
+// construirSerie pivotea las columnas FIN_ENE..FIN_DIC de los saldos en una
+// serie mensual por año de producción, ordenadas por año. Los saldos de un
+// mismo año se suman (un producto puede tener varias zetas y una zeta varias
+// filas). La variación se calcula dentro de cada lote: enero no se compara
+// con diciembre del lote anterior.
+func construirSerie(tipo, clave string, saldos []models.Saldo) models.Serie {
+	serie := models.Serie{Tipo: tipo, Clave: clave, Lotes: []models.LoteSerie{}}
+
+	porAnio := make(map[int]*[12]float64)
+	var anios []int
+	for _, s := range saldos {
+		if serie.Nombre == "" {
+			serie.Nombre = s.NombreProducto
+		}
+		totales, ok := porAnio[s.AnioProduccion]
+		if !ok {
+			totales = &[12]float64{}
+			porAnio[s.AnioProduccion] = totales
+			anios = append(anios, s.AnioProduccion)
+		}
+		for mes, saldo := range s.SaldosMensuales() {
+			totales[mes] += saldo
+		}
+	}
+	sort.Ints(anios)
+
+	for _, anio := range anios {
+		lote := models.LoteSerie{AnioProduccion: anio, Puntos: make([]models.PuntoSerie, 0, 12)}
+		for mes, saldo := range porAnio[anio] {
+			punto := models.PuntoSerie{
+				Mes:       mes + 1,
+				NombreMes: models.Meses[mes],
+				Saldo:     saldo,
+			}
+			if mes > 0 {
+				punto.Variacion = saldo - porAnio[anio][mes-1]
+			}
+			lote.Puntos = append(lote.Puntos, punto)
+		}
+		serie.Lotes = append(serie.Lotes, lote)
+	}
+	return serie
+}
+
+// loadSerie obtiene los saldos del producto o zeta y construye su serie.
+// Responde 404 si no hay saldos para la clave.
+func (a *App) loadSerie(w http.ResponseWriter, r *http.Request, tipo, clave string) (models.Serie, bool) {
+	filter := repository.SaldoFilter{Codigo: clave}
+	if tipo == "zeta" {
+		filter = repository.SaldoFilter{Zeta: clave}
+	}
+
+	saldos, err := a.Saldos.List(r.Context(), filter)
+	if err != nil {
+		respondDataError(w, "Error obteniendo la serie de saldos", err)
+		return models.Serie{}, false
+	}
+	if len(saldos) == 0 {
+		http.Error(w, "No hay saldos para "+tipo+" "+clave, http.StatusNotFound)
+		return models.Serie{}, false
+	}
+	return construirSerie(tipo, clave, saldos), true
+}
+
+// pathParam extrae el segmento variable de una ruta con la forma
+// prefix{valor}suffix. Devuelve false si la ruta no tiene esa forma.
+func pathParam(path, prefix, suffix string) (string, bool) {
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) {
+		return "", false
+	}
+	valor := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	if valor == "" || strings.Contains(valor, "/") {
+		return "", false
+	}
+	return valor, true
+}
+
+// serieRoute identifica el tipo y la clave de las rutas de series:
+// {prefix}saldos/{codigo}/series y {prefix}zetas/{zeta}/series.
+func serieRoute(path, prefix string) (tipo, clave string, ok bool) {
+	if clave, ok = pathParam(path, prefix+"saldos/", "/series"); ok {
+		return "producto", clave, true
+	}
+	if clave, ok = pathParam(path, prefix+"zetas/", "/series"); ok {
+		return "zeta", clave, true
+	}
+	return "", "", false
+}
+
+// SeriesApiHandler maneja /api/saldos/{codigo}/series y
+// /api/zetas/{zeta}/series y devuelve la serie mensual en JSON.
+func (a *App) SeriesApiHandler(w http.ResponseWriter, r *http.Request) {
+	tipo, clave, ok := serieRoute(r.URL.Path, "/api/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	serie, ok := a.loadSerie(w, r, tipo, clave)
+	if !ok {
+		return
+	}
+	writeJSON(w, serie)
+}
+
+// SeriesViewHandler maneja /saldos/{codigo}/series y /zetas/{zeta}/series y
+// muestra la serie mensual con su gráfico.
+func (a *App) SeriesViewHandler(w http.ResponseWriter, r *http.Request) {
+	tipo, clave, ok := serieRoute(r.URL.Path, "/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	serie, ok := a.loadSerie(w, r, tipo, clave)
+	if !ok {
+		return
+	}
+	a.Views.RenderSerie(w, views.SerieViewData{Serie: serie})
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_api/config"
+	"go_api/models"
+	"go_api/repository"
+)
+
+// saldoMensual devuelve un saldo del lote anio con los cierres de mes dados.
+func saldoMensual(anio int, cierres [12]float64) models.Saldo {
+	return models.Saldo{
+		AnioProduccion: anio, NombreProducto: "VINO",
+		SaldoFinEnero: cierres[0], SaldoFinFebrero: cierres[1], SaldoFinMarzo: cierres[2],
+		SaldoFinAbril: cierres[3], SaldoFinMayo: cierres[4], SaldoFinJunio: cierres[5],
+		SaldoFinJulio: cierres[6], SaldoFinAgosto: cierres[7], SaldoFinSeptiembre: cierres[8],
+		SaldoFinOctubre: cierres[9], SaldoFinNoviembre: cierres[10], SaldoFinDiciembre: cierres[11],
+	}
+}
+
+func TestConstruirSerieUnaPorLote(t *testing.T) {
+	serie := construirSerie("producto", "P-1", []models.Saldo{
+		saldoMensual(2025, [12]float64{5, 3}),
+		saldoMensual(2024, [12]float64{10, 12, 12, 0, 0, 0, 0, 0, 0, 0, 0, 7}),
+		// Otra zeta del mismo lote se suma
+		saldoMensual(2025, [12]float64{1, 1}),
+	})
+	if serie.Nombre != "VINO" || len(serie.Lotes) != 2 {
+		t.Fatalf("serie = %+v, se esperaban dos lotes", serie)
+	}
+	if serie.Lotes[0].AnioProduccion != 2024 || serie.Lotes[1].AnioProduccion != 2025 {
+		t.Errorf("lotes = %d, %d; se esperaba 2024 y 2025", serie.Lotes[0].AnioProduccion, serie.Lotes[1].AnioProduccion)
+	}
+	for _, l := range serie.Lotes {
+		if len(l.Puntos) != 12 || l.Puntos[0].Mes != 1 || l.Puntos[11].NombreMes != "Diciembre" {
+			t.Errorf("lote %d: puntos = %+v", l.AnioProduccion, l.Puntos)
+		}
+	}
+
+	tests := []struct {
+		lote, mes        int
+		saldo, variacion float64
+	}{
+		{0, 1, 10, 0},
+		{0, 2, 12, 2},
+		{0, 3, 12, 0},
+		{0, 4, 0, -12},
+		{0, 12, 7, 7},
+		// Enero de 2025 no se compara con diciembre de 2024
+		{1, 1, 6, 0},
+		{1, 2, 4, -2},
+		{1, 3, 0, -4},
+	}
+	for _, tt := range tests {
+		p := serie.Lotes[tt.lote].Puntos[tt.mes-1]
+		if p.Saldo != tt.saldo || p.Variacion != tt.variacion {
+			t.Errorf("lote %d, mes %d: saldo = %v, variación = %v; se esperaba %v, %v",
+				serie.Lotes[tt.lote].AnioProduccion, tt.mes, p.Saldo, p.Variacion, tt.saldo, tt.variacion)
+		}
+	}
+
+	if vacia := construirSerie("zeta", "Z", nil); vacia.Lotes == nil || len(vacia.Lotes) != 0 {
+		t.Errorf("serie vacía = %+v, se esperaba una lista de lotes vacía", vacia)
+	}
+}
+
+func TestSeriesHandlers(t *testing.T) {
+	app := newTestApp(t, config.Config{SucursalPorDefecto: repository.SucursalPorDefecto})
+	saldos, _ := repository.DemoData()
+	s := saldos[0]
+
+	rec := httptest.NewRecorder()
+	app.SeriesApiHandler(rec, httptest.NewRequest(http.MethodGet, "/api/zetas/"+s.Zeta+"/series", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var serie models.Serie
+	if err := json.NewDecoder(rec.Body).Decode(&serie); err != nil {
+		t.Fatal(err)
+	}
+	if serie.Tipo != "zeta" || serie.Clave != s.Zeta || len(serie.Lotes) == 0 {
+		t.Errorf("serie = %+v", serie)
+	}
+
+	rec = httptest.NewRecorder()
+	app.SeriesViewHandler(rec, httptest.NewRequest(http.MethodGet, "/saldos/"+s.CodigoProducto+"/series", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("vista: status = %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "Lote ") || strings.Contains(body, "ZgotmplZ") {
+		t.Errorf("la vista no muestra las líneas por lote")
+	}
+
+	for _, ruta := range []string{"/api/zetas/NO-EXISTE/series", "/api/saldos//series", "/api/otros/x/series"} {
+		rec := httptest.NewRecorder()
+		app.SeriesApiHandler(rec, httptest.NewRequest(http.MethodGet, ruta, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, se esperaba 404", ruta, rec.Code)
+		}
+	}
+}
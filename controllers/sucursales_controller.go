@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_api/models"
+)
+
+// SucursalesHandler maneja /api/sucursales y devuelve las sucursales con
+// stock de productos activos en SQL Server.
+func (a *App) SucursalesHandler(w http.ResponseWriter, r *http.Request) {
+	sucursales, err := a.Stocks.Sucursales(r.Context())
+	if err != nil {
+		respondDataError(w, "Error obteniendo sucursales", err)
+		return
+	}
+	if sucursales == nil {
+		sucursales = []models.Sucursal{}
+	}
+	writeJSON(w, sucursales)
+}
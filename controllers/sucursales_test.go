@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go_api/config"
+	"go_api/models"
+	"go_api/repository"
+)
+
+func TestParseSucursales(t *testing.T) {
+	muchas := make([]string, maxSucursales+1)
+	for i := range muchas {
+		muchas[i] = fmt.Sprint(i + 1)
+	}
+	tests := []struct {
+		valores []string
+		want    []int
+		wantErr bool
+	}{
+		{nil, []int{211}, false},
+		{[]string{""}, []int{211}, false},
+		{[]string{"5"}, []int{5}, false},
+		{[]string{"212, 5,212"}, []int{5, 212}, false},
+		{[]string{"3", "1,2"}, []int{1, 2, 3}, false},
+		{[]string{"0"}, nil, true},
+		{[]string{"-4"}, nil, true},
+		{[]string{"abc"}, nil, true},
+		{[]string{strings.Join(muchas, ",")}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSucursales(url.Values{"sucursal": tt.valores}, 211)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSucursales(%q) = %v, %v; se esperaba %v (error %v)", tt.valores, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSucursalesHandler(t *testing.T) {
+	app := newTestApp(t, config.Config{SucursalPorDefecto: repository.SucursalPorDefecto})
+	rec := httptest.NewRecorder()
+	app.SucursalesHandler(rec, httptest.NewRequest(http.MethodGet, "/api/sucursales", nil))
+	var sucursales []models.Sucursal
+	if err := json.NewDecoder(rec.Body).Decode(&sucursales); err != nil {
+		t.Fatal(err)
+	}
+	ids := map[int]bool{}
+	for _, s := range sucursales {
+		ids[s.IDSucursal] = true
+		if s.Productos == 0 || s.Zetas == 0 {
+			t.Errorf("sucursal %d sin productos ni zetas: %+v", s.IDSucursal, s)
+		}
+	}
+	if !ids[repository.SucursalPorDefecto] || !ids[212] {
+		t.Errorf("sucursales = %+v, se esperaban 211 y 212", sucursales)
+	}
+
+	// Sin stocks la lista es vacía, no null
+	vacia, err := NewAppWithRepositories(config.Config{}, repository.NewMemorySaldoRepository(nil), repository.NewMemoryStockRepository(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	vacia.SucursalesHandler(rec, httptest.NewRequest(http.MethodGet, "/api/sucursales", nil))
+	if got := strings.TrimSpace(rec.Body.String()); got != "[]" {
+		t.Errorf("sin sucursales = %s, se esperaba []", got)
+	}
+}
+
+// TestCombinadoPorSucursal pide varias sucursales y comprueba que cada fila
+// indica la suya.
+func TestCombinadoPorSucursal(t *testing.T) {
+	app := newTestApp(t, config.Config{SucursalPorDefecto: repository.SucursalPorDefecto})
+	porSucursal := func(query string) map[int]int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		app.CombinedDataHandler(rec, httptest.NewRequest(http.MethodGet, "/api/combined?pageSize=500&"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", query, rec.Code)
+		}
+		var resp struct {
+			Data []models.CombinedData `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		filas := map[int]int{}
+		for _, c := range resp.Data {
+			filas[c.IDSucursal]++
+		}
+		return filas
+	}
+
+	if got := porSucursal(""); len(got) != 1 || got[repository.SucursalPorDefecto] == 0 {
+		t.Errorf("por defecto = %v, se esperaba solo la sucursal %d", got, repository.SucursalPorDefecto)
+	}
+	if got := porSucursal("sucursal=212"); len(got) != 1 || got[212] == 0 {
+		t.Errorf("sucursal=212 = %v", got)
+	}
+	if got := porSucursal("sucursal=211,212"); got[211] == 0 || got[212] == 0 {
+		t.Errorf("sucursal=211,212 = %v, se esperaban filas de ambas", got)
+	}
+
+	rec := httptest.NewRecorder()
+	app.CombinedDataHandler(rec, httptest.NewRequest(http.MethodGet, "/api/combined?sucursal=x", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("sucursal inválida: status = %d, se esperaba 400", rec.Code)
+	}
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"go_api/models"
+	"go_api/views"
+)
+
+// sumarValorMeses acumula en meses el saldo de fin de mes de s valorizado a
+// costo CIF y a costo real.
+func sumarValorMeses(meses *[12]models.ValorMes, s models.Saldo) {
+	for i, saldo := range s.SaldosMensuales() {
+		meses[i].Saldo += saldo
+		meses[i].ValorCIF += saldo * s.CostoCIF
+		meses[i].ValorReal += saldo * s.CostoReal
+	}
+}
+
+// completarMeses asigna el número y nombre de cada mes y calcula la
+// variación con el mes anterior (0 en enero).
+func completarMeses(meses *[12]models.ValorMes) {
+	for i := range meses {
+		meses[i].Mes = i + 1
+		meses[i].NombreMes = models.Meses[i]
+		if i > 0 {
+			meses[i].VariacionCIF = meses[i].ValorCIF - meses[i-1].ValorCIF
+			meses[i].VariacionReal = meses[i].ValorReal - meses[i-1].ValorReal
+		}
+	}
+}
+
+// construirValorizacion valoriza los saldos de fin de mes por año de
+// producción y producto. Las zetas de un mismo producto y año se suman.
+func construirValorizacion(saldos []models.Saldo) models.ReporteValorizacion {
+	type clave struct {
+		anio   int
+		codigo string
+	}
+	productos := make(map[clave]*models.ValorizacionProducto)
+	anios := make(map[int]*models.ValorizacionAnio)
+	var ordenAnios []int
+	var reporte models.ReporteValorizacion
+
+	for _, s := range saldos {
+		anio, ok := anios[s.AnioProduccion]
+		if !ok {
+			anio = &models.ValorizacionAnio{Anio: s.AnioProduccion}
+			anios[s.AnioProduccion] = anio
+			ordenAnios = append(ordenAnios, s.AnioProduccion)
+		}
+		k := clave{s.AnioProduccion, s.CodigoProducto}
+		p, ok := productos[k]
+		if !ok {
+			p = &models.ValorizacionProducto{CodigoProducto: s.CodigoProducto, NombreProducto: s.NombreProducto}
+			productos[k] = p
+		}
+		p.CantidadIngresada += s.CantidadIngresada
+		sumarValorMeses(&p.Meses, s)
+		sumarValorMeses(&anio.Total, s)
+		sumarValorMeses(&reporte.Total, s)
+	}
+
+	claves := make([]clave, 0, len(productos))
+	for k := range productos {
+		claves = append(claves, k)
+	}
+	sort.Slice(claves, func(i, j int) bool {
+		if claves[i].anio != claves[j].anio {
+			return claves[i].anio < claves[j].anio
+		}
+		return claves[i].codigo < claves[j].codigo
+	})
+
+	for _, k := range claves {
+		p := productos[k]
+		completarMeses(&p.Meses)
+		anios[k.anio].Productos = append(anios[k.anio].Productos, *p)
+	}
+
+	sort.Ints(ordenAnios)
+	reporte.Anios = make([]models.ValorizacionAnio, 0, len(ordenAnios))
+	for _, a := range ordenAnios {
+		completarMeses(&anios[a].Total)
+		reporte.Anios = append(reporte.Anios, *anios[a])
+	}
+	completarMeses(&reporte.Total)
+	return reporte
+}
+
+// loadValorizacion obtiene los saldos con los filtros de /api/saldos (anio,
+// codigo, search, ...) y los valoriza. Responde con el error y devuelve false
+// si los parámetros son inválidos o falla la consulta.
+func (a *App) loadValorizacion(w http.ResponseWriter, r *http.Request) (models.ReporteValorizacion, bool) {
+	filter, err := parseSaldoFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return models.ReporteValorizacion{}, false
+	}
+	saldos, err := a.Saldos.List(r.Context(), filter)
+	if err != nil {
+		respondDataError(w, "Error obteniendo los saldos", err)
+		return models.ReporteValorizacion{}, false
+	}
+	return construirValorizacion(saldos), true
+}
+
+// ValorizacionApiHandler maneja /api/reportes/valorizacion y devuelve la
+// valorización mensual en JSON.
+func (a *App) ValorizacionApiHandler(w http.ResponseWriter, r *http.Request) {
+	reporte, ok := a.loadValorizacion(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, reporte)
+}
+
+// ValorizacionViewHandler maneja /reportes/valorizacion.
+func (a *App) ValorizacionViewHandler(w http.ResponseWriter, r *http.Request) {
+	reporte, ok := a.loadValorizacion(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	a.Views.RenderValorizacion(w, views.ValorizacionViewData{
+		Reporte: reporte,
+		Anio:    query.Get("anio"),
+		Search:  query.Get("search"),
+	})
+}
+
+// lineaValorizacion es una fila de la valorización aplanada: un mes de un
+// producto y año, o de un total (Codigo "TOTAL").
+type lineaValorizacion struct {
+	Anio           int    `json:"anio,omitempty"` // 0 en el total general
+	CodigoProducto string `json:"codigoProducto"`
+	NombreProducto string `json:"nombreProducto"`
+	models.ValorMes
+}
+
+// valores devuelve la línea en el orden de columnasValorizacion.
+func (l lineaValorizacion) valores() []any {
+	var anio any = l.Anio
+	if l.Anio == 0 {
+		anio = ""
+	}
+	return []any{anio, l.CodigoProducto, l.NombreProducto, l.Mes, l.NombreMes,
+		l.Saldo, l.ValorCIF, l.ValorReal, l.VariacionCIF, l.VariacionReal}
+}
+
+// lineasValorizacion aplana el reporte en una línea por año, producto y mes,
+// seguidas de las líneas de total de cada año y del total general.
+func lineasValorizacion(reporte models.ReporteValorizacion) []lineaValorizacion {
+	var lineas []lineaValorizacion
+	for _, anio := range reporte.Anios {
+		for _, p := range anio.Productos {
+			for _, m := range p.Meses {
+				lineas = append(lineas, lineaValorizacion{anio.Anio, p.CodigoProducto, p.NombreProducto, m})
+			}
+		}
+		for _, m := range anio.Total {
+			lineas = append(lineas, lineaValorizacion{anio.Anio, "TOTAL", "Total año " + strconv.Itoa(anio.Anio), m})
+		}
+	}
+	for _, m := range reporte.Total {
+		lineas = append(lineas, lineaValorizacion{0, "TOTAL", "Total general", m})
+	}
+	return lineas
+}
+
+// columnasValorizacion son las columnas de las exportaciones de la
+// valorización. No llevan fila de totales porque las líneas de total por año
+// y general ya vienen en el reporte.
+var columnasValorizacion = []columna{
+	{"Año Producción", colEntero, 0, false, false},
+	{"Código", colTexto, 0, false, false},
+	{"Nombre Producto", colTexto, 40, false, false},
+	{"Mes", colEntero, 6, false, false},
+	{"Nombre Mes", colTexto, 0, false, false},
+	{"Saldo", colDecimal, 0, false, false},
+	{"Valor CIF", colMoneda, 16, false, false},
+	{"Valor Real", colMoneda, 16, false, false},
+	{"Variación CIF", colMoneda, 16, false, false},
+	{"Variación Real", colMoneda, 16, false, false},
+}
+
+// ExportValorizacionHandler exporta la valorización en Excel (por defecto),
+// CSV (format=csv), NDJSON (format=ndjson) o PDF (format=pdf), con una línea
+// por mes.
+func (a *App) ExportValorizacionHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseExportOptions(r.URL.Query(), formatoXLSX, formatoCSV, formatoNDJSON, formatoPDF)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reporte, ok := a.loadValorizacion(w, r)
+	if !ok {
+		return
+	}
+
+	ew, err := newExportWriter(w, opts, "valorizacion", "Valorización", columnasValorizacion)
+	if err != nil {
+		http.Error(w, "Error al crear la exportación", http.StatusInternalServerError)
+		log.Println("Error al crear la exportación:", err)
+		return
+	}
+	for _, l := range lineasValorizacion(reporte) {
+		if err := ew.Write(l, l.valores()); err != nil {
+			errorExportacion(w, ew, err)
+			return
+		}
+	}
+	if err := ew.Close(); err != nil {
+		log.Println("Error al escribir la exportación:", err)
+	}
+}
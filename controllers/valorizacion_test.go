@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"testing"
+
+	"go_api/models"
+)
+
+// saldoValorizado es un saldo de prueba de un producto con sus costos.
+func saldoValorizado(anio int, codigo string, cif, real float64, cierres [12]float64) models.Saldo {
+	s := saldoMensual(anio, cierres)
+	s.CodigoProducto, s.CostoCIF, s.CostoReal = codigo, cif, real
+	return s
+}
+
+func TestConstruirValorizacionVariacion(t *testing.T) {
+	reporte := construirValorizacion([]models.Saldo{
+		saldoValorizado(2025, "B", 2, 3, [12]float64{10, 4, 4, 6}),
+		saldoValorizado(2024, "A", 1, 1, [12]float64{5, 5}),
+		// Otra zeta del mismo producto y año se suma
+		saldoValorizado(2025, "B", 2, 3, [12]float64{0, 1}),
+	})
+
+	if len(reporte.Anios) != 2 || reporte.Anios[0].Anio != 2024 || reporte.Anios[1].Anio != 2025 {
+		t.Fatalf("años = %+v", reporte.Anios)
+	}
+	b := reporte.Anios[1].Productos
+	if len(b) != 1 || b[0].CodigoProducto != "B" {
+		t.Fatalf("productos de 2025 = %+v", b)
+	}
+	meses := b[0].Meses
+	// Enero no tiene mes anterior: variación 0 aunque el valor no lo sea
+	if meses[0].ValorCIF != 20 || meses[0].VariacionCIF != 0 || meses[0].VariacionReal != 0 {
+		t.Errorf("enero = %+v", meses[0])
+	}
+	tests := []struct {
+		mes              int
+		valorCIF, varCIF float64
+		varReal          float64
+	}{
+		{2, 10, -10, -15}, // 5 x 2 contra 10 x 2
+		{3, 8, -2, -3},    // 4 x 2 contra 5 x 2
+		{4, 12, 4, 6},     // sube
+		{5, 0, -12, -18},  // se agota
+		{12, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		m := meses[tt.mes-1]
+		if m.Mes != tt.mes || m.NombreMes != models.Meses[tt.mes-1] ||
+			m.ValorCIF != tt.valorCIF || m.VariacionCIF != tt.varCIF || m.VariacionReal != tt.varReal {
+			t.Errorf("mes %d = %+v", tt.mes, m)
+		}
+	}
+
+	// La variación del total se calcula sobre los valores sumados, no se
+	// suman las variaciones
+	total := reporte.Total
+	if total[0].ValorCIF != 25 || total[1].ValorCIF != 15 || total[1].VariacionCIF != -10 {
+		t.Errorf("total = %+v, %+v", total[0], total[1])
+	}
+	if total[2].ValorReal != 12 || total[2].VariacionReal != -8 {
+		t.Errorf("total de marzo = %+v", total[2])
+	}
+}
+
+func TestLineasValorizacion(t *testing.T) {
+	reporte := construirValorizacion([]models.Saldo{
+		saldoValorizado(2024, "A", 1, 1, [12]float64{1}),
+		saldoValorizado(2025, "B", 1, 1, [12]float64{1}),
+	})
+	lineas := lineasValorizacion(reporte)
+	// 12 meses por producto, por total de año y por total general
+	if len(lineas) != 12*5 {
+		t.Fatalf("%d líneas", len(lineas))
+	}
+	ultima := lineas[len(lineas)-1]
+	if ultima.Anio != 0 || ultima.CodigoProducto != "TOTAL" || ultima.Mes != 12 || ultima.valores()[0] != "" {
+		t.Errorf("última línea = %+v", ultima)
+	}
+	if l := lineas[12]; l.CodigoProducto != "TOTAL" || l.NombreProducto != "Total año 2024" {
+		t.Errorf("total de 2024 = %+v", l)
+	}
+}
@@ -7,23 +7,20 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 )
 
-// MySQLDB es la variable global que contendrá la conexión a MySQL.
-var MySQLDB *sql.DB
-
-// InitMySQL establece la conexión a MySQL utilizando el DSN proporcionado.
+// OpenMySQL abre y verifica una conexión a MySQL utilizando el DSN proporcionado.
 // Ejemplo de DSN: "usuario:contraseña@tcp(localhost:3306)/nombre_basedatos"
-func InitMySQL(dsn string) error {
-	var err error
-	MySQLDB, err = sql.Open("mysql", dsn)
+func OpenMySQL(dsn string) (*sql.DB, error) {
+	conn, err := sql.Open("mysql", dsn)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Verifica la conexión.
-	if err = MySQLDB.Ping(); err != nil {
-		return err
+	if err = conn.Ping(); err != nil {
+		conn.Close()
+		return nil, err
 	}
 
 	log.Println("Conexión a MySQL establecida correctamente")
-	return nil
+	return conn, nil
 }
@@ -1,11 +1,11 @@
 package main
 
 import (
-	"go_api/db"
+	"go_api/config"
+	"go_api/controllers"
 	"go_api/routes"
 	"log"
 	"net/http"
-	"os"
 
 	"github.com/joho/godotenv"
 )
@@ -16,36 +16,24 @@ func main() {
 		log.Println("Archivo .env no encontrado, usando variables de entorno del sistema")
 	}
 
-	// Inicializar conexión a SQL Server
-	if err := db.InitSQLServer(); err != nil {
-		log.Printf("Error al inicializar SQL Server: %v", err)
-		return
+	cfg := config.Load()
+	if cfg.DemoMode {
+		log.Println("Modo demo activado, usando datos en memoria")
 	}
-	defer db.SQLServerDB.Close()
-
-	// Construir el DSN para MySQL usando variables de entorno
-	mysqlDSN := os.Getenv("MYSQL_USER") + ":" +
-		os.Getenv("MYSQL_PASSWORD") + "@tcp(" +
-		os.Getenv("MYSQL_HOST") + ")/" +
-		os.Getenv("MYSQL_DATABASE")
 
-	// Inicializar la conexión a MySQL
-	if err := db.InitMySQL(mysqlDSN); err != nil {
-		log.Printf("Error al inicializar MySQL: %v", err)
+	// Crear la instancia con sus conexiones
+	app, err := controllers.NewApp(cfg)
+	if err != nil {
+		log.Printf("Error al inicializar la aplicación: %v", err)
 		return
 	}
-	defer db.MySQLDB.Close()
+	defer app.Close()
 
 	// Configurar rutas centralizadas
-	routes.SetupRoutes()
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	routes.SetupRoutes(app)
 
-	log.Printf("Servidor iniciado en http://localhost:%s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	log.Printf("Servidor iniciado en http://localhost:%s", cfg.Port)
+	if err := http.ListenAndServe(":"+cfg.Port, app); err != nil {
 		log.Fatal("Error al iniciar el servidor:", err)
 	}
 }
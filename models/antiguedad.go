@@ -0,0 +1,24 @@
+package models
+
+// TramoAntiguedad acumula los saldos cuyo ingreso tiene entre Desde y Hasta
+// días de antigüedad (ambos incluidos). Hasta es -1 en el último tramo, que
+// no tiene límite superior.
+type TramoAntiguedad struct {
+	Etiqueta       string  `json:"etiqueta"`
+	Desde          int     `json:"desde"`
+	Hasta          int     `json:"hasta"`
+	Lotes          int     `json:"lotes"`
+	Cantidad       float64 `json:"cantidad"`       // suma de SaldoAnterior
+	ValorCostoReal float64 `json:"valorCostoReal"` // Cantidad × CostoReal
+	ValorCostoCIF  float64 `json:"valorCostoCIF"`  // Cantidad × CostoCIF
+}
+
+// ReporteAntiguedad agrupa los saldos de un año de producción en tramos de
+// días desde el ingreso.
+type ReporteAntiguedad struct {
+	Anio       string            `json:"anio"`
+	Sucursales []int             `json:"sucursales"`
+	Tramos     []TramoAntiguedad `json:"tramos"`
+	Total      TramoAntiguedad   `json:"total"`
+	DatosAl    DatosAl           `json:"datosAl"`
+}
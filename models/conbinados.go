@@ -44,7 +44,13 @@ type SaldoData struct {
 
 // Estructura combinada final (puedes agregar o quitar campos según tus necesidades)
 type CombinedData struct {
+	// Cruce que produjo la fila: estrategia (zeta, zeta_codigo, zeta_anio o
+	// codigo) y clave usada.
+	EstrategiaCruce string
+	ClaveCruce      string
+
 	// Datos provenientes de SQL Server:
+	IDSucursal     int
 	CodigoProducto string
 	Zeta           string
 	AnioProduccion int // o Anio, según corresponda
@@ -72,4 +78,37 @@ type CombinedData struct {
 	SaldoFinOctubre    float64
 	SaldoFinNoviembre  float64
 	SaldoFinDiciembre  float64
+
+	// Márgenes calculados (ver CalcularMargenes): margen bruto absoluto,
+	// margen sobre el precio (%) y markup sobre el costo (%), a precio de
+	// venta y de oferta, contra costo real y costo CIF.
+	MargenVentaReal     float64
+	MargenVentaCIF      float64
+	MargenOfertaReal    float64
+	MargenOfertaCIF     float64
+	MargenPctVentaReal  float64
+	MargenPctVentaCIF   float64
+	MargenPctOfertaReal float64
+	MargenPctOfertaCIF  float64
+	MarkupVentaReal     float64
+	MarkupVentaCIF      float64
+	MarkupOfertaReal    float64
+	MarkupOfertaCIF     float64
+}
+
+// Sucursal resume una sucursal con stock registrado en SQL Server.
+type Sucursal struct {
+	IDSucursal int
+	Productos  int // productos activos distintos con stock
+	Zetas      int // zetas distintas con stock
+}
+
+// ColisionCruce es una clave de cruce que corresponde a más de un registro de
+// stock de SQL Server en una sucursal. Candidatos tiene el stock más reciente
+// de cada registro y Elegido el que se usó al combinar.
+type ColisionCruce struct {
+	IDSucursal int         `json:"idSucursal"`
+	Clave      string      `json:"clave"`
+	Candidatos []StockData `json:"candidatos"`
+	Elegido    StockData   `json:"elegido"`
 }
@@ -0,0 +1,52 @@
+package models
+
+// Tipos de discrepancia del reporte de conciliación.
+const (
+	DiscrepanciaSoloMySQL     = "solo_mysql"     // zeta sin stock en SQL Server
+	DiscrepanciaSoloSQLServer = "solo_sqlserver" // zeta sin saldo en MySQL
+	DiscrepanciaCodigo        = "codigo"         // COD_ART distinto de CODIGO_INTERNO
+	DiscrepanciaAnio          = "anio"           // ANIO_PRO distinto de ANIO
+	DiscrepanciaCosto         = "costo"          // cos_uni distinto de COSTO_UNITARIO
+)
+
+// TiposDiscrepancia son los tipos de discrepancia en el orden del reporte.
+var TiposDiscrepancia = []string{
+	DiscrepanciaSoloMySQL, DiscrepanciaSoloSQLServer,
+	DiscrepanciaCodigo, DiscrepanciaAnio, DiscrepanciaCosto,
+}
+
+// Discrepancia es una diferencia entre el saldo de MySQL y el stock de SQL
+// Server de una zeta. Los campos del lado que no existe quedan vacíos.
+type Discrepancia struct {
+	Tipo            string  `json:"tipo"`
+	Zeta            string  `json:"zeta"`
+	IDSucursal      int     `json:"idSucursal,omitempty"`
+	NombreProducto  string  `json:"nombreProducto"`
+	CodigoMySQL     string  `json:"codigoMySQL,omitempty"`
+	CodigoSQLServer string  `json:"codigoSQLServer,omitempty"`
+	AnioMySQL       int     `json:"anioMySQL,omitempty"`
+	AnioSQLServer   int     `json:"anioSQLServer,omitempty"`
+	CostoMySQL      float64 `json:"costoMySQL,omitempty"`
+	CostoSQLServer  float64 `json:"costoSQLServer,omitempty"`
+	Diferencia      float64 `json:"diferencia,omitempty"` // costo SQL Server - costo MySQL
+}
+
+// ToleranciasConciliacion indican cuánto pueden diferir los datos de ambas
+// bases sin considerarse una discrepancia. Una diferencia de costo se reporta
+// solo si supera tanto la tolerancia absoluta como la porcentual.
+type ToleranciasConciliacion struct {
+	Costo    float64 `json:"costo"`    // diferencia absoluta de costo unitario
+	CostoPct float64 `json:"costoPct"` // diferencia porcentual sobre cos_uni
+	Anio     int     `json:"anio"`     // años de diferencia
+}
+
+// ReporteConciliacion lista las discrepancias entre saldos y stocks de un año
+// y unas sucursales, con la cantidad de discrepancias por tipo.
+type ReporteConciliacion struct {
+	Anio          string                  `json:"anio"`
+	Sucursales    []int                   `json:"sucursales"`
+	Tolerancias   ToleranciasConciliacion `json:"tolerancias"`
+	Resumen       map[string]int          `json:"resumen"`
+	Discrepancias []Discrepancia          `json:"discrepancias"`
+	DatosAl       DatosAl                 `json:"datosAl"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// DatosAl indica a qué momento corresponden los datos de cada origen. Con la
+// caché activa puede ser anterior a la petición.
+type DatosAl struct {
+	Stocks time.Time `json:"stocks"`
+	Saldos time.Time `json:"saldos"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// Detalle reúne la información de un producto o de una zeta: sus lotes por
+// zeta y año de producción (MySQL) y el historial completo de STOCKS
+// (SQL Server), del más reciente al más antiguo.
+type Detalle struct {
+	Tipo   string      `json:"tipo"` // "producto" o "zeta"
+	Clave  string      `json:"clave"`
+	Nombre string      `json:"nombre"`
+	Lotes  []Saldo     `json:"lotes"`
+	Stocks []StockData `json:"stocks"`
+}
@@ -0,0 +1,12 @@
+// Package models define los datos que leen los repositorios y los que
+// devuelven los endpoints.
+//
+// Nombres JSON: las filas de las tablas (Saldo, StockData, SaldoData y
+// CombinedData) conservan los nombres que ya publican /api/saldos y
+// /api/combined, para no romper a sus clientes, y aparecen tal cual donde se
+// listan filas (Detalle, ColisionCruce). Todo lo demás (los sobres de
+// paginación, DatosAl y los reportes de márgenes, valorización,
+// conciliación, antigüedad y series) usa camelCase con etiquetas explícitas.
+// Un reporte no incrusta filas de tabla: copia los campos que necesita a su
+// propio tipo, como OfertaBajoCosto.
+package models
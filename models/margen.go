@@ -0,0 +1,94 @@
+package models
+
+// margen devuelve el margen bruto absoluto, el margen sobre el precio (%) y
+// el markup sobre el costo (%). Los porcentajes son 0 si el divisor es 0.
+func margen(precio, costo float64) (absoluto, pct, markup float64) {
+	absoluto = precio - costo
+	if precio != 0 {
+		pct = absoluto / precio * 100
+	}
+	if costo != 0 {
+		markup = absoluto / costo * 100
+	}
+	return absoluto, pct, markup
+}
+
+// CalcularMargenes completa los campos de margen a partir de los precios de
+// SQL Server y los costos de MySQL.
+func (c *CombinedData) CalcularMargenes() {
+	c.MargenVentaReal, c.MargenPctVentaReal, c.MarkupVentaReal = margen(c.PrecioVenta, c.CostoReal)
+	c.MargenVentaCIF, c.MargenPctVentaCIF, c.MarkupVentaCIF = margen(c.PrecioVenta, c.CostoCIF)
+	c.MargenOfertaReal, c.MargenPctOfertaReal, c.MarkupOfertaReal = margen(c.PrecioOferta, c.CostoReal)
+	c.MargenOfertaCIF, c.MargenPctOfertaCIF, c.MarkupOfertaCIF = margen(c.PrecioOferta, c.CostoCIF)
+}
+
+// OfertaBajoCosto indica si el producto tiene precio de oferta y este es
+// menor que el costo real.
+func (c CombinedData) OfertaBajoCosto() bool {
+	return c.PrecioOferta > 0 && c.PrecioOferta < c.CostoReal
+}
+
+// PerdidaOferta devuelve la pérdida de vender todo el saldo a precio de
+// oferta cuando este es menor que el costo real; 0 en otro caso.
+func (c CombinedData) PerdidaOferta() float64 {
+	if !c.OfertaBajoCosto() {
+		return 0
+	}
+	return -c.MargenOfertaReal * c.SaldoAnterior
+}
+
+// OfertaBajoCosto es un producto del reporte de márgenes: los precios de SQL
+// Server, los costos de MySQL y los márgenes a precio de oferta.
+type OfertaBajoCosto struct {
+	IDSucursal          int     `json:"idSucursal"`
+	CodigoProducto      string  `json:"codigoProducto"`
+	Zeta                string  `json:"zeta"`
+	AnioProduccion      int     `json:"anioProduccion"`
+	NombreProducto      string  `json:"nombreProducto"`
+	PrecioVenta         float64 `json:"precioVenta"`
+	PrecioOferta        float64 `json:"precioOferta"`
+	CostoCIF            float64 `json:"costoCIF"`
+	CostoReal           float64 `json:"costoReal"`
+	SaldoAnterior       float64 `json:"saldoAnterior"`
+	MargenOfertaReal    float64 `json:"margenOfertaReal"`
+	MargenPctOfertaReal float64 `json:"margenPctOfertaReal"`
+	MarkupOfertaReal    float64 `json:"markupOfertaReal"`
+	MargenOfertaCIF     float64 `json:"margenOfertaCIF"`
+	MargenPctOfertaCIF  float64 `json:"margenPctOfertaCIF"`
+	MarkupOfertaCIF     float64 `json:"markupOfertaCIF"`
+	PerdidaOferta       float64 `json:"perdidaOferta"` // ver CombinedData.PerdidaOferta
+}
+
+// NewOfertaBajoCosto copia del dato combinado los campos del reporte de
+// márgenes. c debe tener los márgenes calculados.
+func NewOfertaBajoCosto(c CombinedData) OfertaBajoCosto {
+	return OfertaBajoCosto{
+		IDSucursal:          c.IDSucursal,
+		CodigoProducto:      c.CodigoProducto,
+		Zeta:                c.Zeta,
+		AnioProduccion:      c.AnioProduccion,
+		NombreProducto:      c.NombreProducto,
+		PrecioVenta:         c.PrecioVenta,
+		PrecioOferta:        c.PrecioOferta,
+		CostoCIF:            c.CostoCIF,
+		CostoReal:           c.CostoReal,
+		SaldoAnterior:       c.SaldoAnterior,
+		MargenOfertaReal:    c.MargenOfertaReal,
+		MargenPctOfertaReal: c.MargenPctOfertaReal,
+		MarkupOfertaReal:    c.MarkupOfertaReal,
+		MargenOfertaCIF:     c.MargenOfertaCIF,
+		MargenPctOfertaCIF:  c.MargenPctOfertaCIF,
+		MarkupOfertaCIF:     c.MarkupOfertaCIF,
+		PerdidaOferta:       c.PerdidaOferta(),
+	}
+}
+
+// ReporteMargenes lista los productos con precio de oferta bajo el costo
+// real en un año y unas sucursales.
+type ReporteMargenes struct {
+	Anio             string            `json:"anio"`
+	Sucursales       []int             `json:"sucursales"`
+	Productos        []OfertaBajoCosto `json:"productos"`
+	PerdidaPotencial float64           `json:"perdidaPotencial"` // suma de PerdidaOferta
+	DatosAl          DatosAl           `json:"datosAl"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func casi(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestMargen(t *testing.T) {
+	tests := []struct {
+		precio, costo         float64
+		absoluto, pct, markup float64
+	}{
+		{100, 80, 20, 20, 25},
+		{80, 100, -20, -25, -20},
+		{100, 0, 100, 100, 0}, // sin costo no hay markup
+		{0, 50, -50, 0, -100}, // sin precio no hay margen sobre el precio
+		{0, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		a, p, m := margen(tt.precio, tt.costo)
+		if !casi(a, tt.absoluto) || !casi(p, tt.pct) || !casi(m, tt.markup) {
+			t.Errorf("margen(%v, %v) = %v, %v, %v; se esperaba %v, %v, %v",
+				tt.precio, tt.costo, a, p, m, tt.absoluto, tt.pct, tt.markup)
+		}
+	}
+}
+
+func TestCalcularMargenes(t *testing.T) {
+	c := CombinedData{PrecioVenta: 200, PrecioOferta: 90, CostoReal: 100, CostoCIF: 80, SaldoAnterior: 10}
+	c.CalcularMargenes()
+	want := []struct {
+		nombre              string
+		a, p, m             float64
+		wantA, wantP, wantM float64
+	}{
+		{"venta real", c.MargenVentaReal, c.MargenPctVentaReal, c.MarkupVentaReal, 100, 50, 100},
+		{"venta CIF", c.MargenVentaCIF, c.MargenPctVentaCIF, c.MarkupVentaCIF, 120, 60, 150},
+		{"oferta real", c.MargenOfertaReal, c.MargenPctOfertaReal, c.MarkupOfertaReal, -10, -10 / 90.0 * 100, -10},
+		{"oferta CIF", c.MargenOfertaCIF, c.MargenPctOfertaCIF, c.MarkupOfertaCIF, 10, 10 / 90.0 * 100, 12.5},
+	}
+	for _, w := range want {
+		if !casi(w.a, w.wantA) || !casi(w.p, w.wantP) || !casi(w.m, w.wantM) {
+			t.Errorf("%s = %v, %v, %v; se esperaba %v, %v, %v", w.nombre, w.a, w.p, w.m, w.wantA, w.wantP, w.wantM)
+		}
+	}
+
+	if !c.OfertaBajoCosto() || !casi(c.PerdidaOferta(), 100) {
+		t.Errorf("oferta bajo costo = %v, pérdida = %v", c.OfertaBajoCosto(), c.PerdidaOferta())
+	}
+	o := NewOfertaBajoCosto(c)
+	if o.PerdidaOferta != c.PerdidaOferta() || o.MarkupOfertaCIF != c.MarkupOfertaCIF || o.SaldoAnterior != 10 {
+		t.Errorf("NewOfertaBajoCosto = %+v", o)
+	}
+}
+
+func TestPerdidaOfertaSinPerdida(t *testing.T) {
+	tests := map[string]CombinedData{
+		"sin oferta":            {PrecioOferta: 0, CostoReal: 100, SaldoAnterior: 5},
+		"oferta sobre costo":    {PrecioOferta: 120, CostoReal: 100, SaldoAnterior: 5},
+		"oferta igual al costo": {PrecioOferta: 100, CostoReal: 100, SaldoAnterior: 5},
+	}
+	for nombre, c := range tests {
+		c.CalcularMargenes()
+		if c.OfertaBajoCosto() || c.PerdidaOferta() != 0 {
+			t.Errorf("%s: oferta bajo costo = %v, pérdida = %v", nombre, c.OfertaBajoCosto(), c.PerdidaOferta())
+		}
+	}
+}
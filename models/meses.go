@@ -0,0 +1,35 @@
+package models
+
+// Meses son los nombres de los meses en el orden de las columnas de saldo de
+// fin de mes (FIN_ENE ... FIN_DIC).
+var Meses = [12]string{
+	"Enero", "Febrero", "Marzo", "Abril", "Mayo", "Junio",
+	"Julio", "Agosto", "Septiembre", "Octubre", "Noviembre", "Diciembre",
+}
+
+// SaldosMensuales devuelve los doce saldos de fin de mes en orden.
+func (s Saldo) SaldosMensuales() [12]float64 {
+	return [12]float64{
+		s.SaldoFinEnero, s.SaldoFinFebrero, s.SaldoFinMarzo, s.SaldoFinAbril,
+		s.SaldoFinMayo, s.SaldoFinJunio, s.SaldoFinJulio, s.SaldoFinAgosto,
+		s.SaldoFinSeptiembre, s.SaldoFinOctubre, s.SaldoFinNoviembre, s.SaldoFinDiciembre,
+	}
+}
+
+// SaldosMensuales devuelve los doce saldos de fin de mes en orden.
+func (s SaldoData) SaldosMensuales() [12]float64 {
+	return [12]float64{
+		s.SaldoFinEnero, s.SaldoFinFebrero, s.SaldoFinMarzo, s.SaldoFinAbril,
+		s.SaldoFinMayo, s.SaldoFinJunio, s.SaldoFinJulio, s.SaldoFinAgosto,
+		s.SaldoFinSeptiembre, s.SaldoFinOctubre, s.SaldoFinNoviembre, s.SaldoFinDiciembre,
+	}
+}
+
+// SaldosMensuales devuelve los doce saldos de fin de mes en orden.
+func (c CombinedData) SaldosMensuales() [12]float64 {
+	return [12]float64{
+		c.SaldoFinEnero, c.SaldoFinFebrero, c.SaldoFinMarzo, c.SaldoFinAbril,
+		c.SaldoFinMayo, c.SaldoFinJunio, c.SaldoFinJulio, c.SaldoFinAgosto,
+		c.SaldoFinSeptiembre, c.SaldoFinOctubre, c.SaldoFinNoviembre, c.SaldoFinDiciembre,
+	}
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// ResumenSaldos agrupa las cifras globales de la tabla saldos.
+type ResumenSaldos struct {
+	TotalRegistros int       // filas de la tabla saldos
+	UltimoIngreso  time.Time // MAX(FEC_ING)
+	Valorizacion   float64   // suma de saldo por costo real
+}
@@ -0,0 +1,26 @@
+package models
+
+// PuntoSerie es el saldo de fin de mes de un mes del lote.
+type PuntoSerie struct {
+	Mes       int     `json:"mes"`
+	NombreMes string  `json:"nombreMes"`
+	Saldo     float64 `json:"saldo"`
+	Variacion float64 `json:"variacion"` // diferencia con el mes anterior del mismo lote
+}
+
+// LoteSerie es la evolución mensual del saldo de un lote. ANIO_PRO es el año
+// de producción del lote, no el año calendario de los cierres FIN_ENE..FIN_DIC,
+// así que cada lote es una serie aparte y no se encadena con el siguiente.
+type LoteSerie struct {
+	AnioProduccion int          `json:"anioProduccion"`
+	Puntos         []PuntoSerie `json:"puntos"`
+}
+
+// Serie es la evolución mensual del saldo de un producto o de una zeta, con
+// una serie por año de producción.
+type Serie struct {
+	Tipo   string      `json:"tipo"` // "producto" o "zeta"
+	Clave  string      `json:"clave"`
+	Nombre string      `json:"nombre"`
+	Lotes  []LoteSerie `json:"lotes"`
+}
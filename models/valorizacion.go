@@ -0,0 +1,37 @@
+package models
+
+// ValorMes es la valorización del saldo de fin de un mes a costo CIF y a
+// costo real, con la variación respecto del mes anterior.
+type ValorMes struct {
+	Mes           int     `json:"mes"`
+	NombreMes     string  `json:"nombreMes"`
+	Saldo         float64 `json:"saldo"`
+	ValorCIF      float64 `json:"valorCIF"`
+	ValorReal     float64 `json:"valorReal"`
+	VariacionCIF  float64 `json:"variacionCIF"`  // diferencia con el mes anterior
+	VariacionReal float64 `json:"variacionReal"` // diferencia con el mes anterior
+}
+
+// ValorizacionProducto es la valorización mensual de un producto en un año de
+// producción, sumando todas sus zetas.
+type ValorizacionProducto struct {
+	CodigoProducto    string       `json:"codigoProducto"`
+	NombreProducto    string       `json:"nombreProducto"`
+	CantidadIngresada float64      `json:"cantidadIngresada"`
+	Meses             [12]ValorMes `json:"meses"`
+}
+
+// ValorizacionAnio agrupa los productos de un año de producción con el total
+// mensual del año.
+type ValorizacionAnio struct {
+	Anio      int                    `json:"anio"`
+	Productos []ValorizacionProducto `json:"productos"`
+	Total     [12]ValorMes           `json:"total"`
+}
+
+// ReporteValorizacion es la valorización del inventario por año de
+// producción y producto, con el total mensual de todos los años.
+type ReporteValorizacion struct {
+	Anios []ValorizacionAnio `json:"anios"`
+	Total [12]ValorMes       `json:"total"`
+}
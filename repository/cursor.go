@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"go_api/models"
+)
+
+// SaldoCursor identifica el último saldo entregado de un listado para pedir
+// los siguientes por clave (keyset) en lugar de por OFFSET. Guarda los
+// valores del saldo para cada campo del orden completo: los pedidos y
+// COD_ART, ZET_ART y ANIO_PRO, que desempatan. La tabla puede repetir esa
+// clave en varias filas, por lo que el listado por cursor recorre los saldos
+// agrupados por ella, donde sí es única.
+type SaldoCursor struct {
+	Valores []interface{} `json:"v"`
+}
+
+// formatoFechaCursor representa las fechas del cursor de modo que MySQL las
+// compare como fechas y en memoria se ordenen como texto.
+const formatoFechaCursor = "2006-01-02 15:04:05"
+
+// CursorDeSaldo arma el cursor que apunta a s en el orden indicado.
+func CursorDeSaldo(s models.Saldo, o Orden) SaldoCursor {
+	completo := o.completo()
+	c := SaldoCursor{Valores: make([]interface{}, len(completo))}
+	for i, campo := range completo {
+		c.Valores[i] = valorCursor(campo.Campo, s)
+	}
+	return c
+}
+
+// Valido indica si el cursor corresponde al orden o: un valor por campo del
+// orden completo, cada uno del tipo del campo (número, texto o fecha con
+// formatoFechaCursor), antes de usarlos en la consulta.
+func (c SaldoCursor) Valido(o Orden) bool {
+	completo := o.completo()
+	if len(c.Valores) != len(completo) {
+		return false
+	}
+	for i, campo := range completo {
+		esperado := valorCursor(campo.Campo, models.Saldo{})
+		if _, ok := numero(esperado); ok {
+			if x, ok := numero(c.Valores[i]); !ok || math.IsNaN(x) || math.IsInf(x, 0) {
+				return false
+			}
+			continue
+		}
+		texto, ok := c.Valores[i].(string)
+		if !ok {
+			return false
+		}
+		if _, esFecha := camposSaldo[campo.Campo].valor(models.Saldo{}).(time.Time); esFecha {
+			if _, err := time.Parse(formatoFechaCursor, texto); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// valorCursor devuelve el valor del campo en s tal como se guarda en el
+// cursor. Las fechas vacías equivalen a la mínima de MySQL, como en
+// camposSaldo.
+func valorCursor(campo string, s models.Saldo) interface{} {
+	v := camposSaldo[campo].valor(s)
+	if t, ok := v.(time.Time); ok {
+		if t.IsZero() {
+			return "1000-01-01 00:00:00"
+		}
+		return t.Format(formatoFechaCursor)
+	}
+	return v
+}
+
+// having construye la condición parametrizada de los grupos posteriores al
+// cursor en el orden o, sobre las expresiones agregadas. La comparación de
+// tuplas se expande en disyunciones, que admiten una dirección por campo.
+func (c SaldoCursor) having(o Orden) (string, []interface{}) {
+	completo := o.completo()
+	var disyunciones []string
+	var args []interface{}
+	for i, campo := range completo {
+		var conds []string
+		for j, anterior := range completo[:i] {
+			conds = append(conds, o.expresion(anterior.Campo, true)+" = ?")
+			args = append(args, c.Valores[j])
+		}
+		op := " > ?"
+		if campo.Desc {
+			op = " < ?"
+		}
+		conds = append(conds, o.expresion(campo.Campo, true)+op)
+		args = append(args, c.Valores[i])
+		disyunciones = append(disyunciones, "("+strings.Join(conds, " AND ")+")")
+	}
+	return "(" + strings.Join(disyunciones, " OR ") + ")", args
+}
+
+// despues indica si s va después del cursor en el orden o, con la misma
+// semántica que having.
+func (c SaldoCursor) despues(s models.Saldo, o Orden) bool {
+	for i, campo := range o.completo() {
+		if cmp := CompararValores(valorCursor(campo.Campo, s), c.Valores[i]); cmp != 0 {
+			return (cmp > 0) != campo.Desc
+		}
+	}
+	return false
+}
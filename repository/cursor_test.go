@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"go_api/models"
+)
+
+// saldosCursor son saldos con empates en los campos de orden y dos filas con
+// la misma clave (código, zeta y año), que se recorren como un solo saldo.
+func saldosCursor() []models.Saldo {
+	var rows []models.Saldo
+	for i := 0; i < 7; i++ {
+		rows = append(rows, models.Saldo{
+			CodigoProducto:    fmt.Sprintf("P-%02d", i%4),
+			Zeta:              fmt.Sprintf("Z%02d", i),
+			AnioProduccion:    2024 + i%2,
+			NombreProducto:    fmt.Sprintf("PRODUCTO %d", i%3),
+			CostoReal:         float64(100 * (i % 3)),
+			CantidadIngresada: 10,
+			FechaIngreso:      time.Date(2025, time.Month(1+i%2), 1, 0, 0, 0, 0, time.UTC),
+		})
+	}
+	repetida := rows[2]
+	repetida.CantidadIngresada = 5
+	return append(rows, repetida)
+}
+
+func TestCursorJSON(t *testing.T) {
+	s := saldosCursor()[1]
+	for _, spec := range []string{"", "-CostoReal", "FechaIngreso,-NombreProducto", "CantidadIngresada"} {
+		orden, err := ParseOrden(spec, "", EsCampoSaldo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(CursorDeSaldo(s, orden))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c SaldoCursor
+		if err := json.Unmarshal(b, &c); err != nil {
+			t.Fatal(err)
+		}
+		if !c.Valido(orden) {
+			t.Errorf("orden %q: el cursor decodificado %s no es válido", spec, b)
+		}
+		if c.despues(s, orden) {
+			t.Errorf("orden %q: el saldo del cursor no debería ir después de él", spec)
+		}
+	}
+}
+
+func TestCursorValido(t *testing.T) {
+	orden := Orden{{Campo: "FechaIngreso"}, {Campo: "CostoReal", Desc: true}}
+	tests := []struct {
+		nombre  string
+		valores []interface{}
+		want    bool
+	}{
+		{"correcto", []interface{}{"2025-01-01 00:00:00", 100.0, "P-01", "Z01", 2025.0}, true},
+		{"fecha vacía", []interface{}{"1000-01-01 00:00:00", 0.0, "P-01", "Z01", 2025.0}, true},
+		{"faltan valores", []interface{}{"2025-01-01 00:00:00", 100.0, "P-01", "Z01"}, false},
+		{"sobran valores", []interface{}{"2025-01-01 00:00:00", 100.0, "P-01", "Z01", 2025.0, 1.0}, false},
+		{"fecha inválida", []interface{}{"ayer", 100.0, "P-01", "Z01", 2025.0}, false},
+		{"fecha como número", []interface{}{20250101.0, 100.0, "P-01", "Z01", 2025.0}, false},
+		{"número como texto", []interface{}{"2025-01-01 00:00:00", "100", "P-01", "Z01", 2025.0}, false},
+		{"texto como número", []interface{}{"2025-01-01 00:00:00", 100.0, 1.0, "Z01", 2025.0}, false},
+		{"nulo", []interface{}{"2025-01-01 00:00:00", nil, "P-01", "Z01", 2025.0}, false},
+		{"objeto", []interface{}{"2025-01-01 00:00:00", map[string]interface{}{}, "P-01", "Z01", 2025.0}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nombre, func(t *testing.T) {
+			if got := (SaldoCursor{Valores: tt.valores}).Valido(orden); got != tt.want {
+				t.Errorf("Valido(%v) = %v, se esperaba %v", tt.valores, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorHaving(t *testing.T) {
+	orden := Orden{{Campo: "CantidadIngresada", Desc: true}}
+	c := SaldoCursor{Valores: []interface{}{20.0, "P-01", "Z01", 2025.0}}
+	cond, args := c.having(orden)
+	want := "((SUM(CAN_ING) < ?) OR (SUM(CAN_ING) = ? AND COD_ART < ?) OR " +
+		"(SUM(CAN_ING) = ? AND COD_ART = ? AND ZET_ART < ?) OR " +
+		"(SUM(CAN_ING) = ? AND COD_ART = ? AND ZET_ART = ? AND ANIO_PRO < ?))"
+	if cond != want {
+		t.Errorf("having = %q, se esperaba %q", cond, want)
+	}
+	wantArgs := []interface{}{20.0, 20.0, "P-01", 20.0, "P-01", "Z01", 20.0, "P-01", "Z01", 2025.0}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %v, se esperaba %v", args, wantArgs)
+	}
+}
+
+// TestListAfterRecorrido recorre el listado por cursor hacia adelante y
+// hacia atrás, como lo hace el controlador, y compara con Count.
+func TestListAfterRecorrido(t *testing.T) {
+	repo := NewMemorySaldoRepository(saldosCursor())
+	ctx := context.Background()
+	total, err := repo.Count(ctx, SaldoFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 7 {
+		t.Fatalf("Count = %d, se esperaban 7 saldos agrupados", total)
+	}
+
+	for _, spec := range []string{"", "-CostoReal", "FechaIngreso,-NombreProducto", "CantidadIngresada", "-AnioProduccion,Zeta"} {
+		t.Run(spec, func(t *testing.T) {
+			orden, err := ParseOrden(spec, "", EsCampoSaldo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(orden) == 0 {
+				// Como el controlador: el orden vacío no se puede invertir
+				orden = Orden{{Campo: "CodigoProducto"}}
+			}
+			const porPagina = 2
+			clave := func(s models.Saldo) string {
+				return s.CodigoProducto + "|" + s.Zeta + "|" + fmt.Sprint(s.AnioProduccion)
+			}
+
+			// Hacia adelante
+			var adelante []string
+			var paginas [][]models.Saldo
+			var cursor *SaldoCursor
+			for {
+				pagina, err := repo.ListAfter(ctx, cursor, porPagina, SaldoFilter{}, orden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(pagina) == 0 {
+					break
+				}
+				paginas = append(paginas, pagina)
+				for _, s := range pagina {
+					adelante = append(adelante, clave(s))
+				}
+				c := CursorDeSaldo(pagina[len(pagina)-1], orden)
+				cursor = &c
+			}
+			if len(adelante) != total {
+				t.Fatalf("recorrido = %v, se esperaban %d saldos", adelante, total)
+			}
+			vistos := make(map[string]bool)
+			for _, k := range adelante {
+				if vistos[k] {
+					t.Fatalf("saldo repetido %s en %v", k, adelante)
+				}
+				vistos[k] = true
+			}
+			for _, p := range paginas {
+				for _, s := range p {
+					if s.Zeta == "Z02" && s.CantidadIngresada != 15 {
+						t.Errorf("el saldo de la clave repetida suma %v, se esperaba 15", s.CantidadIngresada)
+					}
+				}
+			}
+
+			// Hacia atrás desde la primera fila de la última página
+			var atras []string
+			c := CursorDeSaldo(paginas[len(paginas)-1][0], orden)
+			for cursor := &c; ; {
+				pagina, err := repo.ListAfter(ctx, cursor, porPagina, SaldoFilter{}, orden.Invertir())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(pagina) == 0 {
+					break
+				}
+				for _, s := range pagina {
+					atras = append([]string{clave(s)}, atras...)
+				}
+				c := CursorDeSaldo(pagina[len(pagina)-1], orden)
+				cursor = &c
+			}
+			ultima := len(paginas[len(paginas)-1])
+			if !reflect.DeepEqual(atras, adelante[:len(adelante)-ultima]) {
+				t.Errorf("hacia atrás = %v, se esperaba %v", atras, adelante[:len(adelante)-ultima])
+			}
+		})
+	}
+}
+
+func TestListAfterLimiteGrande(t *testing.T) {
+	repo := NewMemorySaldoRepository(saldosCursor())
+	orden := Orden{{Campo: "CodigoProducto"}}
+	for _, limit := range []int{math.MaxInt, math.MaxInt - 1, 0} {
+		saldos, err := repo.ListAfter(context.Background(), nil, limit, SaldoFilter{}, orden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 7
+		if limit == 0 {
+			want = 0
+		}
+		if len(saldos) != want {
+			t.Errorf("ListAfter(limit=%d) = %d saldos, se esperaban %d", limit, len(saldos), want)
+		}
+	}
+}
+
+func TestPaginarSinDesborde(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		offset, limit int
+		want          []int
+	}{
+		{0, 2, []int{1, 2}},
+		{3, 10, []int{4, 5}},
+		{2, -1, []int{3, 4, 5}},
+		{1, math.MaxInt, []int{2, 3, 4, 5}},
+		{-3, 1, []int{1}},
+		{5, 1, []int{}},
+		{math.MaxInt, math.MaxInt, []int{}},
+	}
+	for _, tt := range tests {
+		if got := paginar(items, tt.offset, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("paginar(%d, %d) = %v, se esperaba %v", tt.offset, tt.limit, got, tt.want)
+		}
+	}
+}
+
+// TestListadosAgrupadosIguales comprueba que ListPaginated, Each y ListAfter
+// recorren los mismos saldos agrupados en el mismo orden.
+func TestListadosAgrupadosIguales(t *testing.T) {
+	repo := NewMemorySaldoRepository(saldosCursor())
+	ctx := context.Background()
+	for _, spec := range []string{"CodigoProducto", "-CantidadIngresada", "FechaIngreso,-NombreProducto"} {
+		orden, err := ParseOrden(spec, "", EsCampoSaldo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		porCursor, err := repo.ListAfter(ctx, nil, 100, SaldoFilter{}, orden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var porPagina []models.Saldo
+		for offset := 0; ; offset += 3 {
+			pagina, total, err := repo.ListPaginated(ctx, offset, 3, SaldoFilter{}, orden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 7 {
+				t.Fatalf("%s: total = %d, se esperaban 7 grupos", spec, total)
+			}
+			if len(pagina) == 0 {
+				break
+			}
+			porPagina = append(porPagina, pagina...)
+		}
+		var recorridos []models.Saldo
+		if err := repo.Each(ctx, SaldoFilter{}, orden, func(s models.Saldo) error {
+			recorridos = append(recorridos, s)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(porPagina, porCursor) || !reflect.DeepEqual(recorridos, porCursor) {
+			t.Errorf("%s: los listados difieren:\npágina %v\nEach   %v\ncursor %v", spec, porPagina, recorridos, porCursor)
+		}
+	}
+}
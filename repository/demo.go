@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"go_api/models"
+)
+
+// DemoData genera un conjunto de saldos y stocks de ejemplo para el modo demo.
+// Incluye zetas sin correspondencia en SQL Server y varias filas de stock por
+// zeta para que todas las vistas tengan algo que mostrar.
+func DemoData() ([]models.Saldo, []models.StockData) {
+	productos := []struct {
+		codigo, nombre string
+		unidadCaja     float64
+		costoCIF       float64
+		costoReal      float64
+		precioVenta    float64
+		precioOferta   float64
+	}{
+		{"VT-001", "VINO TINTO CABERNET 750CC", 12, 1450, 1720, 3990, 3490},
+		{"VT-002", "VINO TINTO MERLOT 750CC", 12, 1320, 1580, 3490, 2990},
+		{"VB-001", "VINO BLANCO SAUVIGNON 750CC", 12, 1210, 1460, 3290, 1390},
+		{"ES-001", "ESPUMANTE BRUT 750CC", 6, 2350, 2810, 5990, 5490},
+		{"PI-001", "PISCO RESERVADO 35G 1L", 12, 2100, 2470, 4990, 4590},
+		{"WH-001", "WHISKY BLENDED 12 AÑOS 750CC", 6, 8900, 10350, 18990, 16990},
+		{"CE-001", "CERVEZA LAGER LATA 470CC", 24, 310, 385, 890, 790},
+		{"RO-001", "RON AÑEJO 1L", 12, 3100, 3640, 6990, 6490},
+	}
+
+	var saldos []models.Saldo
+	var stocks []models.StockData
+	for _, anio := range []int{2024, 2025} {
+		for i, p := range productos {
+			zeta := fmt.Sprintf("Z%d%03d", anio%100, i+1)
+			ingreso := time.Date(anio, time.Month(1+i%6), 5+i, 0, 0, 0, 0, time.UTC)
+			cantidad := float64(120 * (i + 1))
+			saldo := cantidad
+			s := models.Saldo{
+				CodigoProducto:    p.codigo,
+				Zeta:              zeta,
+				AnioProduccion:    anio,
+				NombreProducto:    p.nombre,
+				UnidadCaja:        p.unidadCaja,
+				CostoCIF:          p.costoCIF,
+				CostoReal:         p.costoReal,
+				FechaIngreso:      ingreso,
+				CantidadIngresada: cantidad,
+				SaldoAnterior:     float64(10 * i),
+			}
+			meses := []*float64{
+				&s.SaldoFinEnero, &s.SaldoFinFebrero, &s.SaldoFinMarzo, &s.SaldoFinAbril,
+				&s.SaldoFinMayo, &s.SaldoFinJunio, &s.SaldoFinJulio, &s.SaldoFinAgosto,
+				&s.SaldoFinSeptiembre, &s.SaldoFinOctubre, &s.SaldoFinNoviembre, &s.SaldoFinDiciembre,
+			}
+			for m, ptr := range meses {
+				if m+1 < int(ingreso.Month()) {
+					continue
+				}
+				*ptr = saldo
+				saldo -= math.Floor(cantidad / float64(14+i))
+				if saldo < 0 {
+					saldo = 0
+				}
+			}
+			saldos = append(saldos, s)
+
+			// Las dos últimas zetas de cada año no existen en SQL Server.
+			if i >= len(productos)-2 {
+				continue
+			}
+			for k := 0; k < 2; k++ {
+				stocks = append(stocks, models.StockData{
+					IDSucursal:     SucursalPorDefecto,
+					NombreProducto: p.nombre,
+					CodigoProducto: p.codigo,
+					Zeta:           zeta,
+					Fecha:          ingreso.AddDate(0, k, 0),
+					PrecioVenta:    p.precioVenta,
+					PrecioOferta:   p.precioOferta,
+					CostoUnitario:  p.costoReal + float64(k*10),
+					Anio:           anio,
+				})
+			}
+			stocks = append(stocks, models.StockData{
+				IDSucursal:     SucursalPorDefecto + 1,
+				NombreProducto: p.nombre,
+				CodigoProducto: p.codigo,
+				Zeta:           zeta,
+				Fecha:          ingreso,
+				PrecioVenta:    p.precioVenta,
+				PrecioOferta:   p.precioOferta,
+				CostoUnitario:  p.costoReal,
+				Anio:           anio,
+			})
+		}
+	}
+
+	// Una zeta que solo existe en SQL Server, para la conciliación.
+	p := productos[0]
+	stocks = append(stocks, models.StockData{
+		IDSucursal:     SucursalPorDefecto,
+		NombreProducto: p.nombre,
+		CodigoProducto: p.codigo,
+		Zeta:           "Z25900",
+		Fecha:          time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC),
+		PrecioVenta:    p.precioVenta,
+		PrecioOferta:   p.precioOferta,
+		CostoUnitario:  p.costoReal,
+		Anio:           2025,
+	})
+	return saldos, stocks
+}
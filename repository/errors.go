@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Nombres de los orígenes de datos usados en los errores y en los logs.
+const (
+	BackendMySQL     = "MySQL"
+	BackendSQLServer = "SQL Server"
+)
+
+// QueryError identifica la consulta y el origen de datos que fallaron.
+type QueryError struct {
+	Backend string
+	Query   string
+	Err     error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s: consulta %s: %v", e.Backend, e.Query, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// queryError envuelve err en un QueryError. Si el contexto fue cancelado o
+// venció su plazo se devuelve el error del contexto (el driver suele devolver
+// uno propio) y se registra qué consulta se interrumpió.
+func queryError(ctx context.Context, backend, query string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		log.Printf("Consulta %s en %s cancelada: %v", query, backend, ctxErr)
+		err = ctxErr
+	}
+	return &QueryError{Backend: backend, Query: query, Err: err}
+}
+
+// withTimeout aplica el plazo configurado para el origen de datos; un plazo
+// cero deja el contexto sin cambios.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
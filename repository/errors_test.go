@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueryError(t *testing.T) {
+	if queryError(context.Background(), BackendMySQL, "q", nil) != nil {
+		t.Error("sin error no debería envolver nada")
+	}
+
+	base := errors.New("fallo del driver")
+	err := queryError(context.Background(), BackendSQLServer, "stocks", base)
+	var qe *QueryError
+	if !errors.As(err, &qe) || qe.Backend != BackendSQLServer || qe.Query != "stocks" || !errors.Is(err, base) {
+		t.Errorf("queryError = %#v", err)
+	}
+
+	// Con el contexto cancelado se informa el error del contexto, no el del driver
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := queryError(ctx, BackendMySQL, "q", base); !errors.Is(err, context.Canceled) || errors.Is(err, base) {
+		t.Errorf("con contexto cancelado = %v", err)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := withTimeout(context.Background(), 0)
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("un plazo cero no debería fijar deadline")
+	}
+	ctx, cancel = withTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if d, ok := ctx.Deadline(); !ok || time.Until(d) > time.Minute {
+		t.Errorf("deadline = %v, %v", d, ok)
+	}
+}
+
+// TestMySQLPlazoVencido comprueba que una consulta con el plazo vencido
+// devuelve un QueryError de MySQL que envuelve DeadlineExceeded.
+func TestMySQLPlazoVencido(t *testing.T) {
+	d := &driverGuionado{}
+	repo := NewMySQLSaldoRepository(abrirGuionado(t, d), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	_, err := repo.Count(context.Background(), SaldoFilter{})
+	var qe *QueryError
+	if !errors.As(err, &qe) || qe.Backend != BackendMySQL || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Count = %v; se esperaba un QueryError de MySQL por plazo vencido", err)
+	}
+	if len(d.consultas) != 0 {
+		t.Errorf("la consulta llegó al driver: %q", d.consultas)
+	}
+}
@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go_api/models"
+)
+
+// TipoCampo indica cómo se interpretan y comparan los valores de un campo
+// en las expresiones de filtro.
+type TipoCampo int
+
+const (
+	CampoTexto TipoCampo = iota
+	CampoNumero
+	CampoFecha
+)
+
+// CampoFiltro es un campo que se puede usar en una expresión de filtro: el
+// nombre del campo (el mismo que en el orden) y su tipo.
+type CampoFiltro struct {
+	Campo string
+	Tipo  TipoCampo
+}
+
+// CamposFiltroSaldo son los campos de saldos de las expresiones de filtro,
+// por el nombre que escribe el usuario.
+var CamposFiltroSaldo = map[string]CampoFiltro{
+	"codigo":        {"CodigoProducto", CampoTexto},
+	"zeta":          {"Zeta", CampoTexto},
+	"anio":          {"AnioProduccion", CampoNumero},
+	"nombre":        {"NombreProducto", CampoTexto},
+	"unidad_caja":   {"UnidadCaja", CampoNumero},
+	"costo_cif":     {"CostoCIF", CampoNumero},
+	"costo_real":    {"CostoReal", CampoNumero},
+	"fecha_ingreso": {"FechaIngreso", CampoFecha},
+	"cantidad":      {"CantidadIngresada", CampoNumero},
+	"saldo":         {"SaldoAnterior", CampoNumero},
+	"dias":          {"DiasDesdeIngreso", CampoNumero},
+}
+
+func init() {
+	for _, mes := range models.Meses {
+		CamposFiltroSaldo["saldo_"+strings.ToLower(mes)] = CampoFiltro{"SaldoFin" + mes, CampoNumero}
+	}
+}
+
+// CondicionFiltro es una comparación "campo op valor" de una expresión de
+// filtro, con el valor ya interpretado según el tipo del campo.
+type CondicionFiltro struct {
+	CampoFiltro
+	Op     string // ":", "=", "!=", ">", ">=", "<" o "<="
+	Texto  string
+	Numero float64
+	Fecha  time.Time
+}
+
+// ExpresionFiltro es el resultado de interpretar el cuadro de búsqueda: cada
+// palabra suelta (o frase entre comillas) debe aparecer en nombre, código o
+// zeta, y las comparaciones deben cumplirse todas.
+type ExpresionFiltro struct {
+	Palabras    []string
+	Condiciones []CondicionFiltro
+}
+
+// ErrorFiltro es un error de sintaxis en una expresión de filtro. Pos es la
+// posición (desde 1, en caracteres) donde empieza el término con el error.
+type ErrorFiltro struct {
+	Pos int
+	Msg string
+}
+
+func (e *ErrorFiltro) Error() string {
+	return fmt.Sprintf("filtro inválido en la posición %d: %s", e.Pos, e.Msg)
+}
+
+// columnasFecha son las columnas de los campos de fecha sin el COALESCE que
+// usa el orden.
+var columnasFecha = map[string]string{"FechaIngreso": "FEC_ING"}
+
+// operadoresFiltro en el orden en que se reconocen: los de dos caracteres
+// antes que sus prefijos.
+var operadoresFiltro = []string{">=", "<=", "!=", ":", "=", ">", "<"}
+
+// ParseFiltro interpreta una expresión como
+//
+//	anio:2024 saldo>0 costo_real<=1500 fecha_ingreso>=2025-01-01 nombre:"vino tinto"
+//
+// Cada término es "campo op valor" o una palabra suelta; los valores y las
+// palabras con espacios van entre comillas. En los textos ":" busca el valor
+// contenido y "=" la igualdad; en números y fechas ":" equivale a "=". Las
+// fechas se escriben AAAA-MM-DD y se comparan por día; las filas sin fecha no
+// cumplen ninguna condición sobre ella. Un término con un campo que no está en
+// campos se busca como palabra suelta.
+func ParseFiltro(expr string, campos map[string]CampoFiltro) (ExpresionFiltro, error) {
+	var resultado ExpresionFiltro
+	runas := []rune(expr)
+	for i := 0; i < len(runas); {
+		if unicode.IsSpace(runas[i]) {
+			i++
+			continue
+		}
+		inicio := i
+		var termino strings.Builder
+		comillas := false
+		for ; i < len(runas) && (comillas || !unicode.IsSpace(runas[i])); i++ {
+			if runas[i] == '"' {
+				comillas = !comillas
+			}
+			termino.WriteRune(runas[i])
+		}
+		if comillas {
+			return resultado, &ErrorFiltro{inicio + 1, "falta cerrar las comillas"}
+		}
+
+		cond, esCondicion, err := parseCondicion(termino.String(), campos)
+		if err != nil {
+			return resultado, &ErrorFiltro{inicio + 1, err.Error()}
+		}
+		if esCondicion {
+			resultado.Condiciones = append(resultado.Condiciones, cond)
+		} else if p := strings.ReplaceAll(termino.String(), `"`, ""); p != "" {
+			resultado.Palabras = append(resultado.Palabras, p)
+		}
+	}
+	return resultado, nil
+}
+
+// parseCondicion interpreta un término. Si no empieza con un nombre de campo
+// de campos seguido de un operador es una palabra suelta y devuelve false.
+func parseCondicion(termino string, campos map[string]CampoFiltro) (CondicionFiltro, bool, error) {
+	var cond CondicionFiltro
+	runas := []rune(termino)
+	n := 0
+	for n < len(runas) && (runas[n] == '_' || unicode.IsLetter(runas[n])) {
+		n++
+	}
+	nombre := strings.ToLower(string(runas[:n]))
+	campo, ok := campos[nombre]
+	if !ok {
+		return cond, false, nil
+	}
+	resto := string(runas[n:])
+	for _, op := range operadoresFiltro {
+		if strings.HasPrefix(resto, op) {
+			cond.Op = op
+			break
+		}
+	}
+	if cond.Op == "" {
+		return cond, false, nil
+	}
+	cond.CampoFiltro = campo
+
+	valor := resto[len(cond.Op):]
+	if len(valor) >= 2 && valor[0] == '"' && valor[len(valor)-1] == '"' {
+		valor = valor[1 : len(valor)-1]
+	} else if strings.Contains(valor, `"`) {
+		return cond, false, fmt.Errorf("comillas mal ubicadas en el valor de %s", nombre)
+	}
+	if valor == "" {
+		return cond, false, fmt.Errorf("falta el valor después de %s%s", nombre, cond.Op)
+	}
+
+	switch campo.Tipo {
+	case CampoTexto:
+		if cond.Op != ":" && cond.Op != "=" && cond.Op != "!=" {
+			return cond, false, fmt.Errorf("el operador %s no se aplica a %s (use :, = o !=)", cond.Op, nombre)
+		}
+		cond.Texto = valor
+	case CampoNumero:
+		v, err := strconv.ParseFloat(strings.Replace(valor, ",", ".", 1), 64)
+		if err != nil {
+			return cond, false, fmt.Errorf("%s espera un número: %q", nombre, valor)
+		}
+		cond.Numero = v
+	case CampoFecha:
+		v, err := time.Parse("2006-01-02", valor)
+		if err != nil {
+			return cond, false, fmt.Errorf("%s espera una fecha AAAA-MM-DD: %q", nombre, valor)
+		}
+		cond.Fecha = v
+	}
+	return cond, true, nil
+}
+
+// ContienePalabras indica si cada palabra aparece en alguno de los textos,
+// sin distinguir mayúsculas, como las condiciones LIKE de SaldoFilter.
+func ContienePalabras(palabras []string, textos ...string) bool {
+	for _, p := range palabras {
+		p = strings.ToLower(p)
+		encontrada := false
+		for _, t := range textos {
+			if strings.Contains(strings.ToLower(t), p) {
+				encontrada = true
+				break
+			}
+		}
+		if !encontrada {
+			return false
+		}
+	}
+	return true
+}
+
+// sql arma la condición parametrizada sobre la columna del campo en la
+// tabla saldos. Las fechas se comparan por día (FEC_ING puede incluir hora)
+// sobre la columna sin COALESCE, para que las filas sin fecha no cumplan la
+// condición, igual que en Cumple.
+func (c CondicionFiltro) sql() (string, []interface{}) {
+	col := camposSaldo[c.Campo].columna
+	switch c.Tipo {
+	case CampoTexto:
+		switch c.Op {
+		case ":":
+			return col + " LIKE ?", []interface{}{"%" + c.Texto + "%"}
+		case "!=":
+			return col + " <> ?", []interface{}{c.Texto}
+		}
+		return col + " = ?", []interface{}{c.Texto}
+	case CampoFecha:
+		col = columnasFecha[c.Campo]
+		dia, siguiente := c.Fecha.Format("2006-01-02"), c.Fecha.AddDate(0, 0, 1).Format("2006-01-02")
+		switch c.Op {
+		case ">":
+			return col + " >= ?", []interface{}{siguiente}
+		case ">=":
+			return col + " >= ?", []interface{}{dia}
+		case "<":
+			return col + " < ?", []interface{}{dia}
+		case "<=":
+			return col + " < ?", []interface{}{siguiente}
+		case "!=":
+			return "(" + col + " < ? OR " + col + " >= ?)", []interface{}{dia, siguiente}
+		}
+		return "(" + col + " >= ? AND " + col + " < ?)", []interface{}{dia, siguiente}
+	}
+	op := c.Op
+	switch op {
+	case ":":
+		op = "="
+	case "!=":
+		op = "<>"
+	}
+	return col + " " + op + " ?", []interface{}{c.Numero}
+}
+
+// Cumple indica si el valor del campo (como lo devuelven ValorCampoSaldo o
+// los campos combinados) cumple la condición, con la misma semántica que la
+// consulta SQL.
+func (c CondicionFiltro) Cumple(v interface{}) bool {
+	switch c.Tipo {
+	case CampoTexto:
+		s, _ := v.(string)
+		switch c.Op {
+		case ":":
+			return strings.Contains(strings.ToLower(s), strings.ToLower(c.Texto))
+		case "!=":
+			return !strings.EqualFold(s, c.Texto)
+		}
+		return strings.EqualFold(s, c.Texto)
+	case CampoFecha:
+		t, _ := v.(time.Time)
+		if t.IsZero() {
+			return false
+		}
+		dia, siguiente := c.Fecha, c.Fecha.AddDate(0, 0, 1)
+		switch c.Op {
+		case ">":
+			return !t.Before(siguiente)
+		case ">=":
+			return !t.Before(dia)
+		case "<":
+			return t.Before(dia)
+		case "<=":
+			return t.Before(siguiente)
+		case "!=":
+			return t.Before(dia) || !t.Before(siguiente)
+		}
+		return !t.Before(dia) && t.Before(siguiente)
+	}
+	x, _ := numero(v)
+	switch c.Op {
+	case ">":
+		return x > c.Numero
+	case ">=":
+		return x >= c.Numero
+	case "<":
+		return x < c.Numero
+	case "<=":
+		return x <= c.Numero
+	case "!=":
+		return x != c.Numero
+	}
+	return x == c.Numero
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go_api/models"
+)
+
+func TestParseFiltro(t *testing.T) {
+	fecha := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		nombre string
+		expr   string
+		want   ExpresionFiltro
+	}{
+		{"vacía", "  ", ExpresionFiltro{}},
+		{"palabras", "vino  tinto", ExpresionFiltro{Palabras: []string{"vino", "tinto"}}},
+		{"frase", `"vino tinto" 750`, ExpresionFiltro{Palabras: []string{"vino tinto", "750"}}},
+		{"multibyte", "añejo ÑANDÚ", ExpresionFiltro{Palabras: []string{"añejo", "ÑANDÚ"}}},
+		{"texto contiene", "nombre:vino", ExpresionFiltro{Condiciones: []CondicionFiltro{
+			{CampoFiltro: CampoFiltro{"NombreProducto", CampoTexto}, Op: ":", Texto: "vino"}}}},
+		{"texto entre comillas", `nombre="vino tinto"`, ExpresionFiltro{Condiciones: []CondicionFiltro{
+			{CampoFiltro: CampoFiltro{"NombreProducto", CampoTexto}, Op: "=", Texto: "vino tinto"}}}},
+		{"valor multibyte", "nombre:años", ExpresionFiltro{Condiciones: []CondicionFiltro{
+			{CampoFiltro: CampoFiltro{"NombreProducto", CampoTexto}, Op: ":", Texto: "años"}}}},
+		{"número", "saldo>=10", ExpresionFiltro{Condiciones: []CondicionFiltro{
+			{CampoFiltro: CampoFiltro{"SaldoAnterior", CampoNumero}, Op: ">=", Numero: 10}}}},
+		{"coma decimal", "costo_real<1500,5", ExpresionFiltro{Condiciones: []CondicionFiltro{
+			{CampoFiltro: CampoFiltro{"CostoReal", CampoNumero}, Op: "<", Numero: 1500.5}}}},
+		{"mayúsculas en el campo", "ANIO!=2024", ExpresionFiltro{Condiciones: []CondicionFiltro{
+			{CampoFiltro: CampoFiltro{"AnioProduccion", CampoNumero}, Op: "!=", Numero: 2024}}}},
+		{"fecha", "fecha_ingreso>2025-01-01", ExpresionFiltro{Condiciones: []CondicionFiltro{
+			{CampoFiltro: CampoFiltro{"FechaIngreso", CampoFecha}, Op: ">", Fecha: fecha}}}},
+		{"mes", "saldo_enero:0", ExpresionFiltro{Condiciones: []CondicionFiltro{
+			{CampoFiltro: CampoFiltro{"SaldoFinEnero", CampoNumero}, Op: ":", Numero: 0}}}},
+		{"campo desconocido", "precio:100 vino", ExpresionFiltro{Palabras: []string{"precio:100", "vino"}}},
+		{"campo desconocido con comillas", `x:"a b"`, ExpresionFiltro{Palabras: []string{"x:a b"}}},
+		{"campo multibyte desconocido", "año:2024", ExpresionFiltro{Palabras: []string{"año:2024"}}},
+		{"sin operador", "saldo", ExpresionFiltro{Palabras: []string{"saldo"}}},
+		{"mixta", "whisky anio:2025 12", ExpresionFiltro{
+			Palabras: []string{"whisky", "12"},
+			Condiciones: []CondicionFiltro{
+				{CampoFiltro: CampoFiltro{"AnioProduccion", CampoNumero}, Op: ":", Numero: 2025}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nombre, func(t *testing.T) {
+			got, err := ParseFiltro(tt.expr, CamposFiltroSaldo)
+			if err != nil {
+				t.Fatalf("ParseFiltro(%q) error = %v", tt.expr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFiltro(%q) = %+v, se esperaba %+v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFiltroErrores(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+	}{
+		{`vino "tinto`, 6},
+		{"saldo>abc", 1},
+		{"ñandú saldo>abc", 7},
+		{"anio:2024 fecha_ingreso>=01-01-2025", 11},
+		{"nombre>vino", 1},
+		{"saldo>=", 1},
+		{`nombre:vi"no`, 1},
+	}
+	for _, tt := range tests {
+		_, err := ParseFiltro(tt.expr, CamposFiltroSaldo)
+		var ef *ErrorFiltro
+		if !errors.As(err, &ef) {
+			t.Errorf("ParseFiltro(%q) error = %v, se esperaba ErrorFiltro", tt.expr, err)
+			continue
+		}
+		if ef.Pos != tt.pos {
+			t.Errorf("ParseFiltro(%q) posición = %d, se esperaba %d", tt.expr, ef.Pos, tt.pos)
+		}
+	}
+}
+
+// filasParidad son filas con los casos límite de las comparaciones:
+// mayúsculas, caracteres multibyte, fecha con hora y fecha vacía (NULL).
+var filasParidad = []models.Saldo{
+	{CodigoProducto: "VT-001", Zeta: "Z25001", AnioProduccion: 2025, NombreProducto: "VINO TINTO 750CC",
+		CostoReal: 1500, SaldoAnterior: 0, FechaIngreso: time.Date(2025, 1, 1, 15, 30, 0, 0, time.UTC)},
+	{CodigoProducto: "ro-001", Zeta: "Z24008", AnioProduccion: 2024, NombreProducto: "RON AÑEJO 1L",
+		CostoReal: 3640.5, SaldoAnterior: 70, FechaIngreso: time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)},
+	{CodigoProducto: "WH-001", Zeta: "Z25006", AnioProduccion: 2025, NombreProducto: "Whisky 12 años",
+		CostoReal: 10350, SaldoAnterior: 50, FechaIngreso: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+	{CodigoProducto: "SN-001", Zeta: "Z25009", AnioProduccion: 2025, NombreProducto: "SIN FECHA",
+		CostoReal: 0, SaldoAnterior: -5},
+}
+
+func TestCondicionFiltroParidad(t *testing.T) {
+	expresiones := []string{
+		"nombre:vino", "nombre:AÑEJO", "nombre:años", "nombre=\"ron añejo 1l\"", "nombre!=\"sin fecha\"",
+		"codigo=RO-001", "codigo:001", "zeta!=z25001",
+		"anio:2025", "anio!=2025", "anio>2024", "anio<=2024",
+		"costo_real>1500", "costo_real>=1500", "costo_real<3640,5", "costo_real:0",
+		"saldo<0", "saldo!=0",
+		"fecha_ingreso:2025-01-01", "fecha_ingreso=2025-01-02", "fecha_ingreso!=2025-01-01",
+		"fecha_ingreso>2025-01-01", "fecha_ingreso>=2025-01-01",
+		"fecha_ingreso<2025-01-01", "fecha_ingreso<=2025-01-01", "fecha_ingreso>=0999-01-01",
+	}
+	for _, expr := range expresiones {
+		f, err := ParseFiltro(expr, CamposFiltroSaldo)
+		if err != nil || len(f.Condiciones) != 1 {
+			t.Fatalf("ParseFiltro(%q) = %+v, %v", expr, f, err)
+		}
+		c := f.Condiciones[0]
+		cond, args := c.sql()
+		for _, row := range filasParidad {
+			sql := evaluarSQL(t, cond, args, row)
+			mem := c.Cumple(ValorCampoSaldo(c.Campo, row))
+			if sql != mem {
+				t.Errorf("%s sobre %s: SQL %q = %v, memoria = %v", expr, row.CodigoProducto, cond, sql, mem)
+			}
+		}
+	}
+}
+
+func TestSaldoFilterParidad(t *testing.T) {
+	min, max := 0.0, 60.0
+	condiciones := func(expr string) []CondicionFiltro {
+		f, err := ParseFiltro(expr, CamposFiltroSaldo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f.Condiciones
+	}
+	tests := []struct {
+		nombre string
+		filter SaldoFilter
+		want   []string // códigos de las filas que cumplen
+	}{
+		{"sin filtro", SaldoFilter{}, []string{"VT-001", "ro-001", "WH-001", "SN-001"}},
+		{"una palabra", SaldoFilter{Palabras: []string{"añejo"}}, []string{"ro-001"}},
+		{"cada palabra en cualquier campo", SaldoFilter{Palabras: []string{"001", "z25"}}, []string{"VT-001", "WH-001", "SN-001"}},
+		{"palabras en otro orden", SaldoFilter{Palabras: []string{"12", "whisky"}}, []string{"WH-001"}},
+		{"palabras sin coincidencia común", SaldoFilter{Palabras: []string{"vino", "whisky"}}, nil},
+		{"código", SaldoFilter{Codigo: "RO-001"}, []string{"ro-001"}},
+		{"zeta y año", SaldoFilter{Zeta: "z25006", Anio: 2025}, []string{"WH-001"}},
+		{"fecha desde", SaldoFilter{FechaDesde: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}, []string{"VT-001", "WH-001"}},
+		{"fecha hasta", SaldoFilter{FechaHasta: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}, []string{"VT-001", "ro-001"}},
+		{"saldo", SaldoFilter{SaldoMin: &min, SaldoMax: &max}, []string{"VT-001", "WH-001"}},
+		{"condiciones", SaldoFilter{Palabras: []string{"0"}, Condiciones: condiciones("anio:2025 costo_real>0")}, []string{"VT-001", "WH-001"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nombre, func(t *testing.T) {
+			where, args := tt.filter.where()
+			var sql, mem []string
+			for _, row := range filasParidad {
+				if where == "" || evaluarSQL(t, strings.TrimPrefix(where, " WHERE "), args, row) {
+					sql = append(sql, row.CodigoProducto)
+				}
+				if tt.filter.match(row) {
+					mem = append(mem, row.CodigoProducto)
+				}
+			}
+			if !reflect.DeepEqual(sql, tt.want) || !reflect.DeepEqual(mem, tt.want) {
+				t.Errorf("SQL %q = %v, memoria = %v, se esperaba %v", where, sql, mem, tt.want)
+			}
+		})
+	}
+}
+
+// evaluarSQL evalúa sobre la fila una condición como las que arman
+// CondicionFiltro.sql y SaldoFilter.where: comparaciones "columna op ?"
+// combinadas con AND y OR entre paréntesis. Imita a MySQL con intercalación
+// sin distinción de mayúsculas: NULL no cumple ninguna comparación y las
+// fechas se comparan con el texto AAAA-MM-DD como fecha y hora.
+func evaluarSQL(t *testing.T, cond string, args []interface{}, row models.Saldo) bool {
+	t.Helper()
+	columnas := map[string]func(models.Saldo) interface{}{
+		"FEC_ING": func(s models.Saldo) interface{} {
+			if s.FechaIngreso.IsZero() {
+				return nil
+			}
+			return s.FechaIngreso
+		},
+	}
+	for _, c := range camposSaldo {
+		if _, ok := columnas[c.columna]; !ok {
+			columnas[c.columna] = c.valor
+		}
+	}
+
+	var evaluar func(expr string) bool
+	evaluar = func(expr string) bool {
+		expr = strings.TrimSpace(expr)
+		if partes := dividir(expr, " AND "); len(partes) > 1 {
+			ok := true
+			for _, p := range partes {
+				// Sin cortocircuito: cada comparación consume sus parámetros
+				ok = evaluar(p) && ok
+			}
+			return ok
+		}
+		if partes := dividir(expr, " OR "); len(partes) > 1 {
+			ok := false
+			for _, p := range partes {
+				ok = evaluar(p) || ok
+			}
+			return ok
+		}
+		if strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
+			return evaluar(expr[1 : len(expr)-1])
+		}
+
+		campos := strings.Fields(expr)
+		if len(campos) != 3 || campos[2] != "?" {
+			t.Fatalf("condición no reconocida: %q", expr)
+		}
+		valor, ok := columnas[campos[0]]
+		if !ok {
+			t.Fatalf("columna no reconocida: %q", campos[0])
+		}
+		if len(args) == 0 {
+			t.Fatalf("faltan parámetros para %q", expr)
+		}
+		arg := args[0]
+		args = args[1:]
+		return comparar(t, valor(row), campos[1], arg)
+	}
+	resultado := evaluar(cond)
+	if len(args) != 0 {
+		t.Fatalf("sobran parámetros en %q: %v", cond, args)
+	}
+	return resultado
+}
+
+// dividir separa expr por sep fuera de los paréntesis.
+func dividir(expr, sep string) []string {
+	var partes []string
+	nivel, inicio := 0, 0
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '(':
+			nivel++
+		case ')':
+			nivel--
+		}
+		if nivel == 0 && strings.HasPrefix(expr[i:], sep) {
+			partes = append(partes, expr[inicio:i])
+			inicio = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(partes, expr[inicio:])
+}
+
+// comparar aplica el operador SQL op entre el valor de la columna y el
+// parámetro.
+func comparar(t *testing.T, v interface{}, op string, arg interface{}) bool {
+	t.Helper()
+	if v == nil {
+		return false
+	}
+	var cmp int
+	switch v := v.(type) {
+	case time.Time:
+		s, _ := arg.(string)
+		a, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatalf("fecha inválida en el parámetro: %v", arg)
+		}
+		cmp = v.Compare(a)
+	case string:
+		s, _ := arg.(string)
+		if op == "LIKE" {
+			patron := strings.ToLower(strings.Trim(s, "%"))
+			return strings.Contains(strings.ToLower(v), patron)
+		}
+		cmp = strings.Compare(strings.ToLower(v), strings.ToLower(s))
+	default:
+		x, _ := numero(v)
+		y, ok := numero(arg)
+		if !ok {
+			t.Fatalf("número inválido en el parámetro: %v", arg)
+		}
+		cmp = CompararValores(x, y)
+	}
+	switch op {
+	case "=":
+		return cmp == 0
+	case "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	t.Fatalf("operador no reconocido: %q", op)
+	return false
+}
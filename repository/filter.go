@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"go_api/models"
+)
+
+// SaldoFilter reúne los filtros aplicables a los listados de saldos. Los
+// campos vacíos (o nil) no filtran.
+type SaldoFilter struct {
+	// Palabras deben aparecer cada una en nombre, código o zeta (un LIKE por
+	// palabra).
+	Palabras []string
+	// Codigo y Zeta filtran por igualdad con COD_ART y ZET_ART.
+	Codigo string
+	Zeta   string
+	// Anio filtra por ANIO_PRO cuando es distinto de cero.
+	Anio int
+	// FechaDesde y FechaHasta acotan FEC_ING; ambos extremos son inclusivos.
+	FechaDesde time.Time
+	FechaHasta time.Time
+	// SaldoMin y SaldoMax acotan SAL_ANT.
+	SaldoMin *float64
+	SaldoMax *float64
+	// Condiciones son las comparaciones de la expresión de filtro (ParseFiltro)
+	// sobre campos de saldos; deben cumplirse todas.
+	Condiciones []CondicionFiltro
+}
+
+// where construye la cláusula WHERE parametrizada para MySQL.
+func (f SaldoFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	for _, p := range f.Palabras {
+		searchPattern := "%" + p + "%"
+		conds = append(conds, "(DES_INT LIKE ? OR COD_ART LIKE ? OR ZET_ART LIKE ?)")
+		args = append(args, searchPattern, searchPattern, searchPattern)
+	}
+	if f.Codigo != "" {
+		conds = append(conds, "COD_ART = ?")
+		args = append(args, f.Codigo)
+	}
+	if f.Zeta != "" {
+		conds = append(conds, "ZET_ART = ?")
+		args = append(args, f.Zeta)
+	}
+	if f.Anio != 0 {
+		conds = append(conds, "ANIO_PRO = ?")
+		args = append(args, f.Anio)
+	}
+	if !f.FechaDesde.IsZero() {
+		conds = append(conds, "FEC_ING >= ?")
+		args = append(args, f.FechaDesde.Format("2006-01-02"))
+	}
+	if !f.FechaHasta.IsZero() {
+		// FEC_ING puede incluir hora: se compara contra el día siguiente.
+		conds = append(conds, "FEC_ING < ?")
+		args = append(args, f.FechaHasta.AddDate(0, 0, 1).Format("2006-01-02"))
+	}
+	if f.SaldoMin != nil {
+		conds = append(conds, "SAL_ANT >= ?")
+		args = append(args, *f.SaldoMin)
+	}
+	if f.SaldoMax != nil {
+		conds = append(conds, "SAL_ANT <= ?")
+		args = append(args, *f.SaldoMax)
+	}
+	for _, c := range f.Condiciones {
+		cond, condArgs := c.sql()
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// match aplica el filtro sobre una fila en memoria con la misma semántica que
+// where.
+func (f SaldoFilter) match(row models.Saldo) bool {
+	if !ContienePalabras(f.Palabras, row.NombreProducto, row.CodigoProducto, row.Zeta) {
+		return false
+	}
+	if f.Codigo != "" && !strings.EqualFold(row.CodigoProducto, f.Codigo) {
+		return false
+	}
+	if f.Zeta != "" && !strings.EqualFold(row.Zeta, f.Zeta) {
+		return false
+	}
+	if f.Anio != 0 && row.AnioProduccion != f.Anio {
+		return false
+	}
+	if !f.FechaDesde.IsZero() && row.FechaIngreso.Before(f.FechaDesde) {
+		return false
+	}
+	// Sin fecha de ingreso no cumple ningún extremo, como FEC_ING NULL en SQL.
+	if !f.FechaHasta.IsZero() && (row.FechaIngreso.IsZero() || !row.FechaIngreso.Before(f.FechaHasta.AddDate(0, 0, 1))) {
+		return false
+	}
+	if f.SaldoMin != nil && row.SaldoAnterior < *f.SaldoMin {
+		return false
+	}
+	if f.SaldoMax != nil && row.SaldoAnterior > *f.SaldoMax {
+		return false
+	}
+	for _, c := range f.Condiciones {
+		if !c.Cumple(ValorCampoSaldo(c.Campo, row)) {
+			return false
+		}
+	}
+	return true
+}
+
+// StockFilter reúne los filtros del historial de stocks de SQL Server.
+type StockFilter struct {
+	Sucursales []int
+	// Codigo filtra por CODIGO_INTERNO del producto.
+	Codigo string
+	// Zeta filtra por ZETA del stock.
+	Zeta string
+	// Zetas y Codigos restringen ZETA y CODIGO_INTERNO a una lista de valores.
+	Zetas   []string
+	Codigos []string
+}
+
+// match aplica el filtro sobre una fila de stock en memoria.
+func (f StockFilter) match(s models.StockData) bool {
+	if len(f.Sucursales) > 0 {
+		incluida := false
+		for _, id := range f.Sucursales {
+			if id == s.IDSucursal {
+				incluida = true
+				break
+			}
+		}
+		if !incluida {
+			return false
+		}
+	}
+	if f.Codigo != "" && !strings.EqualFold(s.CodigoProducto, f.Codigo) {
+		return false
+	}
+	if f.Zeta != "" && !strings.EqualFold(s.Zeta, f.Zeta) {
+		return false
+	}
+	if f.Zetas != nil && !contieneTexto(f.Zetas, s.Zeta) {
+		return false
+	}
+	if f.Codigos != nil && !contieneTexto(f.Codigos, s.CodigoProducto) {
+		return false
+	}
+	return true
+}
+
+// contieneTexto indica si v está en valores sin distinguir mayúsculas, como
+// la intercalación de SQL Server.
+func contieneTexto(valores []string, v string) bool {
+	for _, x := range valores {
+		if strings.EqualFold(x, v) {
+			return true
+		}
+	}
+	return false
+}
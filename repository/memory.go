@@ -0,0 +1,467 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go_api/models"
+)
+
+// MemorySaldoRepository implementa SaldoRepository sobre filas en memoria.
+// Cada elemento de rows representa una fila de la tabla saldos; las
+// agrupaciones, búsquedas y ordenamientos replican los de MySQL.
+type MemorySaldoRepository struct {
+	mu   sync.RWMutex
+	rows []models.Saldo
+	now  func() time.Time
+}
+
+// NewMemorySaldoRepository crea un repositorio de saldos con las filas dadas.
+func NewMemorySaldoRepository(rows []models.Saldo) *MemorySaldoRepository {
+	return &MemorySaldoRepository{rows: append([]models.Saldo(nil), rows...), now: time.Now}
+}
+
+// Add agrega filas a la tabla en memoria.
+func (r *MemorySaldoRepository) Add(rows ...models.Saldo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rows = append(r.rows, rows...)
+}
+
+// List agrupa las filas que cumplen el filtro por código, zeta y año igual
+// que la consulta MySQL.
+func (r *MemorySaldoRepository) List(ctx context.Context, filter SaldoFilter) ([]models.Saldo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	saldos := r.agruparPorClave(filter)
+	sort.SliceStable(saldos, func(i, j int) bool {
+		if saldos[i].AnioProduccion != saldos[j].AnioProduccion {
+			return saldos[i].AnioProduccion < saldos[j].AnioProduccion
+		}
+		return saldos[i].CodigoProducto < saldos[j].CodigoProducto
+	})
+	return saldos, nil
+}
+
+// agruparPorClave agrupa las filas que cumplen el filtro por código, zeta y
+// año como selectSaldosPorClave, en el orden en que aparecen. Debe llamarse
+// con el lock tomado.
+func (r *MemorySaldoRepository) agruparPorClave(filter SaldoFilter) []models.Saldo {
+	type clave struct {
+		codigo, zeta string
+		anio         int
+	}
+	grupos := make(map[clave]*models.Saldo)
+	var orden []clave
+	for _, row := range r.rows {
+		if !filter.match(row) {
+			continue
+		}
+		k := clave{row.CodigoProducto, row.Zeta, row.AnioProduccion}
+		g, ok := grupos[k]
+		if !ok {
+			s := row
+			s.CantidadIngresada = 0
+			grupos[k] = &s
+			orden = append(orden, k)
+			g = &s
+		}
+		g.CantidadIngresada += row.CantidadIngresada
+		if row.NombreProducto > g.NombreProducto {
+			g.NombreProducto = row.NombreProducto
+		}
+		g.UnidadCaja = maxFloat(g.UnidadCaja, row.UnidadCaja)
+		g.CostoCIF = maxFloat(g.CostoCIF, row.CostoCIF)
+		g.CostoReal = maxFloat(g.CostoReal, row.CostoReal)
+		g.SaldoAnterior = maxFloat(g.SaldoAnterior, row.SaldoAnterior)
+		if row.FechaIngreso.After(g.FechaIngreso) {
+			g.FechaIngreso = row.FechaIngreso
+		}
+		g.SaldoFinEnero = maxFloat(g.SaldoFinEnero, row.SaldoFinEnero)
+		g.SaldoFinFebrero = maxFloat(g.SaldoFinFebrero, row.SaldoFinFebrero)
+		g.SaldoFinMarzo = maxFloat(g.SaldoFinMarzo, row.SaldoFinMarzo)
+		g.SaldoFinAbril = maxFloat(g.SaldoFinAbril, row.SaldoFinAbril)
+		g.SaldoFinMayo = maxFloat(g.SaldoFinMayo, row.SaldoFinMayo)
+		g.SaldoFinJunio = maxFloat(g.SaldoFinJunio, row.SaldoFinJunio)
+		g.SaldoFinJulio = maxFloat(g.SaldoFinJulio, row.SaldoFinJulio)
+		g.SaldoFinAgosto = maxFloat(g.SaldoFinAgosto, row.SaldoFinAgosto)
+		g.SaldoFinSeptiembre = maxFloat(g.SaldoFinSeptiembre, row.SaldoFinSeptiembre)
+		g.SaldoFinOctubre = maxFloat(g.SaldoFinOctubre, row.SaldoFinOctubre)
+		g.SaldoFinNoviembre = maxFloat(g.SaldoFinNoviembre, row.SaldoFinNoviembre)
+		g.SaldoFinDiciembre = maxFloat(g.SaldoFinDiciembre, row.SaldoFinDiciembre)
+	}
+
+	saldos := make([]models.Saldo, 0, len(orden))
+	for _, k := range orden {
+		s := *grupos[k]
+		s.DiasDesdeIngreso = diasDesde(r.now(), s.FechaIngreso)
+		saldos = append(saldos, s)
+	}
+	return saldos
+}
+
+// ListPaginated pagina los saldos agrupados por código, zeta y año, como
+// ListAfter, y devuelve también la cantidad de grupos.
+func (r *MemorySaldoRepository) ListPaginated(ctx context.Context, offset, limit int, filter SaldoFilter, orden Orden) ([]models.Saldo, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	grupos := r.agrupadosOrdenados(filter, orden)
+	return paginar(grupos, offset, limit), len(grupos), nil
+}
+
+// Each recorre una copia de los saldos agrupados y ordenados, para no
+// bloquear el repositorio mientras fn consume cada uno.
+func (r *MemorySaldoRepository) Each(ctx context.Context, filter SaldoFilter, orden Orden, fn func(models.Saldo) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.RLock()
+	filas := r.agrupadosOrdenados(filter, orden)
+	r.mu.RUnlock()
+
+	for _, s := range filas {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListAfter devuelve hasta limit saldos agrupados por código, zeta y año
+// posteriores al cursor en el orden indicado.
+func (r *MemorySaldoRepository) ListAfter(ctx context.Context, cursor *SaldoCursor, limit int, filter SaldoFilter, orden Orden) ([]models.Saldo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	grupos := r.agrupadosOrdenados(filter, orden)
+	// limit llega del cliente: la capacidad no pasa de los saldos que hay
+	saldos := make([]models.Saldo, 0, max(0, min(limit, len(grupos))))
+	for _, s := range grupos {
+		if len(saldos) == limit {
+			break
+		}
+		if cursor == nil || cursor.despues(s, orden) {
+			saldos = append(saldos, s)
+		}
+	}
+	return saldos, nil
+}
+
+// Count cuenta los saldos agrupados por código, zeta y año que cumplen el
+// filtro.
+func (r *MemorySaldoRepository) Count(ctx context.Context, filter SaldoFilter) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.agruparPorClave(filter)), nil
+}
+
+// EstimateCount devuelve el conteo exacto: en memoria no hay nada que
+// ahorrar.
+func (r *MemorySaldoRepository) EstimateCount(ctx context.Context, filter SaldoFilter) (int, error) {
+	return r.Count(ctx, filter)
+}
+
+// agrupadosOrdenados agrupa las filas que cumplen el filtro por código, zeta
+// y año y las ordena en el orden completo. Es el listado que recorren
+// ListPaginated, ListAfter y Each. Debe llamarse con el lock tomado.
+func (r *MemorySaldoRepository) agrupadosOrdenados(filter SaldoFilter, orden Orden) []models.Saldo {
+	grupos := r.agruparPorClave(filter)
+	sort.SliceStable(grupos, func(i, j int) bool {
+		return compararSaldos(grupos[i], grupos[j], orden) < 0
+	})
+	return grupos
+}
+
+// ListByYear agrupa las filas del año indicado como la consulta de MySQL
+// usada para la fusión con SQL Server.
+func (r *MemorySaldoRepository) ListByYear(ctx context.Context, year string) ([]models.SaldoData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	saldos := r.agruparPorAnio(year, SaldoFilter{})
+	sort.SliceStable(saldos, func(i, j int) bool {
+		return saldos[i].CodigoProducto < saldos[j].CodigoProducto
+	})
+	return saldos, nil
+}
+
+// ListByYearPaginated agrupa las filas del año que cumplen el filtro, las
+// ordena y pagina como la consulta de MySQL.
+func (r *MemorySaldoRepository) ListByYearPaginated(ctx context.Context, year string, offset, limit int, filter SaldoFilter, orden Orden) ([]models.SaldoData, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	saldos := r.agruparPorAnio(year, filter)
+	sort.SliceStable(saldos, func(i, j int) bool {
+		// SaldoData tiene los mismos campos que Saldo; el grupo se desempata
+		// además por nombre, como en MySQL.
+		if cmp := compararSaldos(models.Saldo(saldos[i]), models.Saldo(saldos[j]), orden); cmp != 0 {
+			return cmp < 0
+		}
+		return saldos[i].NombreProducto < saldos[j].NombreProducto
+	})
+	return paginar(saldos, offset, limit), len(saldos), nil
+}
+
+// agruparPorAnio agrupa las filas del año que cumplen el filtro como la
+// consulta de saldos agrupados de MySQL. Debe llamarse con el lock tomado.
+func (r *MemorySaldoRepository) agruparPorAnio(year string, filter SaldoFilter) []models.SaldoData {
+	type clave struct {
+		codigo, zeta, nombre          string
+		anio                          int
+		unidadCaja, costoCIF, costoRl float64
+	}
+	grupos := make(map[clave]*models.SaldoData)
+	var orden []clave
+	for _, row := range r.rows {
+		if strconv.Itoa(row.AnioProduccion) != year || !filter.match(row) {
+			continue
+		}
+		k := clave{row.CodigoProducto, row.Zeta, row.NombreProducto, row.AnioProduccion,
+			row.UnidadCaja, row.CostoCIF, row.CostoReal}
+		g, ok := grupos[k]
+		if !ok {
+			g = &models.SaldoData{
+				CodigoProducto: row.CodigoProducto,
+				Zeta:           row.Zeta,
+				AnioProduccion: row.AnioProduccion,
+				NombreProducto: row.NombreProducto,
+				UnidadCaja:     row.UnidadCaja,
+				CostoCIF:       row.CostoCIF,
+				CostoReal:      row.CostoReal,
+			}
+			grupos[k] = g
+			orden = append(orden, k)
+		}
+		g.CantidadIngresada += row.CantidadIngresada
+		g.SaldoAnterior = maxFloat(g.SaldoAnterior, row.SaldoAnterior)
+		if row.FechaIngreso.After(g.FechaIngreso) {
+			g.FechaIngreso = row.FechaIngreso
+		}
+		g.SaldoFinEnero = maxFloat(g.SaldoFinEnero, row.SaldoFinEnero)
+		g.SaldoFinFebrero = maxFloat(g.SaldoFinFebrero, row.SaldoFinFebrero)
+		g.SaldoFinMarzo = maxFloat(g.SaldoFinMarzo, row.SaldoFinMarzo)
+		g.SaldoFinAbril = maxFloat(g.SaldoFinAbril, row.SaldoFinAbril)
+		g.SaldoFinMayo = maxFloat(g.SaldoFinMayo, row.SaldoFinMayo)
+		g.SaldoFinJunio = maxFloat(g.SaldoFinJunio, row.SaldoFinJunio)
+		g.SaldoFinJulio = maxFloat(g.SaldoFinJulio, row.SaldoFinJulio)
+		g.SaldoFinAgosto = maxFloat(g.SaldoFinAgosto, row.SaldoFinAgosto)
+		g.SaldoFinSeptiembre = maxFloat(g.SaldoFinSeptiembre, row.SaldoFinSeptiembre)
+		g.SaldoFinOctubre = maxFloat(g.SaldoFinOctubre, row.SaldoFinOctubre)
+		g.SaldoFinNoviembre = maxFloat(g.SaldoFinNoviembre, row.SaldoFinNoviembre)
+		g.SaldoFinDiciembre = maxFloat(g.SaldoFinDiciembre, row.SaldoFinDiciembre)
+	}
+
+	saldos := make([]models.SaldoData, 0, len(orden))
+	for _, k := range orden {
+		s := *grupos[k]
+		s.DiasDesdeIngreso = diasDesde(r.now(), s.FechaIngreso)
+		saldos = append(saldos, s)
+	}
+	return saldos
+}
+
+// Resumen calcula las cifras globales sobre las filas en memoria.
+func (r *MemorySaldoRepository) Resumen(ctx context.Context) (models.ResumenSaldos, error) {
+	if err := ctx.Err(); err != nil {
+		return models.ResumenSaldos{}, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	resumen := models.ResumenSaldos{TotalRegistros: len(r.rows)}
+	for _, row := range r.rows {
+		if row.FechaIngreso.After(resumen.UltimoIngreso) {
+			resumen.UltimoIngreso = row.FechaIngreso
+		}
+		resumen.Valorizacion += row.SaldoAnterior * row.CostoReal
+	}
+	return resumen, nil
+}
+
+// Zetas devuelve las zetas distintas de las filas en memoria.
+func (r *MemorySaldoRepository) Zetas(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	zetas := make([]string, 0, len(r.rows))
+	vistas := make(map[string]bool)
+	for _, row := range r.rows {
+		if !vistas[row.Zeta] {
+			vistas[row.Zeta] = true
+			zetas = append(zetas, row.Zeta)
+		}
+	}
+	return zetas, nil
+}
+
+// MemoryStockRepository implementa StockRepository sobre filas en memoria.
+// Cada elemento representa una fila de STOCKS ya unida con un PRODUCTO activo.
+type MemoryStockRepository struct {
+	mu     sync.RWMutex
+	stocks []models.StockData
+}
+
+// NewMemoryStockRepository crea un repositorio de stocks con las filas dadas.
+func NewMemoryStockRepository(stocks []models.StockData) *MemoryStockRepository {
+	return &MemoryStockRepository{stocks: append([]models.StockData(nil), stocks...)}
+}
+
+// Add agrega filas de stock en memoria.
+func (r *MemoryStockRepository) Add(stocks ...models.StockData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stocks = append(r.stocks, stocks...)
+}
+
+// List devuelve los stocks de las sucursales indicadas.
+func (r *MemoryStockRepository) List(ctx context.Context, sucursales []int) ([]models.StockData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	incluir := make(map[int]bool, len(sucursales))
+	for _, id := range sucursales {
+		incluir[id] = true
+	}
+	var stocks []models.StockData
+	for _, s := range r.stocks {
+		if incluir[s.IDSucursal] {
+			stocks = append(stocks, s)
+		}
+	}
+	return stocks, nil
+}
+
+// Historial devuelve las filas que cumplen el filtro de la más reciente a la
+// más antigua.
+func (r *MemoryStockRepository) Historial(ctx context.Context, filter StockFilter) ([]models.StockData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var stocks []models.StockData
+	for _, s := range r.stocks {
+		if filter.match(s) {
+			stocks = append(stocks, s)
+		}
+	}
+	sort.SliceStable(stocks, func(i, j int) bool {
+		return stocks[i].Fecha.After(stocks[j].Fecha)
+	})
+	return stocks, nil
+}
+
+// Sucursales resume las sucursales presentes en los stocks en memoria.
+func (r *MemoryStockRepository) Sucursales(ctx context.Context) ([]models.Sucursal, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	productos := make(map[int]map[string]bool)
+	zetas := make(map[int]map[string]bool)
+	for _, s := range r.stocks {
+		if productos[s.IDSucursal] == nil {
+			productos[s.IDSucursal] = make(map[string]bool)
+			zetas[s.IDSucursal] = make(map[string]bool)
+		}
+		productos[s.IDSucursal][s.CodigoProducto] = true
+		zetas[s.IDSucursal][s.Zeta] = true
+	}
+
+	sucursales := make([]models.Sucursal, 0, len(productos))
+	for id := range productos {
+		sucursales = append(sucursales, models.Sucursal{
+			IDSucursal: id,
+			Productos:  len(productos[id]),
+			Zetas:      len(zetas[id]),
+		})
+	}
+	sort.Slice(sucursales, func(i, j int) bool {
+		return sucursales[i].IDSucursal < sucursales[j].IDSucursal
+	})
+	return sucursales, nil
+}
+
+// Zetas devuelve las zetas distintas con stock en las sucursales indicadas.
+func (r *MemoryStockRepository) Zetas(ctx context.Context, sucursales []int) ([]string, error) {
+	stocks, err := r.List(ctx, sucursales)
+	if err != nil {
+		return nil, err
+	}
+	var zetas []string
+	vistas := make(map[string]bool)
+	for _, s := range stocks {
+		if !vistas[s.Zeta] {
+			vistas[s.Zeta] = true
+			zetas = append(zetas, s.Zeta)
+		}
+	}
+	return zetas, nil
+}
+
+// diasDesde replica DATEDIFF(CURDATE(), fecha); una fecha vacía equivale a NULL.
+func diasDesde(now, fecha time.Time) int {
+	if fecha.IsZero() {
+		return 0
+	}
+	hoy := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	dia := time.Date(fecha.Year(), fecha.Month(), fecha.Day(), 0, 0, 0, 0, time.UTC)
+	return int(hoy.Sub(dia).Hours() / 24)
+}
+
+func maxFloat(a, b float64) float64 {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+// paginar devuelve el tramo [offset, offset+limit) de items.
+func paginar[T any](items []T, offset, limit int) []T {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(items) {
+		return []T{}
+	}
+	if limit < 0 || limit > len(items)-offset {
+		return items[offset:]
+	}
+	return items[offset : offset+limit]
+}
@@ -0,0 +1,518 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_api/models"
+)
+
+// MySQLSaldoRepository implementa SaldoRepository sobre la tabla saldos.
+type MySQLSaldoRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+// NewMySQLSaldoRepository crea un repositorio de saldos sobre la conexión dada.
+// timeout limita la duración de cada consulta (cero para no limitarla).
+func NewMySQLSaldoRepository(db *sql.DB, timeout time.Duration) *MySQLSaldoRepository {
+	return &MySQLSaldoRepository{db: db, timeout: timeout}
+}
+
+// List obtiene la lista de saldos que cumplen el filtro agrupada por código,
+// zeta y año.
+func (r *MySQLSaldoRepository) List(ctx context.Context, filter SaldoFilter) ([]models.Saldo, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	whereClause, args := filter.where()
+	query := selectSaldosPorClave + whereClause + " " + groupBySaldosPorClave + `
+ORDER BY ANIO_PRO, COD_ART;`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError(ctx, BackendMySQL, "saldos agrupados", err)
+	}
+	defer rows.Close()
+
+	var saldos []models.Saldo
+	for rows.Next() {
+		s, err := scanSaldo(rows)
+		if err != nil {
+			return nil, queryError(ctx, BackendMySQL, "saldos agrupados", err)
+		}
+		saldos = append(saldos, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, queryError(ctx, BackendMySQL, "saldos agrupados", err)
+	}
+
+	return saldos, nil
+}
+
+// ListPaginated obtiene 'limit' saldos agrupados por código, zeta y año a
+// partir de 'offset' aplicando el filtro y el orden indicados, como
+// ListAfter. El total es la cantidad de grupos.
+func (r *MySQLSaldoRepository) ListPaginated(ctx context.Context, offset, limit int, filter SaldoFilter, orden Orden) ([]models.Saldo, int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	// Agregar condición WHERE según el filtro
+	whereClause, args := filter.where()
+
+	// Construir consulta final con LIMIT y OFFSET
+	query := selectSaldosPorClave + whereClause + " " + groupBySaldosPorClave +
+		orden.orderBy(true) + " LIMIT ? OFFSET ?"
+
+	rows, err := r.db.QueryContext(ctx, query, append(args[:len(args):len(args)], limit, offset)...)
+	if err != nil {
+		log.Printf("Error en la consulta: %v", err)
+		return nil, 0, queryError(ctx, BackendMySQL, "saldos paginados", err)
+	}
+	defer rows.Close()
+
+	// Procesar resultados
+	var saldos []models.Saldo
+	for rows.Next() {
+		s, err := scanSaldo(rows)
+		if err != nil {
+			log.Printf("Error al escanear fila: %v", err)
+			return nil, 0, queryError(ctx, BackendMySQL, "saldos paginados", err)
+		}
+		saldos = append(saldos, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, queryError(ctx, BackendMySQL, "saldos paginados", err)
+	}
+
+	// Obtener total de grupos
+	total, err := r.count(ctx, whereClause, args)
+	if err != nil {
+		log.Printf("Error al contar registros: %v", err)
+		return nil, 0, err
+	}
+
+	return saldos, total, nil
+}
+
+// ListAfter obtiene hasta 'limit' registros posteriores al cursor (desde el
+// primero si es nil) en el orden indicado. A diferencia de ListPaginated no
+// recorre las filas anteriores, por lo que su costo no crece con la página.
+func (r *MySQLSaldoRepository) ListAfter(ctx context.Context, cursor *SaldoCursor, limit int, filter SaldoFilter, orden Orden) ([]models.Saldo, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	whereClause, args := filter.where()
+	query := selectSaldosPorClave + whereClause + " " + groupBySaldosPorClave
+	if cursor != nil {
+		keyset, keyArgs := cursor.having(orden)
+		query += " HAVING " + keyset
+		args = append(args, keyArgs...)
+	}
+	query += orden.orderBy(true) + " LIMIT ?"
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, queryError(ctx, BackendMySQL, "saldos por cursor", err)
+	}
+	defer rows.Close()
+
+	var saldos []models.Saldo
+	for rows.Next() {
+		s, err := scanSaldo(rows)
+		if err != nil {
+			return nil, queryError(ctx, BackendMySQL, "saldos por cursor", err)
+		}
+		saldos = append(saldos, s)
+	}
+	return saldos, queryError(ctx, BackendMySQL, "saldos por cursor", rows.Err())
+}
+
+// Count cuenta los saldos agrupados por código, zeta y año que cumplen el
+// filtro.
+func (r *MySQLSaldoRepository) Count(ctx context.Context, filter SaldoFilter) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+	whereClause, args := filter.where()
+	return r.count(ctx, whereClause, args)
+}
+
+// count cuenta los grupos por código, zeta y año de las filas que cumplen
+// whereClause.
+func (r *MySQLSaldoRepository) count(ctx context.Context, whereClause string, args []interface{}) (int, error) {
+	var total int
+	countQuery := "SELECT COUNT(*) FROM (SELECT 1 FROM saldos" + whereClause + " " + groupBySaldosPorClave + ") grupos"
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return 0, queryError(ctx, BackendMySQL, "conteo de saldos", err)
+	}
+	return total, nil
+}
+
+// muestraEstimacion es la cantidad de filas que lee EstimateCount para
+// calcular cuántas filas hay por grupo.
+const muestraEstimacion = 10000
+
+// EstimateCount estima los saldos agrupados que cumplen el filtro sin
+// recorrerlos. Lee hasta muestraEstimacion filas y cuenta sus grupos: si no
+// hay más filas ese es el conteo exacto; si no, escala la estimación de
+// filas de estimarFilas por la proporción de grupos de la muestra.
+func (r *MySQLSaldoRepository) EstimateCount(ctx context.Context, filter SaldoFilter) (int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	whereClause, args := filter.where()
+	var filasMuestra, gruposMuestra int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(DISTINCT COD_ART, ZET_ART, ANIO_PRO)
+        FROM (SELECT COD_ART, ZET_ART, ANIO_PRO FROM saldos`+whereClause+` LIMIT ?) muestra`,
+		append(args[:len(args):len(args)], muestraEstimacion)...).Scan(&filasMuestra, &gruposMuestra)
+	if err != nil {
+		return 0, queryError(ctx, BackendMySQL, "estimación de saldos", err)
+	}
+	if filasMuestra < muestraEstimacion {
+		return gruposMuestra, nil
+	}
+
+	filas, err := r.estimarFilas(ctx, whereClause, args)
+	if err != nil {
+		return 0, err
+	}
+	// Las estadísticas pueden quedarse cortas: nunca menos que la muestra
+	filas = max(filas, float64(filasMuestra))
+	return int(filas*float64(gruposMuestra)/float64(filasMuestra) + 0.5), nil
+}
+
+// estimarFilas estima las filas que cumplen whereClause sin recorrerlas: sin
+// filtro usa las estadísticas de la tabla y con filtro la estimación del
+// plan de ejecución (filas examinadas por el porcentaje filtrado).
+func (r *MySQLSaldoRepository) estimarFilas(ctx context.Context, whereClause string, args []interface{}) (float64, error) {
+	if whereClause == "" {
+		var total sql.NullInt64
+		err := r.db.QueryRowContext(ctx, `
+            SELECT TABLE_ROWS FROM information_schema.TABLES
+            WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'saldos'
+        `).Scan(&total)
+		return float64(total.Int64), queryError(ctx, BackendMySQL, "estimación de saldos", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, "EXPLAIN SELECT 1 FROM saldos"+whereClause, args...)
+	if err != nil {
+		return 0, queryError(ctx, BackendMySQL, "estimación de saldos", err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, queryError(ctx, BackendMySQL, "estimación de saldos", err)
+	}
+
+	// Las columnas de EXPLAIN cambian entre versiones: se leen por nombre.
+	estimado := 0.0
+	for rows.Next() {
+		valores := make([]sql.NullString, len(cols))
+		destinos := make([]interface{}, len(cols))
+		for i := range valores {
+			destinos[i] = &valores[i]
+		}
+		if err := rows.Scan(destinos...); err != nil {
+			return 0, queryError(ctx, BackendMySQL, "estimación de saldos", err)
+		}
+		filas, filtrado := 0.0, 100.0
+		for i, col := range cols {
+			switch strings.ToLower(col) {
+			case "rows":
+				filas, _ = strconv.ParseFloat(valores[i].String, 64)
+			case "filtered":
+				if valores[i].Valid {
+					filtrado, _ = strconv.ParseFloat(valores[i].String, 64)
+				}
+			}
+		}
+		estimado += filas * filtrado / 100
+	}
+	return estimado, queryError(ctx, BackendMySQL, "estimación de saldos", rows.Err())
+}
+
+// Each recorre los saldos agrupados por código, zeta y año que cumplen el
+// filtro en el orden indicado, los mismos que ListPaginated. No aplica el
+// timeout de consulta, porque la duración depende de quien consume las
+// filas; la cancelación de ctx la interrumpe.
+func (r *MySQLSaldoRepository) Each(ctx context.Context, filter SaldoFilter, orden Orden, fn func(models.Saldo) error) error {
+	whereClause, args := filter.where()
+	query := selectSaldosPorClave + whereClause + " " + groupBySaldosPorClave + orden.orderBy(true)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return queryError(ctx, BackendMySQL, "recorrido de saldos", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanSaldo(rows)
+		if err != nil {
+			return queryError(ctx, BackendMySQL, "recorrido de saldos", err)
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return queryError(ctx, BackendMySQL, "recorrido de saldos", rows.Err())
+}
+
+// ListByYear obtiene los saldos de un año de producción.
+func (r *MySQLSaldoRepository) ListByYear(ctx context.Context, year string) ([]models.SaldoData, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := selectSaldosAgrupados + `
+        WHERE ANIO_PRO = ?  
+        ` + groupBySaldos + `
+        ORDER BY ANIO_PRO, COD_ART
+    `
+
+	saldos, err := querySaldosAgrupados(ctx, r.db, query, year)
+	return saldos, queryError(ctx, BackendMySQL, "saldos por año", err)
+}
+
+// ListByYearPaginated obtiene 'limit' saldos agrupados del año a partir de
+// 'offset', aplicando el filtro sobre las filas antes de agrupar y ordenando
+// por el campo indicado. Devuelve también el total de grupos.
+func (r *MySQLSaldoRepository) ListByYearPaginated(ctx context.Context, year string, offset, limit int, filter SaldoFilter, orden Orden) ([]models.SaldoData, int, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	whereClause, args := filter.where()
+	if whereClause == "" {
+		whereClause = " WHERE ANIO_PRO = ?"
+	} else {
+		whereClause += " AND ANIO_PRO = ?"
+	}
+	args = append(args, year)
+
+	// Se desempata por las columnas del grupo para que las páginas no se
+	// solapen cuando varios grupos comparten el valor ordenado.
+	query := selectSaldosAgrupados + whereClause + " " + groupBySaldos +
+		orden.orderBy(true) + ", DES_INT LIMIT ? OFFSET ?"
+
+	saldos, err := querySaldosAgrupados(ctx, r.db, query, append(args[:len(args):len(args)], limit, offset)...)
+	if err != nil {
+		return nil, 0, queryError(ctx, BackendMySQL, "saldos por año paginados", err)
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM (SELECT 1 FROM saldos" + whereClause + " " + groupBySaldos + ") grupos"
+	if err = r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, queryError(ctx, BackendMySQL, "conteo de saldos por año", err)
+	}
+	return saldos, total, nil
+}
+
+// Resumen obtiene el total de filas, el último ingreso y la valorización
+// del stock (saldo por costo real) de la tabla saldos.
+func (r *MySQLSaldoRepository) Resumen(ctx context.Context) (models.ResumenSaldos, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var resumen models.ResumenSaldos
+	var fechaBytes []byte
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*), MAX(FEC_ING), COALESCE(SUM(SAL_ANT * cos_uni), 0) FROM saldos`).
+		Scan(&resumen.TotalRegistros, &fechaBytes, &resumen.Valorizacion)
+	if err != nil {
+		return resumen, queryError(ctx, BackendMySQL, "resumen de saldos", err)
+	}
+	resumen.UltimoIngreso, err = parseFecha(fechaBytes)
+	return resumen, err
+}
+
+// Zetas obtiene las zetas distintas de la tabla saldos.
+func (r *MySQLSaldoRepository) Zetas(ctx context.Context) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+	zetas, err := queryStrings(ctx, r.db, `SELECT DISTINCT ZET_ART FROM saldos`)
+	return zetas, queryError(ctx, BackendMySQL, "zetas de saldos", err)
+}
+
+// selectSaldosPorClave selecciona los saldos agrupados por código, zeta y
+// año, con las columnas en el orden que espera scanSaldo. Debe completarse
+// con WHERE y groupBySaldosPorClave.
+const selectSaldosPorClave = `SELECT 
+    COD_ART AS Codigo_Producto,
+    ZET_ART AS Zeta,
+    ANIO_PRO AS Año_Produccion,
+    MAX(DES_INT) AS Nombre_Producto,
+    MAX(UNI_CAJ) AS Unidad_Caja,
+    MAX(CIF_UNI) AS Costo_CIF,
+    MAX(cos_uni) AS Costo_Real,
+    MAX(FEC_ING) AS Fecha_Ingreso,
+    SUM(CAN_ING) AS Cantidad_Ingresada,
+    MAX(SAL_ANT) AS Saldo_Anterior,
+    DATEDIFF(CURDATE(), MAX(FEC_ING)) AS Dias_Desde_Ingreso,
+    MAX(FIN_ENE) AS Saldo_Fin_Enero,
+    MAX(FIN_FEB) AS Saldo_Fin_Febrero,
+    MAX(FIN_MAR) AS Saldo_Fin_Marzo,
+    MAX(FIN_ABR) AS Saldo_Fin_Abril,
+    MAX(FIN_MAY) AS Saldo_Fin_Mayo,
+    MAX(FIN_JUN) AS Saldo_Fin_Junio,
+    MAX(FIN_JUL) AS Saldo_Fin_Julio,
+    MAX(FIN_AGO) AS Saldo_Fin_Agosto,
+    MAX(FIN_SEP) AS Saldo_Fin_Septiembre,
+    MAX(FIN_OCT) AS Saldo_Fin_Octubre,
+    MAX(FIN_NOV) AS Saldo_Fin_Noviembre,
+    MAX(FIN_DIC) AS Saldo_Fin_Diciembre
+FROM saldos`
+
+// groupBySaldosPorClave agrupa las filas como selectSaldosPorClave.
+const groupBySaldosPorClave = `GROUP BY COD_ART, ZET_ART, ANIO_PRO`
+
+// selectSaldosAgrupados selecciona los saldos agrupados por producto, zeta y
+// año para la fusión con SQL Server, con las columnas en el orden que espera
+// querySaldosAgrupados. Debe completarse con WHERE y groupBySaldos.
+const selectSaldosAgrupados = `
+        SELECT 
+            COD_ART AS Codigo_Producto,
+            ZET_ART AS Zeta,
+            ANIO_PRO AS Año_Produccion,
+            DES_INT AS Nombre_Producto, 
+            UNI_CAJ AS Unidad_Caja,
+            CIF_UNI AS Costo_CIF,
+            cos_uni AS Costo_Real,
+            MAX(FEC_ING) AS Fecha_Ingreso,    
+            SUM(CAN_ING) AS Cantidad_Ingresada, 
+            MAX(SAL_ANT) AS Saldo_Anterior,   
+            DATEDIFF(CURDATE(), MAX(FEC_ING)) AS Dias_Desde_Ingreso,
+            MAX(FIN_ENE) AS Saldo_Fin_Enero,
+            MAX(FIN_FEB) AS Saldo_Fin_Febrero,
+            MAX(FIN_MAR) AS Saldo_Fin_Marzo,
+            MAX(FIN_ABR) AS Saldo_Fin_Abril,
+            MAX(FIN_MAY) AS Saldo_Fin_Mayo,
+            MAX(FIN_JUN) AS Saldo_Fin_Junio,
+            MAX(FIN_JUL) AS Saldo_Fin_Julio,
+            MAX(FIN_AGO) AS Saldo_Fin_Agosto,
+            MAX(FIN_SEP) AS Saldo_Fin_Septiembre,
+            MAX(FIN_OCT) AS Saldo_Fin_Octubre,
+            MAX(FIN_NOV) AS Saldo_Fin_Noviembre,
+            MAX(FIN_DIC) AS Saldo_Fin_Diciembre
+        FROM saldos`
+
+// groupBySaldos agrupa las filas de saldos como selectSaldosAgrupados.
+const groupBySaldos = `GROUP BY COD_ART, ZET_ART, ANIO_PRO, DES_INT, UNI_CAJ, CIF_UNI, cos_uni`
+
+// querySaldosAgrupados ejecuta una consulta basada en selectSaldosAgrupados.
+func querySaldosAgrupados(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]models.SaldoData, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var saldos []models.SaldoData
+	for rows.Next() {
+		var s models.SaldoData
+		var fechaIngresoBytes []byte
+		var dias sql.NullInt64
+		err := rows.Scan(
+			&s.CodigoProducto,
+			&s.Zeta,
+			&s.AnioProduccion,
+			&s.NombreProducto,
+			&s.UnidadCaja,
+			&s.CostoCIF,
+			&s.CostoReal,
+			&fechaIngresoBytes,
+			&s.CantidadIngresada,
+			&s.SaldoAnterior,
+			&dias,
+			&s.SaldoFinEnero,
+			&s.SaldoFinFebrero,
+			&s.SaldoFinMarzo,
+			&s.SaldoFinAbril,
+			&s.SaldoFinMayo,
+			&s.SaldoFinJunio,
+			&s.SaldoFinJulio,
+			&s.SaldoFinAgosto,
+			&s.SaldoFinSeptiembre,
+			&s.SaldoFinOctubre,
+			&s.SaldoFinNoviembre,
+			&s.SaldoFinDiciembre,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.FechaIngreso, err = parseFecha(fechaIngresoBytes); err != nil {
+			return nil, err
+		}
+		if dias.Valid {
+			s.DiasDesdeIngreso = int(dias.Int64)
+		}
+
+		saldos = append(saldos, s)
+	}
+	return saldos, rows.Err()
+}
+
+// scanSaldo lee una fila con las columnas de models.Saldo en el orden de las
+// consultas de este archivo.
+func scanSaldo(rows *sql.Rows) (models.Saldo, error) {
+	var s models.Saldo
+	var fechaIngresoBytes []byte // variable temporal para Fecha_Ingreso
+	var dias sql.NullInt64       // variable temporal para Dias_Desde_Ingreso
+	err := rows.Scan(
+		&s.CodigoProducto,
+		&s.Zeta,
+		&s.AnioProduccion,
+		&s.NombreProducto,
+		&s.UnidadCaja,
+		&s.CostoCIF,
+		&s.CostoReal,
+		&fechaIngresoBytes,
+		&s.CantidadIngresada,
+		&s.SaldoAnterior,
+		&dias,
+		&s.SaldoFinEnero,
+		&s.SaldoFinFebrero,
+		&s.SaldoFinMarzo,
+		&s.SaldoFinAbril,
+		&s.SaldoFinMayo,
+		&s.SaldoFinJunio,
+		&s.SaldoFinJulio,
+		&s.SaldoFinAgosto,
+		&s.SaldoFinSeptiembre,
+		&s.SaldoFinOctubre,
+		&s.SaldoFinNoviembre,
+		&s.SaldoFinDiciembre,
+	)
+	if err != nil {
+		return s, err
+	}
+	if s.FechaIngreso, err = parseFecha(fechaIngresoBytes); err != nil {
+		log.Printf("Error al parsear fecha: %v", err)
+		return s, err
+	}
+	if dias.Valid {
+		s.DiasDesdeIngreso = int(dias.Int64)
+	}
+	return s, nil
+}
+
+// queryStrings ejecuta una consulta de una sola columna de texto.
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
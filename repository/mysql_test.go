@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"go_api/models"
+)
+
+// driverGuionado es un driver de database/sql que responde a cada consulta
+// con las filas de la primera respuesta cuyo fragmento contiene, y registra
+// las consultas recibidas.
+type driverGuionado struct {
+	mu         sync.Mutex
+	consultas  []string
+	respuestas []respuestaGuionada
+}
+
+type respuestaGuionada struct {
+	fragmento string
+	columnas  []string
+	filas     [][]driver.Value
+}
+
+var (
+	driversGuionados   = map[string]*driverGuionado{}
+	driversGuionadosMu sync.Mutex
+)
+
+func init() {
+	sql.Register("guionado", conectorGuionado{})
+}
+
+// abrirGuionado abre una conexión que responde según d.
+func abrirGuionado(t *testing.T, d *driverGuionado) *sql.DB {
+	t.Helper()
+	driversGuionadosMu.Lock()
+	driversGuionados[t.Name()] = d
+	driversGuionadosMu.Unlock()
+	db, err := sql.Open("guionado", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type conectorGuionado struct{}
+
+func (conectorGuionado) Open(nombre string) (driver.Conn, error) {
+	driversGuionadosMu.Lock()
+	defer driversGuionadosMu.Unlock()
+	return conexionGuionada{driversGuionados[nombre]}, nil
+}
+
+type conexionGuionada struct{ d *driverGuionado }
+
+func (c conexionGuionada) Prepare(query string) (driver.Stmt, error) {
+	return sentenciaGuionada{c.d, query}, nil
+}
+func (conexionGuionada) Close() error              { return nil }
+func (conexionGuionada) Begin() (driver.Tx, error) { return nil, fmt.Errorf("sin transacciones") }
+
+type sentenciaGuionada struct {
+	d     *driverGuionado
+	query string
+}
+
+func (sentenciaGuionada) Close() error  { return nil }
+func (sentenciaGuionada) NumInput() int { return -1 }
+func (sentenciaGuionada) Exec([]driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("sin Exec")
+}
+
+func (s sentenciaGuionada) Query([]driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.consultas = append(s.d.consultas, s.query)
+	for _, r := range s.d.respuestas {
+		if strings.Contains(s.query, r.fragmento) {
+			return &filasGuionadas{columnas: r.columnas, filas: r.filas}, nil
+		}
+	}
+	return nil, fmt.Errorf("consulta no prevista: %s", s.query)
+}
+
+type filasGuionadas struct {
+	columnas []string
+	filas    [][]driver.Value
+}
+
+func (f *filasGuionadas) Columns() []string { return f.columnas }
+func (f *filasGuionadas) Close() error      { return nil }
+func (f *filasGuionadas) Next(dest []driver.Value) error {
+	if len(f.filas) == 0 {
+		return io.EOF
+	}
+	copy(dest, f.filas[0])
+	f.filas = f.filas[1:]
+	return nil
+}
+
+func TestMySQLEstimateCountGrupos(t *testing.T) {
+	muestra := func(filas, grupos int64) respuestaGuionada {
+		return respuestaGuionada{"COUNT(DISTINCT COD_ART, ZET_ART, ANIO_PRO)",
+			[]string{"filas", "grupos"}, [][]driver.Value{{filas, grupos}}}
+	}
+	estadisticas := respuestaGuionada{"information_schema.TABLES", []string{"TABLE_ROWS"}, [][]driver.Value{{int64(40000)}}}
+	plan := respuestaGuionada{"EXPLAIN", []string{"id", "rows", "filtered"}, [][]driver.Value{{int64(1), "30000", "50.00"}}}
+
+	tests := []struct {
+		nombre     string
+		filtro     SaldoFilter
+		respuestas []respuestaGuionada
+		want       int
+		consultas  int
+	}{
+		{"la muestra cubre todo", SaldoFilter{}, []respuestaGuionada{muestra(120, 80)}, 80, 1},
+		{"sin filtro", SaldoFilter{}, []respuestaGuionada{muestra(muestraEstimacion, muestraEstimacion/4), estadisticas}, 10000, 2},
+		{"con filtro", SaldoFilter{Palabras: []string{"vino"}}, []respuestaGuionada{muestra(muestraEstimacion, muestraEstimacion/2), plan}, 7500, 2},
+		{"estadísticas cortas", SaldoFilter{}, []respuestaGuionada{muestra(muestraEstimacion, muestraEstimacion/2),
+			{"information_schema.TABLES", []string{"TABLE_ROWS"}, [][]driver.Value{{int64(10)}}}}, muestraEstimacion / 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nombre, func(t *testing.T) {
+			d := &driverGuionado{respuestas: tt.respuestas}
+			repo := NewMySQLSaldoRepository(abrirGuionado(t, d), 0)
+			got, err := repo.EstimateCount(context.Background(), tt.filtro)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("EstimateCount = %d, se esperaba %d", got, tt.want)
+			}
+			if len(d.consultas) != tt.consultas {
+				t.Errorf("consultas = %q, se esperaban %d", d.consultas, tt.consultas)
+			}
+		})
+	}
+}
+
+// TestMySQLListadosAgrupados comprueba que el listado por página, el
+// recorrido, el listado por cursor y el conteo agrupan por la misma clave.
+func TestMySQLListadosAgrupados(t *testing.T) {
+	d := &driverGuionado{respuestas: []respuestaGuionada{
+		{"SELECT COUNT(*)", []string{"total"}, [][]driver.Value{{int64(0)}}},
+		{"SELECT", nil, nil},
+	}}
+	repo := NewMySQLSaldoRepository(abrirGuionado(t, d), 0)
+	ctx := context.Background()
+	orden := Orden{{Campo: "CantidadIngresada", Desc: true}}
+	if _, _, err := repo.ListPaginated(ctx, 0, 10, SaldoFilter{}, orden); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Each(ctx, SaldoFilter{}, orden, func(models.Saldo) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ListAfter(ctx, nil, 10, SaldoFilter{}, orden); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Count(ctx, SaldoFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.consultas) != 5 {
+		t.Fatalf("consultas = %q, se esperaban 5", d.consultas)
+	}
+	for _, q := range d.consultas {
+		if !strings.Contains(q, groupBySaldosPorClave) {
+			t.Errorf("la consulta no agrupa por clave: %s", q)
+		}
+		if strings.Contains(q, "ORDER BY") && !strings.Contains(q, "ORDER BY SUM(CAN_ING) DESC") {
+			t.Errorf("la consulta no ordena por la expresión agrupada: %s", q)
+		}
+	}
+}
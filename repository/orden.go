@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go_api/models"
+)
+
+// campoSaldo describe un campo de saldos por el que se puede ordenar: su
+// expresión en SQL sobre una fila, su expresión en las consultas agrupadas
+// (válida tanto agrupando por código, zeta y año como por groupBySaldos) y
+// cómo leerlo de una fila en memoria.
+type campoSaldo struct {
+	columna  string
+	agregada string
+	valor    func(models.Saldo) interface{}
+}
+
+// camposSaldo es la lista blanca de campos ordenables de saldos, por el
+// nombre que usan la API y las vistas. Las mismas claves valen para ordenar
+// los datos combinados en memoria. Las fechas nulas se ordenan como la fecha
+// cero, igual que se leen.
+var camposSaldo = map[string]campoSaldo{
+	"CodigoProducto": {"COD_ART", "COD_ART", func(s models.Saldo) interface{} { return s.CodigoProducto }},
+	"Zeta":           {"ZET_ART", "ZET_ART", func(s models.Saldo) interface{} { return s.Zeta }},
+	"AnioProduccion": {"ANIO_PRO", "ANIO_PRO", func(s models.Saldo) interface{} { return s.AnioProduccion }},
+	"NombreProducto": {"DES_INT", "MAX(DES_INT)", func(s models.Saldo) interface{} { return s.NombreProducto }},
+	"UnidadCaja":     {"UNI_CAJ", "MAX(UNI_CAJ)", func(s models.Saldo) interface{} { return s.UnidadCaja }},
+	"CostoCIF":       {"CIF_UNI", "MAX(CIF_UNI)", func(s models.Saldo) interface{} { return s.CostoCIF }},
+	"CostoReal":      {"cos_uni", "MAX(cos_uni)", func(s models.Saldo) interface{} { return s.CostoReal }},
+	"FechaIngreso": {"COALESCE(FEC_ING, DATE('1000-01-01'))", "COALESCE(MAX(FEC_ING), DATE('1000-01-01'))",
+		func(s models.Saldo) interface{} { return s.FechaIngreso }},
+	"CantidadIngresada": {"CAN_ING", "SUM(CAN_ING)", func(s models.Saldo) interface{} { return s.CantidadIngresada }},
+	"SaldoAnterior":     {"SAL_ANT", "MAX(SAL_ANT)", func(s models.Saldo) interface{} { return s.SaldoAnterior }},
+	"DiasDesdeIngreso": {"COALESCE(DATEDIFF(CURDATE(), FEC_ING), 0)", "COALESCE(DATEDIFF(CURDATE(), MAX(FEC_ING)), 0)",
+		func(s models.Saldo) interface{} { return s.DiasDesdeIngreso }},
+}
+
+// columnasMeses son las columnas de saldo de fin de mes, de enero a diciembre.
+var columnasMeses = [12]string{
+	"FIN_ENE", "FIN_FEB", "FIN_MAR", "FIN_ABR", "FIN_MAY", "FIN_JUN",
+	"FIN_JUL", "FIN_AGO", "FIN_SEP", "FIN_OCT", "FIN_NOV", "FIN_DIC",
+}
+
+func init() {
+	for i, mes := range models.Meses {
+		i := i
+		camposSaldo["SaldoFin"+mes] = campoSaldo{columnasMeses[i], "MAX(" + columnasMeses[i] + ")",
+			func(s models.Saldo) interface{} { return s.SaldosMensuales()[i] }}
+	}
+}
+
+// EsCampoSaldo indica si nombre es un campo ordenable de saldos.
+func EsCampoSaldo(nombre string) bool {
+	_, ok := camposSaldo[nombre]
+	return ok
+}
+
+// camposDeProducto son los campos de las columnas de groupBySaldos.
+var camposDeProducto = map[string]bool{
+	"CodigoProducto": true, "Zeta": true, "AnioProduccion": true, "NombreProducto": true,
+	"UnidadCaja": true, "CostoCIF": true, "CostoReal": true,
+}
+
+// EsCampoDeProducto indica si el campo de saldos tiene el mismo valor en
+// todas las filas que ListByYear agrupa por producto, de modo que filtrarlo
+// por fila equivale a filtrar el producto.
+func EsCampoDeProducto(nombre string) bool {
+	return camposDeProducto[nombre]
+}
+
+// ValorCampoSaldo devuelve el valor del campo de saldos nombre en la fila,
+// como lo compara CompararValores.
+func ValorCampoSaldo(nombre string, s models.Saldo) interface{} {
+	return camposSaldo[nombre].valor(s)
+}
+
+// CampoOrden es un criterio de orden: el campo y si es descendente.
+type CampoOrden struct {
+	Campo string
+	Desc  bool
+}
+
+// Orden es una lista de criterios de orden, del más al menos significativo.
+// Vacío equivale a ordenar por código.
+type Orden []CampoOrden
+
+// maxCamposOrden limita los criterios de un mismo orden.
+const maxCamposOrden = 5
+
+// ParseOrden lee una lista de campos separados por coma como
+// "AnioProduccion,-CostoReal". El prefijo "-" pide orden descendente y "+"
+// ascendente; sin prefijo se usa dir, para mantener sort=X&dir=desc. Solo
+// acepta los campos para los que valido devuelve true.
+func ParseOrden(spec, dir string, valido func(string) bool) (Orden, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	var orden Orden
+	vistos := make(map[string]bool)
+	for _, parte := range strings.Split(spec, ",") {
+		parte = strings.TrimSpace(parte)
+		c := CampoOrden{Desc: strings.EqualFold(dir, "desc")}
+		switch {
+		case strings.HasPrefix(parte, "-"):
+			c.Desc, parte = true, parte[1:]
+		case strings.HasPrefix(parte, "+"):
+			c.Desc, parte = false, parte[1:]
+		}
+		c.Campo = parte
+		if !valido(c.Campo) {
+			return nil, fmt.Errorf("campo de orden inválido: %q", c.Campo)
+		}
+		if vistos[c.Campo] {
+			return nil, fmt.Errorf("campo de orden repetido: %q", c.Campo)
+		}
+		vistos[c.Campo] = true
+		orden = append(orden, c)
+	}
+	if len(orden) > maxCamposOrden {
+		return nil, fmt.Errorf("se puede ordenar por hasta %d campos", maxCamposOrden)
+	}
+	return orden, nil
+}
+
+// String devuelve el orden con la sintaxis de ParseOrden, con la dirección
+// de cada campo explícita si es descendente.
+func (o Orden) String() string {
+	partes := make([]string, len(o))
+	for i, c := range o {
+		partes[i] = c.Campo
+		if c.Desc {
+			partes[i] = "-" + c.Campo
+		}
+	}
+	return strings.Join(partes, ",")
+}
+
+// Invertir devuelve el orden con todas las direcciones invertidas.
+func (o Orden) Invertir() Orden {
+	inv := make(Orden, len(o))
+	for i, c := range o {
+		inv[i] = CampoOrden{Campo: c.Campo, Desc: !c.Desc}
+	}
+	return inv
+}
+
+// camposDesempate completan el orden de los listados de saldos para que sea
+// estable y sirva para paginar por cursor.
+var camposDesempate = []string{"CodigoProducto", "Zeta", "AnioProduccion"}
+
+// completo devuelve el orden seguido de los campos de desempate que no
+// incluye, con la dirección del primer criterio.
+func (o Orden) completo() Orden {
+	if len(o) == 0 {
+		o = Orden{{Campo: "CodigoProducto"}}
+	}
+	completo := append(Orden(nil), o...)
+	for _, campo := range camposDesempate {
+		incluido := false
+		for _, c := range o {
+			incluido = incluido || c.Campo == campo
+		}
+		if !incluido {
+			completo = append(completo, CampoOrden{Campo: campo, Desc: o[0].Desc})
+		}
+	}
+	return completo
+}
+
+// orderBy arma la cláusula ORDER BY del orden completo, con las expresiones
+// por fila o las de la consulta agrupada.
+func (o Orden) orderBy(agrupado bool) string {
+	completo := o.completo()
+	partes := make([]string, len(completo))
+	for i, c := range completo {
+		partes[i] = o.expresion(c.Campo, agrupado) + " ASC"
+		if c.Desc {
+			partes[i] = o.expresion(c.Campo, agrupado) + " DESC"
+		}
+	}
+	return " ORDER BY " + strings.Join(partes, ", ")
+}
+
+func (o Orden) expresion(campo string, agrupado bool) string {
+	if agrupado {
+		return camposSaldo[campo].agregada
+	}
+	return camposSaldo[campo].columna
+}
+
+// compararSaldos compara dos filas en el orden completo.
+func compararSaldos(a, b models.Saldo, o Orden) int {
+	for _, c := range o.completo() {
+		valor := camposSaldo[c.Campo].valor
+		if cmp := CompararValores(valor(a), valor(b)); cmp != 0 {
+			if c.Desc {
+				return -cmp
+			}
+			return cmp
+		}
+	}
+	return 0
+}
+
+// CompararValores compara textos, números (de cualquier tipo, como llegan
+// del cursor decodificado) y fechas. Devuelve -1, 0 o 1.
+func CompararValores(a, b interface{}) int {
+	if x, ok := numero(a); ok {
+		y, _ := numero(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	if x, ok := a.(time.Time); ok {
+		y, _ := b.(time.Time)
+		return x.Compare(y)
+	}
+	x, _ := a.(string)
+	y, _ := b.(string)
+	return strings.Compare(x, y)
+}
+
+func numero(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOrden(t *testing.T) {
+	tests := []struct {
+		nombre  string
+		spec    string
+		dir     string
+		want    Orden
+		wantErr bool
+	}{
+		{"vacío", "", "desc", nil, false},
+		{"solo espacios", "  ", "", nil, false},
+		{"un campo", "CostoReal", "", Orden{{Campo: "CostoReal"}}, false},
+		{"dir desc", "CostoReal", "desc", Orden{{Campo: "CostoReal", Desc: true}}, false},
+		{"dir en mayúsculas", "CostoReal", "DESC", Orden{{Campo: "CostoReal", Desc: true}}, false},
+		{"varios campos", "AnioProduccion,-CostoReal", "",
+			Orden{{Campo: "AnioProduccion"}, {Campo: "CostoReal", Desc: true}}, false},
+		{"prefijos sobre dir", "+AnioProduccion, -CostoReal,Zeta", "desc",
+			Orden{{Campo: "AnioProduccion"}, {Campo: "CostoReal", Desc: true}, {Campo: "Zeta", Desc: true}}, false},
+		{"campo inválido", "Precio", "", nil, true},
+		{"campo vacío", "CostoReal,", "", nil, true},
+		{"sql", "CostoReal;DROP TABLE saldos", "", nil, true},
+		{"repetido", "CostoReal,-CostoReal", "", nil, true},
+		{"máximo de campos", "CodigoProducto,Zeta,AnioProduccion,CostoReal,CostoCIF", "",
+			Orden{{Campo: "CodigoProducto"}, {Campo: "Zeta"}, {Campo: "AnioProduccion"}, {Campo: "CostoReal"}, {Campo: "CostoCIF"}}, false},
+		{"demasiados campos", "CodigoProducto,Zeta,AnioProduccion,CostoReal,CostoCIF,SaldoAnterior", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nombre, func(t *testing.T) {
+			got, err := ParseOrden(tt.spec, tt.dir, EsCampoSaldo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOrden(%q, %q) error = %v, se esperaba error: %v", tt.spec, tt.dir, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseOrden(%q, %q) = %v, se esperaba %v", tt.spec, tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrdenStringInvertir(t *testing.T) {
+	tests := []struct {
+		spec      string
+		string    string
+		invertido string
+	}{
+		{"CostoReal", "CostoReal", "-CostoReal"},
+		{"AnioProduccion,-CostoReal", "AnioProduccion,-CostoReal", "-AnioProduccion,CostoReal"},
+		{"-FechaIngreso,+Zeta", "-FechaIngreso,Zeta", "FechaIngreso,-Zeta"},
+	}
+	for _, tt := range tests {
+		o, err := ParseOrden(tt.spec, "", EsCampoSaldo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := o.String(); got != tt.string {
+			t.Errorf("%q.String() = %q, se esperaba %q", tt.spec, got, tt.string)
+		}
+		if got := o.Invertir().String(); got != tt.invertido {
+			t.Errorf("%q.Invertir() = %q, se esperaba %q", tt.spec, got, tt.invertido)
+		}
+		// String se vuelve a leer igual
+		if again, err := ParseOrden(o.String(), "", EsCampoSaldo); err != nil || !reflect.DeepEqual(again, o) {
+			t.Errorf("ParseOrden(%q) = %v, %v; se esperaba %v", o.String(), again, err, o)
+		}
+	}
+}
+
+func TestOrdenCompletoYOrderBy(t *testing.T) {
+	tests := []struct {
+		nombre   string
+		orden    Orden
+		agrupado bool
+		want     string
+	}{
+		{"sin orden", nil, false,
+			" ORDER BY COD_ART ASC, ZET_ART ASC, ANIO_PRO ASC"},
+		{"desempate con la dirección del primero", Orden{{Campo: "CostoReal", Desc: true}}, false,
+			" ORDER BY cos_uni DESC, COD_ART DESC, ZET_ART DESC, ANIO_PRO DESC"},
+		{"desempate incluido", Orden{{Campo: "Zeta"}, {Campo: "CodigoProducto", Desc: true}}, false,
+			" ORDER BY ZET_ART ASC, COD_ART DESC, ANIO_PRO ASC"},
+		{"agrupado", Orden{{Campo: "CantidadIngresada"}, {Campo: "NombreProducto", Desc: true}}, true,
+			" ORDER BY SUM(CAN_ING) ASC, MAX(DES_INT) DESC, COD_ART ASC, ZET_ART ASC, ANIO_PRO ASC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nombre, func(t *testing.T) {
+			if got := tt.orden.orderBy(tt.agrupado); got != tt.want {
+				t.Errorf("orderBy = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompararValores(t *testing.T) {
+	tests := []struct {
+		a, b interface{}
+		want int
+	}{
+		{1, 2, -1},
+		{2.5, 2, 1},
+		{3, 3.0, 0},
+		{"a", "b", -1},
+		{"b", "a", 1},
+		{"x", "x", 0},
+	}
+	for _, tt := range tests {
+		if got := CompararValores(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompararValores(%v, %v) = %d, se esperaba %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
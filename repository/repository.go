@@ -0,0 +1,76 @@
+// Package repository define el acceso a datos de saldos (MySQL) y stocks
+// (SQL Server) detrás de interfaces, de modo que los handlers puedan usar
+// las bases reales o una implementación en memoria (pruebas y modo demo).
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go_api/models"
+)
+
+// SucursalPorDefecto es la sucursal que se consulta en SQL Server cuando la
+// configuración no indica otra.
+const SucursalPorDefecto = 211
+
+// SaldoRepository expone las consultas sobre la tabla saldos de MySQL.
+// Todas las consultas respetan la cancelación y el plazo del contexto.
+type SaldoRepository interface {
+	// List devuelve los saldos que cumplen el filtro agrupados por código,
+	// zeta y año de producción.
+	List(ctx context.Context, filter SaldoFilter) ([]models.Saldo, error)
+	// ListPaginated devuelve una página de saldos y el total de registros que
+	// cumplen el filtro.
+	ListPaginated(ctx context.Context, offset, limit int, filter SaldoFilter, orden Orden) ([]models.Saldo, int, error)
+	// ListAfter devuelve hasta limit saldos agrupados por código, zeta y año
+	// (como List) posteriores al cursor (desde el primero si es nil) en el
+	// orden indicado, para paginar por clave: el grupo es único por clave.
+	ListAfter(ctx context.Context, cursor *SaldoCursor, limit int, filter SaldoFilter, orden Orden) ([]models.Saldo, error)
+	// Count devuelve la cantidad de saldos agrupados que recorre ListAfter.
+	Count(ctx context.Context, filter SaldoFilter) (int, error)
+	// EstimateCount devuelve una estimación rápida de Count.
+	EstimateCount(ctx context.Context, filter SaldoFilter) (int, error)
+	// Each recorre las filas que cumplen el filtro en el orden indicado sin
+	// cargarlas todas en memoria, llamando a fn por cada una. Se detiene en
+	// el primer error de fn y lo devuelve.
+	Each(ctx context.Context, filter SaldoFilter, orden Orden, fn func(models.Saldo) error) error
+	// ListByYear devuelve los saldos de un año de producción para fusionarlos
+	// con los stocks de SQL Server.
+	ListByYear(ctx context.Context, year string) ([]models.SaldoData, error)
+	// ListByYearPaginated devuelve una página de los saldos agrupados de un
+	// año que cumplen el filtro, en el orden indicado, y el total de grupos.
+	ListByYearPaginated(ctx context.Context, year string, offset, limit int, filter SaldoFilter, orden Orden) ([]models.SaldoData, int, error)
+	// Resumen devuelve las cifras globales de la tabla saldos.
+	Resumen(ctx context.Context) (models.ResumenSaldos, error)
+	// Zetas devuelve las zetas distintas registradas en saldos.
+	Zetas(ctx context.Context) ([]string, error)
+}
+
+// StockRepository expone las consultas sobre STOCKS/PRODUCTO de SQL Server.
+type StockRepository interface {
+	// List devuelve los stocks de productos activos de las sucursales dadas.
+	List(ctx context.Context, sucursales []int) ([]models.StockData, error)
+	// Zetas devuelve las zetas distintas con stock en las sucursales dadas.
+	Zetas(ctx context.Context, sucursales []int) ([]string, error)
+	// Historial devuelve todas las filas de STOCKS que cumplen el filtro,
+	// de la más reciente a la más antigua. Con Zetas o Codigos sirve para
+	// leer solo los stocks de una página de saldos.
+	Historial(ctx context.Context, filter StockFilter) ([]models.StockData, error)
+	// Sucursales devuelve las sucursales con stock de productos activos.
+	Sucursales(ctx context.Context) ([]models.Sucursal, error)
+}
+
+// parseFecha convierte el valor de FEC_ING leído como []byte a time.Time.
+// Un valor vacío se interpreta como la fecha cero.
+func parseFecha(b []byte) (time.Time, error) {
+	fechaStr := string(b)
+	if fechaStr == "" {
+		return time.Time{}, nil
+	}
+	fecha, err := time.Parse("2006-01-02", fechaStr)
+	if err != nil {
+		fecha, err = time.Parse("2006-01-02 15:04:05", fechaStr)
+	}
+	return fecha, err
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_api/models"
+)
+
+// SQLServerStockRepository implementa StockRepository sobre el ERP en SQL Server.
+type SQLServerStockRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+// NewSQLServerStockRepository crea un repositorio de stocks sobre la conexión
+// dada. timeout limita la duración de cada consulta (cero para no limitarla).
+func NewSQLServerStockRepository(db *sql.DB, timeout time.Duration) *SQLServerStockRepository {
+	return &SQLServerStockRepository{db: db, timeout: timeout}
+}
+
+// List obtiene los stocks de productos activos de las sucursales indicadas.
+func (r *SQLServerStockRepository) List(ctx context.Context, sucursales []int) ([]models.StockData, error) {
+	if len(sucursales) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+        SELECT 
+            s.ID_SUCURSAL,
+            p.NOMBRE_PRODUCTO,
+            p.CODIGO_INTERNO AS Codigo_Producto,
+            s.ZETA,
+            s.FECHA,
+            p.PRECIO_VENTA,
+            p.PRECIO_OFERTA,
+            s.COSTO_UNITARIO,
+            s.ANIO
+        FROM STOCKS s
+        INNER JOIN PRODUCTO p 
+            ON s.ID_PRODUCTO = p.ID_PRODUCTO
+        WHERE p.ACTIVO = 1 AND s.ID_SUCURSAL IN (` + placeholders(len(sucursales)) + `)
+    `
+	stocks, err := queryStocks(ctx, r.db, query, intArgs(sucursales)...)
+	return stocks, queryError(ctx, BackendSQLServer, "stocks", err)
+}
+
+// Historial obtiene todas las filas de STOCKS de productos activos que
+// cumplen el filtro, de la más reciente a la más antigua.
+func (r *SQLServerStockRepository) Historial(ctx context.Context, filter StockFilter) ([]models.StockData, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	conds := []string{"p.ACTIVO = 1"}
+	var args []interface{}
+	if len(filter.Sucursales) > 0 {
+		ps := make([]string, len(filter.Sucursales))
+		for i, id := range filter.Sucursales {
+			args = append(args, id)
+			ps[i] = "@p" + strconv.Itoa(len(args))
+		}
+		conds = append(conds, "s.ID_SUCURSAL IN ("+strings.Join(ps, ", ")+")")
+	}
+	if filter.Codigo != "" {
+		args = append(args, filter.Codigo)
+		conds = append(conds, "p.CODIGO_INTERNO = @p"+strconv.Itoa(len(args)))
+	}
+	if filter.Zeta != "" {
+		args = append(args, filter.Zeta)
+		conds = append(conds, "s.ZETA = @p"+strconv.Itoa(len(args)))
+	}
+	if filter.Zetas != nil {
+		conds = append(conds, inStrings("s.ZETA", filter.Zetas, &args))
+	}
+	if filter.Codigos != nil {
+		conds = append(conds, inStrings("p.CODIGO_INTERNO", filter.Codigos, &args))
+	}
+
+	query := `
+        SELECT 
+            s.ID_SUCURSAL,
+            p.NOMBRE_PRODUCTO,
+            p.CODIGO_INTERNO AS Codigo_Producto,
+            s.ZETA,
+            s.FECHA,
+            p.PRECIO_VENTA,
+            p.PRECIO_OFERTA,
+            s.COSTO_UNITARIO,
+            s.ANIO
+        FROM STOCKS s
+        INNER JOIN PRODUCTO p 
+            ON s.ID_PRODUCTO = p.ID_PRODUCTO
+        WHERE ` + strings.Join(conds, " AND ") + `
+        ORDER BY s.FECHA DESC
+    `
+	stocks, err := queryStocks(ctx, r.db, query, args...)
+	return stocks, queryError(ctx, BackendSQLServer, "historial de stocks", err)
+}
+
+// Zetas obtiene las zetas distintas con stock de productos activos en las
+// sucursales indicadas.
+func (r *SQLServerStockRepository) Zetas(ctx context.Context, sucursales []int) ([]string, error) {
+	if len(sucursales) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+	zetas, err := queryStrings(ctx, r.db, `
+        SELECT DISTINCT s.ZETA
+        FROM STOCKS s
+        INNER JOIN PRODUCTO p 
+            ON s.ID_PRODUCTO = p.ID_PRODUCTO
+        WHERE p.ACTIVO = 1 AND s.ID_SUCURSAL IN (`+placeholders(len(sucursales))+`)
+    `, intArgs(sucursales)...)
+	return zetas, queryError(ctx, BackendSQLServer, "zetas de stocks", err)
+}
+
+// Sucursales obtiene las sucursales con stock de productos activos.
+func (r *SQLServerStockRepository) Sucursales(ctx context.Context) ([]models.Sucursal, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+        SELECT 
+            s.ID_SUCURSAL,
+            COUNT(DISTINCT s.ID_PRODUCTO) AS Productos,
+            COUNT(DISTINCT s.ZETA) AS Zetas
+        FROM STOCKS s
+        INNER JOIN PRODUCTO p 
+            ON s.ID_PRODUCTO = p.ID_PRODUCTO
+        WHERE p.ACTIVO = 1
+        GROUP BY s.ID_SUCURSAL
+        ORDER BY s.ID_SUCURSAL
+    `
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, queryError(ctx, BackendSQLServer, "sucursales", err)
+	}
+	defer rows.Close()
+
+	var sucursales []models.Sucursal
+	for rows.Next() {
+		var s models.Sucursal
+		if err := rows.Scan(&s.IDSucursal, &s.Productos, &s.Zetas); err != nil {
+			return nil, queryError(ctx, BackendSQLServer, "sucursales", err)
+		}
+		sucursales = append(sucursales, s)
+	}
+	return sucursales, queryError(ctx, BackendSQLServer, "sucursales", rows.Err())
+}
+
+// placeholders genera la lista "@p1, @p2, ..." de parámetros de SQL Server.
+func placeholders(n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = "@p" + strconv.Itoa(i+1)
+	}
+	return strings.Join(ps, ", ")
+}
+
+// inStrings arma la condición "columna IN (...)" agregando los valores a
+// args. Una lista vacía no coincide con ninguna fila.
+func inStrings(columna string, valores []string, args *[]interface{}) string {
+	if len(valores) == 0 {
+		return "1 = 0"
+	}
+	ps := make([]string, len(valores))
+	for i, v := range valores {
+		*args = append(*args, v)
+		ps[i] = "@p" + strconv.Itoa(len(*args))
+	}
+	return columna + " IN (" + strings.Join(ps, ", ") + ")"
+}
+
+// intArgs convierte una lista de enteros en argumentos de consulta.
+func intArgs(values []int) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
+
+// queryStocks ejecuta una consulta que devuelve las columnas de StockData.
+func queryStocks(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]models.StockData, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stocks []models.StockData
+	for rows.Next() {
+		var s models.StockData
+		if err := rows.Scan(&s.IDSucursal, &s.NombreProducto, &s.CodigoProducto, &s.Zeta,
+			&s.Fecha, &s.PrecioVenta, &s.PrecioOferta, &s.CostoUnitario, &s.Anio); err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, s)
+	}
+	return stocks, rows.Err()
+}
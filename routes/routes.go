@@ -5,21 +5,57 @@ import (
 	"net/http"
 )
 
-func SetupRoutes() {
+// SetupRoutes registra las rutas de la aplicación en el ServeMux de la instancia.
+func SetupRoutes(app *controllers.App) {
+	mux := app.Mux
+
 	// Servir archivos estáticos
 	fs := http.FileServer(http.Dir("static"))
-	http.Handle("/static/", http.StripPrefix("/static/", fs))
+	mux.Handle("/static/", http.StripPrefix("/static/", fs))
 
 	// Ruta principal
-	http.HandleFunc("/", controllers.IndexHandler)
+	mux.HandleFunc("/", app.IndexHandler)
 	// Registrar rutas de API y vistas
-	http.HandleFunc("/api/saldos", controllers.ApiSaldosHandler)
-	http.HandleFunc("/saldos", controllers.SaldosHandler)
+	mux.HandleFunc("/api/saldos", app.ApiSaldosHandler)
+	mux.HandleFunc("/saldos", app.SaldosHandler)
+	// Series mensuales por producto (API y vista con gráfico)
+	mux.HandleFunc("/api/saldos/", app.SeriesApiHandler)
+	mux.HandleFunc("/saldos/", app.SeriesViewHandler)
+	// Detalle de producto y de zeta; /zetas/{zeta}/series también pasa por aquí
+	mux.HandleFunc("/api/productos/", app.DetalleApiHandler)
+	mux.HandleFunc("/api/zetas/", app.DetalleApiHandler)
+	mux.HandleFunc("/productos/", app.DetalleViewHandler)
+	mux.HandleFunc("/zetas/", app.DetalleViewHandler)
 	// Ruta para exportar saldos paginados
-	http.HandleFunc("/export", controllers.ExportSaldosHandler)
+	mux.HandleFunc("/export", app.ExportSaldosHandler)
+	// API de datos combinados y de registros sin correspondencia
+	mux.HandleFunc("/api/combined", app.CombinedDataHandler)
+	mux.HandleFunc("/api/combined/missing", app.CombinedMissingHandler)
+	mux.HandleFunc("/api/combined/colisiones", app.CombinedCollisionsHandler)
+	// Sucursales disponibles en SQL Server
+	mux.HandleFunc("/api/sucursales", app.SucursalesHandler)
 	// Ruta para visualizar datos combinados
-	http.HandleFunc("/combined", controllers.CombinedViewHandler)
+	mux.HandleFunc("/combined", app.CombinedViewHandler)
 	// Nueva ruta para exportar datos combinados completos
-	http.HandleFunc("/exportCombined", controllers.ExportCombinedHandler)
+	mux.HandleFunc("/exportCombined", app.ExportCombinedHandler)
+	// Reporte de antigüedad de saldos (vista, API y Excel)
+	mux.HandleFunc("/reportes/antiguedad", app.AntiguedadViewHandler)
+	mux.HandleFunc("/api/reportes/antiguedad", app.AntiguedadApiHandler)
+	mux.HandleFunc("/reportes/antiguedad/export", app.ExportAntiguedadHandler)
+	// Reporte de ofertas bajo el costo real (vista, API y Excel)
+	mux.HandleFunc("/reportes/margenes", app.MargenesViewHandler)
+	mux.HandleFunc("/api/reportes/margenes", app.MargenesApiHandler)
+	mux.HandleFunc("/reportes/margenes/export", app.ExportMargenesHandler)
+	// Valorización del inventario por año, producto y mes (vista, API, Excel y CSV)
+	mux.HandleFunc("/reportes/valorizacion", app.ValorizacionViewHandler)
+	mux.HandleFunc("/api/reportes/valorizacion", app.ValorizacionApiHandler)
+	mux.HandleFunc("/reportes/valorizacion/export", app.ExportValorizacionHandler)
+	// Conciliación entre saldos de MySQL y stocks de SQL Server (vista, API, Excel y CSV)
+	mux.HandleFunc("/reportes/conciliacion", app.ConciliacionViewHandler)
+	mux.HandleFunc("/api/reportes/conciliacion", app.ConciliacionApiHandler)
+	mux.HandleFunc("/reportes/conciliacion/export", app.ExportConciliacionHandler)
+	// Administración de la caché de stocks y saldos
+	mux.HandleFunc("/api/admin/cache", app.CacheEstadoHandler)
+	mux.HandleFunc("/api/admin/cache/invalidar", app.CacheInvalidarHandler)
 	// ...agregar más rutas si es necesario...
 }
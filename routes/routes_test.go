@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go_api/config"
+	"go_api/controllers"
+	"go_api/models"
+	"go_api/repository"
+)
+
+// nuevaApp crea una instancia con n saldos en memoria y sus rutas.
+func nuevaApp(t *testing.T, n int) *controllers.App {
+	t.Helper()
+	saldos := make([]models.Saldo, n)
+	for i := range saldos {
+		saldos[i] = models.Saldo{CodigoProducto: "P" + string(rune('A'+i)), Zeta: "Z" + string(rune('A'+i)), AnioProduccion: 2025}
+	}
+	app, err := controllers.NewAppWithRepositories(config.Config{},
+		repository.NewMemorySaldoRepository(saldos), repository.NewMemoryStockRepository(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetupRoutes(app)
+	return app
+}
+
+// TestInstanciasIndependientes comprueba que dos instancias en el mismo
+// proceso responden con sus propios datos y no registran rutas globales.
+func TestInstanciasIndependientes(t *testing.T) {
+	apps := []*controllers.App{nuevaApp(t, 2), nuevaApp(t, 5)}
+	for i, want := range []int{2, 5} {
+		rec := httptest.NewRecorder()
+		apps[i].ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/saldos", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("instancia %d: status = %d", i, rec.Code)
+		}
+		var resp struct {
+			Total int `json:"total"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Total != want {
+			t.Errorf("instancia %d: total = %d, se esperaba %d", i, resp.Total, want)
+		}
+	}
+
+	if _, patron := http.DefaultServeMux.Handler(httptest.NewRequest(http.MethodGet, "/api/saldos", nil)); patron != "" {
+		t.Errorf("la ruta quedó registrada en http.DefaultServeMux (%q)", patron)
+	}
+	// Sin conexiones propias Close no hace nada
+	if err := apps[0].Close(); err != nil {
+		t.Errorf("Close = %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package views
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"go_api/models"
+)
+
+// AntiguedadViewData contiene el reporte de antigüedad y los filtros usados.
+type AntiguedadViewData struct {
+	Reporte     models.ReporteAntiguedad
+	TramosParam string // límites de los tramos tal como se pidieron
+	SucursalesFiltro
+}
+
+// FilterQuery devuelve los parámetros del reporte para el enlace de
+// exportación.
+func (d AntiguedadViewData) FilterQuery() template.URL {
+	q := url.Values{}
+	q.Set("year", d.Reporte.Anio)
+	for _, id := range d.Reporte.Sucursales {
+		q.Add("sucursal", strconv.Itoa(id))
+	}
+	if d.TramosParam != "" {
+		q.Set("tramos", d.TramosParam)
+	}
+	return template.URL(q.Encode())
+}
+
+// Participacion devuelve el porcentaje del valor a costo real del tramo
+// sobre el total.
+func (d AntiguedadViewData) Participacion(t models.TramoAntiguedad) float64 {
+	if d.Reporte.Total.ValorCostoReal == 0 {
+		return 0
+	}
+	return t.ValorCostoReal / d.Reporte.Total.ValorCostoReal * 100
+}
+
+// RenderAntiguedad renderiza el reporte de antigüedad de saldos.
+func (r *Renderer) RenderAntiguedad(w http.ResponseWriter, data AntiguedadViewData) {
+	r.render(w, "antiguedad", data)
+}
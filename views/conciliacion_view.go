@@ -0,0 +1,70 @@
+package views
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"go_api/models"
+)
+
+// etiquetasDiscrepancia son los nombres de los tipos de discrepancia.
+var etiquetasDiscrepancia = map[string]string{
+	models.DiscrepanciaSoloMySQL:     "Solo en MySQL",
+	models.DiscrepanciaSoloSQLServer: "Solo en SQL Server",
+	models.DiscrepanciaCodigo:        "Código distinto",
+	models.DiscrepanciaAnio:          "Año distinto",
+	models.DiscrepanciaCosto:         "Costo distinto",
+}
+
+// ConciliacionViewData contiene el reporte de conciliación y los filtros
+// usados.
+type ConciliacionViewData struct {
+	Reporte models.ReporteConciliacion
+	Tipos   []string // tipos de discrepancia seleccionados; vacío es todos
+	SucursalesFiltro
+}
+
+// TiposDiscrepancia devuelve los tipos en el orden del reporte.
+func (d ConciliacionViewData) TiposDiscrepancia() []string {
+	return models.TiposDiscrepancia
+}
+
+// Etiqueta devuelve el nombre del tipo de discrepancia.
+func (d ConciliacionViewData) Etiqueta(tipo string) string {
+	return etiquetasDiscrepancia[tipo]
+}
+
+// TipoSeleccionado indica si el tipo está entre los seleccionados.
+func (d ConciliacionViewData) TipoSeleccionado(tipo string) bool {
+	for _, t := range d.Tipos {
+		if t == tipo {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterQuery devuelve los parámetros del reporte para el enlace de
+// exportación.
+func (d ConciliacionViewData) FilterQuery() template.URL {
+	q := url.Values{}
+	q.Set("year", d.Reporte.Anio)
+	for _, id := range d.Reporte.Sucursales {
+		q.Add("sucursal", strconv.Itoa(id))
+	}
+	tol := d.Reporte.Tolerancias
+	q.Set("tolCosto", strconv.FormatFloat(tol.Costo, 'f', -1, 64))
+	q.Set("tolCostoPct", strconv.FormatFloat(tol.CostoPct, 'f', -1, 64))
+	q.Set("tolAnio", strconv.Itoa(tol.Anio))
+	for _, t := range d.Tipos {
+		q.Add("tipo", t)
+	}
+	return template.URL(q.Encode())
+}
+
+// RenderConciliacion renderiza el reporte de conciliación.
+func (r *Renderer) RenderConciliacion(w http.ResponseWriter, data ConciliacionViewData) {
+	r.render(w, "conciliacion", data)
+}
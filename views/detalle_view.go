@@ -0,0 +1,38 @@
+package views
+
+import (
+	"net/http"
+
+	"go_api/models"
+)
+
+// DetalleViewData contiene el detalle de un producto o zeta.
+type DetalleViewData struct {
+	Detalle models.Detalle
+}
+
+// Vigente indica si el stock i es el más reciente de su sucursal y zeta, es
+// decir, el que se usa al combinar con los saldos. Los stocks vienen
+// ordenados del más reciente al más antiguo.
+func (d DetalleViewData) Vigente(i int) bool {
+	actual := d.Detalle.Stocks[i]
+	for _, s := range d.Detalle.Stocks[:i] {
+		if s.IDSucursal == actual.IDSucursal && s.Zeta == actual.Zeta {
+			return false
+		}
+	}
+	return true
+}
+
+// SerieURL devuelve el enlace a la serie mensual del producto o zeta.
+func (d DetalleViewData) SerieURL() string {
+	if d.Detalle.Tipo == "zeta" {
+		return "/zetas/" + d.Detalle.Clave + "/series"
+	}
+	return "/saldos/" + d.Detalle.Clave + "/series"
+}
+
+// RenderDetalle renderiza el detalle de un producto o zeta.
+func (r *Renderer) RenderDetalle(w http.ResponseWriter, data DetalleViewData) {
+	r.render(w, "detalle", data)
+}
@@ -0,0 +1,36 @@
+package views
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"go_api/models"
+)
+
+// MargenesViewData contiene el reporte de ofertas bajo costo y sus filtros.
+type MargenesViewData struct {
+	Reporte models.ReporteMargenes
+	Search  string
+	SucursalesFiltro
+}
+
+// FilterQuery devuelve los parámetros del reporte para el enlace de
+// exportación.
+func (d MargenesViewData) FilterQuery() template.URL {
+	q := url.Values{}
+	q.Set("year", d.Reporte.Anio)
+	for _, id := range d.Reporte.Sucursales {
+		q.Add("sucursal", strconv.Itoa(id))
+	}
+	if d.Search != "" {
+		q.Set("search", d.Search)
+	}
+	return template.URL(q.Encode())
+}
+
+// RenderMargenes renderiza el reporte de ofertas bajo costo.
+func (r *Renderer) RenderMargenes(w http.ResponseWriter, data MargenesViewData) {
+	r.render(w, "margenes", data)
+}
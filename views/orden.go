@@ -0,0 +1,87 @@
+package views
+
+import (
+	"strconv"
+	"strings"
+)
+
+// criterioOrden es un campo del parámetro sort y su dirección.
+type criterioOrden struct {
+	campo string
+	desc  bool
+}
+
+// criteriosOrden separa sort ("AnioProduccion,-CostoReal") aplicando dir a
+// los campos sin prefijo, como lo interpreta el controlador.
+func criteriosOrden(sort, dir string) []criterioOrden {
+	var criterios []criterioOrden
+	for _, parte := range strings.Split(sort, ",") {
+		parte = strings.TrimSpace(parte)
+		c := criterioOrden{campo: parte, desc: dir == "desc"}
+		switch {
+		case strings.HasPrefix(parte, "-"):
+			c = criterioOrden{campo: parte[1:], desc: true}
+		case strings.HasPrefix(parte, "+"):
+			c = criterioOrden{campo: parte[1:], desc: false}
+		}
+		if c.campo != "" {
+			criterios = append(criterios, c)
+		}
+	}
+	return criterios
+}
+
+// indicadorOrden devuelve la flecha del campo si forma parte del orden y,
+// cuando hay varios criterios, su posición.
+func indicadorOrden(sort, dir, field string) string {
+	criterios := criteriosOrden(sort, dir)
+	for i, c := range criterios {
+		if c.campo != field {
+			continue
+		}
+		flecha := "↑"
+		if c.desc {
+			flecha = "↓"
+		}
+		if len(criterios) > 1 {
+			flecha += strconv.Itoa(i + 1)
+		}
+		return flecha
+	}
+	return ""
+}
+
+// siguienteDir devuelve la dirección del enlace que ordena solo por field:
+// invierte la actual si ya es el único criterio ascendente.
+func siguienteDir(sort, dir, field string) string {
+	criterios := criteriosOrden(sort, dir)
+	if len(criterios) == 1 && criterios[0].campo == field && !criterios[0].desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// agregarOrden devuelve el parámetro sort con field como criterio adicional
+// ascendente, o con su dirección invertida si ya está. Los descendentes
+// llevan "-" y los ascendentes van sin prefijo: el enlace no incluye dir.
+func agregarOrden(sort, dir, field string) string {
+	criterios := criteriosOrden(sort, dir)
+	incluido := false
+	for i := range criterios {
+		if criterios[i].campo == field {
+			criterios[i].desc = !criterios[i].desc
+			incluido = true
+		}
+	}
+	if !incluido {
+		criterios = append(criterios, criterioOrden{campo: field})
+	}
+	partes := make([]string, len(criterios))
+	for i, c := range criterios {
+		partes[i] = c.campo
+		if c.desc {
+			partes[i] = "-" + c.campo
+		}
+	}
+	return strings.Join(partes, ",")
+}
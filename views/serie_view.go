@@ -0,0 +1,98 @@
+package views
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go_api/models"
+)
+
+// Dimensiones del gráfico SVG de la serie.
+const (
+	chartWidth   = 900
+	chartHeight  = 300
+	chartPadding = 40
+)
+
+// SerieViewData contiene la serie mensual a graficar.
+type SerieViewData struct {
+	Serie models.Serie
+}
+
+// coloresLotes son los colores de las líneas de cada lote, en orden.
+var coloresLotes = []string{"#2563eb", "#dc2626", "#16a34a", "#d97706", "#7c3aed", "#0891b2"}
+
+// ChartPoint es un punto del gráfico ya escalado a coordenadas SVG.
+type ChartPoint struct {
+	X, Y  float64
+	Label string
+	Saldo float64
+}
+
+// ChartLine es la línea de un lote.
+type ChartLine struct {
+	Label  string
+	Color  string
+	Points []ChartPoint
+	// Polyline es la lista de coordenadas para el atributo points.
+	Polyline string
+}
+
+// Chart contiene la geometría del gráfico de líneas de la serie: el eje X
+// son los meses y cada lote es una línea.
+type Chart struct {
+	Width, Height int
+	Padding       int
+	Max           float64
+	Lines         []ChartLine
+	// MonthMarks son las posiciones X de cada mes.
+	MonthMarks []ChartPoint
+}
+
+// Chart escala los puntos de cada lote al área del gráfico.
+func (d SerieViewData) Chart() Chart {
+	c := Chart{Width: chartWidth, Height: chartHeight, Padding: chartPadding}
+	for _, l := range d.Serie.Lotes {
+		for _, p := range l.Puntos {
+			if p.Saldo > c.Max {
+				c.Max = p.Saldo
+			}
+		}
+	}
+
+	ancho := float64(chartWidth - 2*chartPadding)
+	alto := float64(chartHeight - 2*chartPadding)
+	paso := ancho / float64(len(models.Meses)-1)
+	escala := 0.0
+	if c.Max > 0 {
+		escala = alto / c.Max
+	}
+	x := func(mes int) float64 { return float64(chartPadding) + paso*float64(mes-1) }
+
+	for i, nombre := range models.Meses {
+		c.MonthMarks = append(c.MonthMarks, ChartPoint{X: x(i + 1), Label: nombre[:3]})
+	}
+	for i, l := range d.Serie.Lotes {
+		linea := ChartLine{Label: fmt.Sprintf("Lote %d", l.AnioProduccion), Color: coloresLotes[i%len(coloresLotes)]}
+		coords := make([]string, 0, len(l.Puntos))
+		for _, p := range l.Puntos {
+			cp := ChartPoint{
+				X:     x(p.Mes),
+				Y:     float64(chartHeight-chartPadding) - p.Saldo*escala,
+				Label: fmt.Sprintf("%s, lote %d", p.NombreMes, l.AnioProduccion),
+				Saldo: p.Saldo,
+			}
+			linea.Points = append(linea.Points, cp)
+			coords = append(coords, fmt.Sprintf("%.1f,%.1f", cp.X, cp.Y))
+		}
+		linea.Polyline = strings.Join(coords, " ")
+		c.Lines = append(c.Lines, linea)
+	}
+	return c
+}
+
+// RenderSerie renderiza la serie mensual de un producto o zeta.
+func (r *Renderer) RenderSerie(w http.ResponseWriter, data SerieViewData) {
+	r.render(w, "serie", data)
+}
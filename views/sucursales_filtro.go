@@ -0,0 +1,37 @@
+package views
+
+import "go_api/models"
+
+// SucursalesFiltro reúne las sucursales seleccionadas y las disponibles para
+// los selectores de sucursal de las vistas.
+type SucursalesFiltro struct {
+	Sucursales  []int             // sucursales seleccionadas
+	Disponibles []models.Sucursal // sucursales para el selector
+}
+
+// SucursalSeleccionada indica si la sucursal está entre las seleccionadas.
+func (f SucursalesFiltro) SucursalSeleccionada(id int) bool {
+	for _, s := range f.Sucursales {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Opciones devuelve las sucursales a mostrar en el selector: las disponibles
+// más las seleccionadas que no aparezcan entre ellas.
+func (f SucursalesFiltro) Opciones() []int {
+	var ids []int
+	vistas := make(map[int]bool)
+	for _, s := range f.Disponibles {
+		vistas[s.IDSucursal] = true
+		ids = append(ids, s.IDSucursal)
+	}
+	for _, id := range f.Sucursales {
+		if !vistas[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
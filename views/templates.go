@@ -0,0 +1,140 @@
+package views
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_api/models"
+)
+
+// templatesFS contiene el layout y las plantillas de cada página.
+//
+//go:embed templates/*.tmpl
+var templatesFS embed.FS
+
+// layoutFile es la plantilla base que envuelve el contenido de cada página.
+const layoutFile = "layout.tmpl"
+
+// pages son las plantillas de contenido que se combinan con el layout.
+var pages = []string{"saldos", "combined", "index", "serie", "detalle", "antiguedad", "margenes", "valorizacion", "conciliacion"}
+
+var funcMap = template.FuncMap{
+	"formatDate": func(t time.Time) string {
+		return t.Format("2006-01-02")
+	},
+	"formatDateTime": func(t time.Time) string {
+		return t.Format("02-01-2006 15:04:05")
+	},
+	"formatNumber": FormatNumber,
+	"meses":        func() [12]string { return models.Meses },
+	"inc":          func(i int) int { return i + 1 },
+	"sub":          func(a, b int) int { return a - b },
+	"dec": func(i int) int {
+		if i > 1 {
+			return i - 1
+		}
+		return 1
+	},
+}
+
+// Renderer renderiza las vistas HTML. Las plantillas embebidas se parsean una
+// vez al crearlo; en modo desarrollo se vuelven a leer desde disco en cada
+// petición para ver los cambios sin recompilar.
+type Renderer struct {
+	devDir string
+	cache  map[string]*template.Template
+}
+
+// NewRenderer crea un Renderer. Si devDir no está vacío las plantillas se leen
+// desde ese directorio en cada petición en lugar de usar las embebidas.
+func NewRenderer(devDir string) (*Renderer, error) {
+	r := &Renderer{devDir: devDir, cache: make(map[string]*template.Template)}
+	if devDir != "" {
+		return r, nil
+	}
+	sub, err := fs.Sub(templatesFS, "templates")
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range pages {
+		tmpl, err := parsePage(sub, page)
+		if err != nil {
+			return nil, err
+		}
+		r.cache[page] = tmpl
+	}
+	return r, nil
+}
+
+// parsePage combina el layout con la plantilla de la página indicada.
+func parsePage(fsys fs.FS, page string) (*template.Template, error) {
+	tmpl, err := template.New(layoutFile).Funcs(funcMap).ParseFS(fsys, layoutFile, page+".tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("parseando plantilla %s: %w", page, err)
+	}
+	return tmpl, nil
+}
+
+// lookup devuelve la plantilla de la página desde la caché o desde disco.
+func (r *Renderer) lookup(page string) (*template.Template, error) {
+	if r.devDir != "" {
+		return parsePage(os.DirFS(r.devDir), page)
+	}
+	tmpl, ok := r.cache[page]
+	if !ok {
+		return nil, fmt.Errorf("plantilla %s no registrada", page)
+	}
+	return tmpl, nil
+}
+
+// render ejecuta el layout con el contenido de la página indicada.
+func (r *Renderer) render(w http.ResponseWriter, page string, data interface{}) {
+	tmpl, err := r.lookup(page)
+	if err != nil {
+		log.Println("Error al cargar la plantilla:", err)
+		http.Error(w, "Error al cargar la plantilla", http.StatusInternalServerError)
+		return
+	}
+	// Renderizar en un buffer para poder responder con error si algo falla
+	var buf bytes.Buffer
+	if err = tmpl.ExecuteTemplate(&buf, layoutFile, data); err != nil {
+		log.Printf("Error al renderizar %s: %v", page, err)
+		http.Error(w, "Error al renderizar la plantilla", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+// FormatNumber formatea un número con separador de miles "." y dos decimales
+// con ",", como se usa en los reportes.
+func FormatNumber(v float64) string {
+	neg := v < 0
+	if neg {
+		v = -v
+	}
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	entero, decimales := s[:len(s)-3], s[len(s)-2:]
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	for i, c := range entero {
+		if i > 0 && (len(entero)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	b.WriteByte(',')
+	b.WriteString(decimales)
+	return b.String()
+}
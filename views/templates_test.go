@@ -0,0 +1,88 @@
+package views
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewRendererEmbebidas(t *testing.T) {
+	r, err := NewRenderer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range pages {
+		tmpl, err := r.lookup(page)
+		if err != nil {
+			t.Errorf("%s: %v", page, err)
+			continue
+		}
+		for _, nombre := range []string{layoutFile, "title", "content"} {
+			if tmpl.Lookup(nombre) == nil {
+				t.Errorf("%s: falta la plantilla %q", page, nombre)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	r.render(rec, "no-existe", nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("página sin plantilla: status = %d, se esperaba 500", rec.Code)
+	}
+}
+
+// TestRendererModoDesarrollo comprueba que en modo desarrollo los cambios en
+// disco se ven en la siguiente petición.
+func TestRendererModoDesarrollo(t *testing.T) {
+	dir := t.TempDir()
+	escribir := func(nombre, contenido string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, nombre), []byte(contenido), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	escribir(layoutFile, `{{define "layout.tmpl"}}<title>{{template "title" .}}</title>{{template "content" .}}{{end}}`)
+	escribir("index.tmpl", `{{define "title"}}Inicio{{end}}{{define "content"}}versión 1{{end}}`)
+
+	r, err := NewRenderer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"versión 1", "versión 2"} {
+		escribir("index.tmpl", `{{define "title"}}Inicio{{end}}{{define "content"}}`+want+`{{end}}`)
+		rec := httptest.NewRecorder()
+		r.render(rec, "index", nil)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("status = %d, cuerpo = %q; se esperaba %q", rec.Code, rec.Body.String(), want)
+		}
+	}
+
+	// Un error de ejecución no deja la página a medio enviar
+	escribir("index.tmpl", `{{define "title"}}Inicio{{end}}{{define "content"}}{{.NoExiste}}{{end}}`)
+	rec := httptest.NewRecorder()
+	r.render(rec, "index", struct{}{})
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "<title>") {
+		t.Errorf("status = %d, cuerpo = %q; se esperaba solo el error", rec.Code, rec.Body.String())
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		valor float64
+		want  string
+	}{
+		{0, "0,00"},
+		{1.5, "1,50"},
+		{999.999, "1.000,00"},
+		{1234567.891, "1.234.567,89"},
+		{-1234.5, "-1.234,50"},
+	}
+	for _, tt := range tests {
+		if got := FormatNumber(tt.valor); got != tt.want {
+			t.Errorf("FormatNumber(%v) = %q, se esperaba %q", tt.valor, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package views
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"go_api/models"
+)
+
+// ValorizacionViewData contiene la valorización del inventario y los filtros
+// usados.
+type ValorizacionViewData struct {
+	Reporte models.ReporteValorizacion
+	Anio    string
+	Search  string
+}
+
+// FilterQuery devuelve los filtros para los enlaces de exportación.
+func (d ValorizacionViewData) FilterQuery() template.URL {
+	q := url.Values{}
+	if d.Anio != "" {
+		q.Set("anio", d.Anio)
+	}
+	if d.Search != "" {
+		q.Set("search", d.Search)
+	}
+	return template.URL(q.Encode())
+}
+
+// RenderValorizacion renderiza el reporte de valorización.
+func (r *Renderer) RenderValorizacion(w http.ResponseWriter, data ValorizacionViewData) {
+	r.render(w, "valorizacion", data)
+}